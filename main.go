@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"time"
+	_ "time/tzdata" // Event time zones must resolve even without system zoneinfo
 
 	"github.com/joho/godotenv"
 
 	"01-Login/platform/authenticator"
 	"01-Login/platform/database"
 	"01-Login/platform/models"
+	"01-Login/platform/notifications"
+	"01-Login/platform/realtime"
 	"01-Login/platform/router"
+	"01-Login/platform/services"
+	"01-Login/platform/sessionstore"
 )
 
 func main() {
@@ -17,6 +24,12 @@ func main() {
 		log.Fatalf("Failed to load the env vars: %v", err)
 	}
 
+	// Refuse to run in production with a guessable session secret
+	sessionSecret, err := services.SessionSecret()
+	if err != nil {
+		log.Fatalf("Invalid session secret: %v", err)
+	}
+
 	// Initialize database
 	database.Connect()
 
@@ -25,14 +38,62 @@ func main() {
 		&models.User{},
 		&models.Event{},
 		&models.RSVP{},
+		&models.EventStatusTransition{},
+		&models.EventQuestion{},
+		&models.EventOccurrenceOverride{},
+		&models.CalendarFeed{},
+		&models.EventInvitation{},
+		&models.EventMember{},
+		&models.NotificationPreference{},
+		&models.EventReminder{},
+		&models.Job{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.WebhookAttempt{},
+		&models.EventComment{},
+		&models.EventAnnouncement{},
+		&models.AnnouncementDelivery{},
+		&models.EmailNotification{},
+		&models.DatePoll{},
+		&models.DatePollOption{},
+		&models.DatePollVote{},
+		&models.Session{},
+		&models.PersonalAccessToken{},
+		&models.AdminAuditLog{},
 	)
 
+	// Give events from before per-event roles their owner membership
+	if err := services.NewMemberService().BackfillOwners(); err != nil {
+		log.Fatalf("Failed to backfill event owners: %v", err)
+	}
+
+	// Initialize email delivery
+	if err := notifications.Setup(); err != nil {
+		log.Fatalf("Failed to set up email notifications: %v", err)
+	}
+
+	// Send event reminders in the background
+	go services.NewReminderService().Run(context.Background(), time.Minute)
+
+	// Relay event changes from every server instance to live streams
+	go realtime.Listen(context.Background(), database.DSN())
+
+	// Run background jobs, such as creating Google Photos albums, delivering webhooks and emailing announcements
+	go services.NewJobService().Run(context.Background(), 2, 5*time.Second)
+
 	auth, err := authenticator.New()
 	if err != nil {
 		log.Fatalf("Failed to initialize the authenticator: %v", err)
 	}
 
-	rtr := router.New(auth)
+	// Keep sessions on the server, deleting ended ones hourly
+	sessionStore, err := sessionstore.Setup(sessionSecret)
+	if err != nil {
+		log.Fatalf("Failed to set up sessions: %v", err)
+	}
+	go sessionStore.Run(context.Background(), time.Hour)
+
+	rtr := router.New(auth, sessionStore)
 
 	log.Print("Server listening on http://localhost:3000/")
 	if err := http.ListenAndServe("0.0.0.0:3000", rtr); err != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"os"
+	"strings"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"golang.org/x/oauth2"
@@ -13,6 +14,38 @@ import (
 type Authenticator struct {
 	*oidc.Provider
 	oauth2.Config
+	// Audience is the API identifier Auth0 access tokens must be issued
+	// for. Access tokens are refused when it's empty.
+	Audience string
+}
+
+// ErrAccessTokensDisabled is returned when verifying an access token
+// without AUTH0_AUDIENCE configured
+var ErrAccessTokensDisabled = errors.New("auth0 access tokens are not accepted")
+
+// AccessTokenClaims are the claims of a verified Auth0 access token
+type AccessTokenClaims struct {
+	Subject string
+	// Scopes are the token's OAuth scopes
+	Scopes []string
+	// Permissions are the API permissions Auth0 RBAC granted the subject
+	Permissions []string
+}
+
+// Grants reports whether the token carries a scope, either as an OAuth
+// scope or as an RBAC permission
+func (c *AccessTokenClaims) Grants(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	for _, p := range c.Permissions {
+		if p == scope {
+			return true
+		}
+	}
+	return false
 }
 
 // New instantiates the *Authenticator.
@@ -36,6 +69,7 @@ func New() (*Authenticator, error) {
 	return &Authenticator{
 		Provider: provider,
 		Config:   conf,
+		Audience: os.Getenv("AUTH0_AUDIENCE"),
 	}, nil
 }
 
@@ -52,3 +86,34 @@ func (a *Authenticator) VerifyIDToken(ctx context.Context, token *oauth2.Token)
 
 	return a.Verifier(oidcConfig).Verify(ctx, rawIDToken)
 }
+
+// VerifyAccessToken verifies an Auth0-issued access token for the API: its
+// signature against the tenant's JWKS, its issuer, its audience and its
+// expiry.
+func (a *Authenticator) VerifyAccessToken(ctx context.Context, rawToken string) (*AccessTokenClaims, error) {
+	if a.Audience == "" {
+		return nil, ErrAccessTokensDisabled
+	}
+
+	oidcConfig := &oidc.Config{
+		ClientID: a.Audience,
+	}
+	token, err := a.Verifier(oidcConfig).Verify(ctx, rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		Scope       string   `json:"scope"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := token.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &AccessTokenClaims{
+		Subject:     token.Subject,
+		Scopes:      strings.Fields(claims.Scope),
+		Permissions: claims.Permissions,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package authorization
+
+import (
+	"01-Login/platform/models"
+)
+
+// EventPermission is something a member may do to an event
+type EventPermission string
+
+const (
+	// EventPermissionEdit covers details, schedule, status, questions and invitations
+	EventPermissionEdit EventPermission = "edit"
+	// EventPermissionViewGuests covers the guest list and RSVP answers
+	EventPermissionViewGuests EventPermission = "view_guests"
+	// EventPermissionDelete covers deleting the event
+	EventPermissionDelete EventPermission = "delete"
+	// EventPermissionManageMembers covers adding and removing members and transferring ownership
+	EventPermissionManageMembers EventPermission = "manage_members"
+	// EventPermissionModerateComments covers deleting anyone's comments and pinning one
+	EventPermissionModerateComments EventPermission = "moderate_comments"
+)
+
+// eventRolePermissions lists what each event role may do
+var eventRolePermissions = map[models.EventRole][]EventPermission{
+	models.EventRoleOwner: {
+		EventPermissionEdit,
+		EventPermissionViewGuests,
+		EventPermissionDelete,
+		EventPermissionManageMembers,
+		EventPermissionModerateComments,
+	},
+	models.EventRoleCoHost: {
+		EventPermissionEdit,
+		EventPermissionViewGuests,
+		EventPermissionModerateComments,
+	},
+	models.EventRoleCheckInStaff: {
+		EventPermissionViewGuests,
+	},
+}
+
+// EventRoleAllows reports whether a role on an event grants a permission.
+// The empty role, for users who aren't members, grants nothing.
+func EventRoleAllows(role models.EventRole, permission EventPermission) bool {
+	for _, granted := range eventRolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// CanManageEvent reports whether a role lets its holder edit an event
+func CanManageEvent(role models.EventRole) bool {
+	return EventRoleAllows(role, EventPermissionEdit)
+}
+
+// CanViewEvent reports whether someone may see an event and RSVP to it.
+// Private events are limited to members and invitees; role is the viewer's
+// role on the event, empty for non-members and signed-out visitors, and
+// invited says whether they hold an invitation.
+func CanViewEvent(event *models.Event, role models.EventRole, invited bool) bool {
+	if event == nil {
+		return false
+	}
+	return event.IsPublic || role != "" || invited
+}
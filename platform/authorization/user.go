@@ -0,0 +1,49 @@
+package authorization
+
+import (
+	"01-Login/platform/models"
+)
+
+// UserPermission is something a user's app-wide role may allow
+type UserPermission string
+
+const (
+	// UserPermissionManageUsers covers listing, changing, deactivating and deleting users
+	UserPermissionManageUsers UserPermission = "manage_users"
+	// UserPermissionModerateEvents covers unpublishing any event and lifting the hold
+	UserPermissionModerateEvents UserPermission = "moderate_events"
+	// UserPermissionImpersonate covers acting as another user for support
+	UserPermissionImpersonate UserPermission = "impersonate"
+	// UserPermissionViewAuditLog covers reading the admin audit log
+	UserPermissionViewAuditLog UserPermission = "view_audit_log"
+)
+
+// userRolePermissions lists what each app-wide role may do; plain users
+// get nothing beyond their own account and events
+var userRolePermissions = map[models.UserRole][]UserPermission{
+	models.UserRoleAdmin: {
+		UserPermissionManageUsers,
+		UserPermissionModerateEvents,
+		UserPermissionImpersonate,
+		UserPermissionViewAuditLog,
+	},
+	models.UserRoleModerator: {
+		UserPermissionModerateEvents,
+	},
+}
+
+// UserRoleAllows reports whether an app-wide role grants a permission
+func UserRoleAllows(role models.UserRole, permission UserPermission) bool {
+	for _, granted := range userRolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// UserCan reports whether a user's role grants a permission. Deactivated
+// users can't do anything privileged.
+func UserCan(user *models.User, permission UserPermission) bool {
+	return user != nil && user.IsActive && UserRoleAllows(user.Role, permission)
+}
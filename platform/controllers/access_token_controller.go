@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AccessTokenController struct {
+	tokenService *services.AccessTokenService
+}
+
+// NewAccessTokenController creates a new access token controller
+func NewAccessTokenController() *AccessTokenController {
+	return &AccessTokenController{
+		tokenService: services.NewAccessTokenService(),
+	}
+}
+
+// GetTokens handles GET /api/user/tokens
+func (tc *AccessTokenController) GetTokens(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	tokens, err := tc.tokenService.GetTokens(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": tokens})
+}
+
+// CreateToken handles POST /api/user/tokens. The token is only ever
+// returned here.
+func (tc *AccessTokenController) CreateToken(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var req services.AccessTokenInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accessToken, token, err := tc.tokenService.CreateToken(user.ID, &req)
+	if err != nil {
+		var validationErrs services.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": accessToken, "token": token})
+}
+
+// DeleteToken handles DELETE /api/user/tokens/:token
+func (tc *AccessTokenController) DeleteToken(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	tokenID, err := uuid.Parse(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	if err := tc.tokenService.DeleteToken(user.ID, tokenID); err != nil {
+		if errors.Is(err, services.ErrAccessTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Access token deleted successfully"})
+}
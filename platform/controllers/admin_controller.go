@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"01-Login/platform/middleware"
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+	"01-Login/platform/sessionstore"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminController struct {
+	adminService *services.AdminService
+	store        *sessionstore.Store
+}
+
+// NewAdminController creates a new admin controller
+func NewAdminController(store *sessionstore.Store) *AdminController {
+	return &AdminController{
+		adminService: services.NewAdminService(),
+		store:        store,
+	}
+}
+
+// adminActionRequest is the body of admin actions, all of which take a reason
+type adminActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// auditInfo describes the signed-in admin taking an action, for the audit log
+func auditInfo(c *gin.Context, reason string) services.AuditInfo {
+	user := c.MustGet("user").(models.User)
+	return services.AuditInfo{
+		ActorID:   user.ID,
+		IPAddress: c.ClientIP(),
+		Reason:    reason,
+	}
+}
+
+// bindAdminAction reads the optional body of an admin action
+func bindAdminAction(c *gin.Context) (adminActionRequest, bool) {
+	var req adminActionRequest
+	if c.Request.ContentLength == 0 {
+		return req, true
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+// respondAdminError maps admin service errors to responses
+func respondAdminError(c *gin.Context, err error) {
+	var validationErrs services.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrEventNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOwnAccount), errors.Is(err, services.ErrImpersonateAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUserDeactivated),
+		errors.Is(err, services.ErrNoModerationHold),
+		errors.Is(err, services.ErrIllegalTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// idParam parses the :id path parameter
+func idParam(c *gin.Context, what string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + what + " ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// SetUserRole handles PUT /api/admin/users/:id/role
+func (ac *AdminController) SetUserRole(c *gin.Context) {
+	id, ok := idParam(c, "user")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role   models.UserRole `json:"role" binding:"required"`
+		Reason string          `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := ac.adminService.SetUserRole(auditInfo(c, req.Reason), id, req.Role)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+// DeactivateUser handles POST /api/admin/users/:id/deactivate
+func (ac *AdminController) DeactivateUser(c *gin.Context) {
+	ac.setUserActive(c, false)
+}
+
+// ReactivateUser handles POST /api/admin/users/:id/reactivate
+func (ac *AdminController) ReactivateUser(c *gin.Context) {
+	ac.setUserActive(c, true)
+}
+
+func (ac *AdminController) setUserActive(c *gin.Context, active bool) {
+	id, ok := idParam(c, "user")
+	if !ok {
+		return
+	}
+	req, ok := bindAdminAction(c)
+	if !ok {
+		return
+	}
+
+	user, err := ac.adminService.SetUserActive(auditInfo(c, req.Reason), id, active)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	// Sign a deactivated user out everywhere
+	if !active {
+		if _, err := ac.store.RevokeAllUserSessions(user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+// UnpublishEvent handles POST /api/admin/events/:id/unpublish
+func (ac *AdminController) UnpublishEvent(c *gin.Context) {
+	id, ok := idParam(c, "event")
+	if !ok {
+		return
+	}
+	req, ok := bindAdminAction(c)
+	if !ok {
+		return
+	}
+
+	event, err := ac.adminService.UnpublishEvent(auditInfo(c, req.Reason), id)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": event})
+}
+
+// LiftEventHold handles POST /api/admin/events/:id/lift-hold
+func (ac *AdminController) LiftEventHold(c *gin.Context) {
+	id, ok := idParam(c, "event")
+	if !ok {
+		return
+	}
+	req, ok := bindAdminAction(c)
+	if !ok {
+		return
+	}
+
+	event, err := ac.adminService.LiftEventHold(auditInfo(c, req.Reason), id)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": event})
+}
+
+// StartImpersonation handles POST /api/admin/users/:id/impersonate. Until it
+// ends, the admin's session acts as the user on the API.
+func (ac *AdminController) StartImpersonation(c *gin.Context) {
+	id, ok := idParam(c, "user")
+	if !ok {
+		return
+	}
+	req, ok := bindAdminAction(c)
+	if !ok {
+		return
+	}
+	if req.Reason == "" {
+		respondAdminError(c, services.ValidationErrors{"reason": "is required"})
+		return
+	}
+
+	user, err := ac.adminService.StartImpersonation(auditInfo(c, req.Reason), id)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	started := time.Now()
+	session := sessions.Default(c)
+	session.Set(sessionstore.ImpersonatedUserIDKey, user.ID.String())
+	session.Set(sessionstore.ImpersonationStartedKey, started.Unix())
+	if err := session.Save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       user,
+		"expires_at": started.Add(middleware.ImpersonationTimeout),
+	})
+}
+
+// EndImpersonation handles DELETE /api/admin/impersonation. It is open to
+// any signed-in session, as while impersonating the session's user is the
+// impersonated one.
+func (ac *AdminController) EndImpersonation(c *gin.Context) {
+	adminInterface, impersonating := c.Get("impersonator")
+	if !impersonating {
+		c.JSON(http.StatusConflict, gin.H{"error": "Not impersonating anyone"})
+		return
+	}
+	admin := adminInterface.(models.User)
+	user := c.MustGet("user").(models.User)
+
+	session := sessions.Default(c)
+	session.Delete(sessionstore.ImpersonatedUserIDKey)
+	session.Delete(sessionstore.ImpersonationStartedKey)
+	if err := session.Save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	audit := services.AuditInfo{ActorID: admin.ID, IPAddress: c.ClientIP()}
+	if err := ac.adminService.EndImpersonation(audit, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Impersonation ended successfully"})
+}
+
+// GetAuditLog handles GET /api/admin/audit-log
+func (ac *AdminController) GetAuditLog(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 50
+	}
+
+	filter := services.AuditLogFilter{Action: models.AdminAuditAction(c.Query("action"))}
+	var ok bool
+	if filter.ActorID, ok = uuidQuery(c, "actor_id"); !ok {
+		return
+	}
+	if filter.TargetID, ok = uuidQuery(c, "target_id"); !ok {
+		return
+	}
+
+	entries, total, err := ac.adminService.GetAuditLog(filter, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": entries,
+		"pagination": gin.H{
+			"page":        page,
+			"page_size":   pageSize,
+			"total":       total,
+			"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// uuidQuery parses an optional UUID query parameter
+func uuidQuery(c *gin.Context, name string) (*uuid.UUID, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return nil, false
+	}
+	return &id, true
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AnnouncementController struct {
+	announcementService *services.AnnouncementService
+}
+
+// NewAnnouncementController creates a new announcement controller
+func NewAnnouncementController() *AnnouncementController {
+	return &AnnouncementController{
+		announcementService: services.NewAnnouncementService(),
+	}
+}
+
+// GetAnnouncements handles GET /api/events/:id/announcements (viewers only)
+func (ac *AnnouncementController) GetAnnouncements(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	announcements, err := ac.announcementService.GetAnnouncements(event.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": announcements})
+}
+
+// PostAnnouncement handles POST /api/events/:id/announcements (organizer only)
+func (ac *AnnouncementController) PostAnnouncement(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	user := c.MustGet("user").(models.User)
+
+	var req services.AnnouncementInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	announcement, err := ac.announcementService.PostAnnouncement(&event, user.ID, &req)
+	if err != nil {
+		var validationErrs services.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": announcement})
+}
+
+// GetAnnouncementDeliveries handles GET /api/events/:id/announcements/:announcement/deliveries (organizer only)
+func (ac *AnnouncementController) GetAnnouncementDeliveries(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	announcementID, err := uuid.Parse(c.Param("announcement"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid announcement ID"})
+		return
+	}
+
+	announcement, err := ac.announcementService.GetAnnouncement(event.ID, announcementID)
+	if err != nil {
+		if errors.Is(err, services.ErrAnnouncementNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	deliveries, err := ac.announcementService.GetDeliveries(event.ID, announcementID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": deliveries, "announcement": announcement})
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"01-Login/platform/ical"
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize is the largest .ics file accepted for import
+const maxImportSize = 5 << 20
+
+type CalendarController struct {
+	calendarService *services.CalendarService
+}
+
+// NewCalendarController creates a new calendar controller
+func NewCalendarController() *CalendarController {
+	return &CalendarController{
+		calendarService: services.NewCalendarService(),
+	}
+}
+
+// baseURL is the public address of the app, used for links in exported
+// calendars. APP_BASE_URL wins over the address the request came in on.
+func baseURL(c *gin.Context) string {
+	if base := os.Getenv("APP_BASE_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// writeCalendar sends a calendar as an .ics response
+func writeCalendar(c *gin.Context, calendar *ical.Calendar, filename string) {
+	var buf bytes.Buffer
+	if err := calendar.Encode(&buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
+
+// ExportEvent handles GET /api/events/:id/ics (viewers only)
+func (cc *CalendarController) ExportEvent(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	calendar, err := cc.calendarService.ExportEvent(&event, baseURL(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	writeCalendar(c, calendar, event.ID.String()+".ics")
+}
+
+// GetFeed handles GET /api/user/calendar-feed
+func (cc *CalendarController) GetFeed(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	feed, err := cc.calendarService.GetFeed(user.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not enabled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": feed})
+}
+
+// CreateFeed handles POST /api/user/calendar-feed. The feed URL is only
+// returned here; calling it again replaces the URL with a new one.
+func (cc *CalendarController) CreateFeed(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	feed, token, err := cc.calendarService.CreateFeed(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": feed,
+		"url":  baseURL(c) + "/api/calendar/" + token + ".ics",
+	})
+}
+
+// DeleteFeed handles DELETE /api/user/calendar-feed
+func (cc *CalendarController) DeleteFeed(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	if err := cc.calendarService.DeleteFeed(user.ID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed deleted successfully"})
+}
+
+// GetFeedCalendar handles GET /api/calendar/:token, the URL calendar apps
+// subscribe to. The token is the only credential, so no session is needed.
+func (cc *CalendarController) GetFeedCalendar(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	calendar, err := cc.calendarService.GetFeedCalendar(token, baseURL(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+
+	writeCalendar(c, calendar, "events.ics")
+}
+
+// ImportEvents handles POST /api/events/import. The .ics file is sent as the
+// "file" field of a multipart form or as the raw request body. With
+// dry_run=true nothing is created and the response previews the import.
+func (cc *CalendarController) ImportEvents(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	opts := services.ImportOptions{
+		DryRun: c.Query("dry_run") == "true",
+		Status: c.DefaultQuery("status", models.EventStatusDraft),
+	}
+	if opts.Status != models.EventStatusDraft && opts.Status != models.EventStatusPublished {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be draft or published"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An .ics file is required in the file field"})
+			return
+		}
+		opened, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer opened.Close()
+		body = opened
+	}
+
+	calendar, err := ical.Decode(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "The file is larger than 5 MB"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid iCalendar file: " + err.Error()})
+		return
+	}
+
+	results, err := cc.calendarService.ImportCalendar(user.ID, calendar, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary := map[string]int{
+		services.ImportActionCreate:   0,
+		services.ImportActionExisting: 0,
+		services.ImportActionSkip:     0,
+	}
+	for _, result := range results {
+		summary[result.Action]++
+	}
+
+	status := http.StatusCreated
+	if opts.DryRun || summary[services.ImportActionCreate] == 0 {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{
+		"data":    results,
+		"summary": summary,
+		"dry_run": opts.DryRun,
+	})
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"01-Login/platform/authorization"
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CommentController struct {
+	commentService *services.CommentService
+	memberService  *services.MemberService
+}
+
+// NewCommentController creates a new comment controller
+func NewCommentController() *CommentController {
+	return &CommentController{
+		commentService: services.NewCommentService(),
+		memberService:  services.NewMemberService(),
+	}
+}
+
+// respondCommentError maps comment service errors to responses
+func respondCommentError(c *gin.Context, err error) {
+	var validationErrs services.ValidationErrors
+	var rateLimitErr *services.CommentRateLimitError
+	switch {
+	case errors.As(err, &validationErrs):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+	case errors.As(err, &rateLimitErr):
+		c.Header("Retry-After", strconv.Itoa(int(rateLimitErr.RetryAfter.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCommentForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// commentParam parses the :comment path parameter
+func commentParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("comment"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// GetComments handles GET /api/events/:id/comments (viewers only)
+func (cc *CommentController) GetComments(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	comments, total, err := cc.commentService.GetComments(event.ID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": comments,
+		"pagination": gin.H{
+			"page":        page,
+			"page_size":   pageSize,
+			"total":       total,
+			"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// CreateComment handles POST /api/events/:id/comments (viewers only)
+func (cc *CommentController) CreateComment(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	user := c.MustGet("user").(models.User)
+
+	var req services.CommentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := cc.commentService.CreateComment(&event, user.ID, &req)
+	if err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": comment})
+}
+
+// UpdateComment handles PATCH /api/events/:id/comments/:comment (author only)
+func (cc *CommentController) UpdateComment(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	user := c.MustGet("user").(models.User)
+	commentID, ok := commentParam(c)
+	if !ok {
+		return
+	}
+
+	var req services.CommentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := cc.commentService.UpdateComment(&event, user.ID, commentID, &req)
+	if err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": comment})
+}
+
+// DeleteComment handles DELETE /api/events/:id/comments/:comment (author or organizer)
+func (cc *CommentController) DeleteComment(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	user := c.MustGet("user").(models.User)
+	commentID, ok := commentParam(c)
+	if !ok {
+		return
+	}
+
+	role, err := cc.memberService.GetRole(event.ID, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	canModerate := authorization.EventRoleAllows(role, authorization.EventPermissionModerateComments)
+
+	if err := cc.commentService.DeleteComment(event.ID, user.ID, commentID, canModerate); err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// PinComment handles POST /api/events/:id/comments/:comment/pin (organizer only)
+func (cc *CommentController) PinComment(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	commentID, ok := commentParam(c)
+	if !ok {
+		return
+	}
+
+	comment, err := cc.commentService.PinComment(event.ID, commentID)
+	if err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": comment})
+}
+
+// UnpinComment handles DELETE /api/events/:id/comments/:comment/pin (organizer only)
+func (cc *CommentController) UnpinComment(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	commentID, ok := commentParam(c)
+	if !ok {
+		return
+	}
+
+	comment, err := cc.commentService.UnpinComment(event.ID, commentID)
+	if err != nil {
+		respondCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": comment})
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type DatePollController struct {
+	pollService *services.DatePollService
+}
+
+// NewDatePollController creates a new date poll controller
+func NewDatePollController() *DatePollController {
+	return &DatePollController{
+		pollService: services.NewDatePollService(),
+	}
+}
+
+// CreateDatePollRequest lists a new poll's candidate dates
+type CreateDatePollRequest struct {
+	Options []services.DatePollOptionInput `json:"options"`
+}
+
+// DatePollVoteRequest holds a voter's answers keyed by option ID
+type DatePollVoteRequest struct {
+	Votes map[uuid.UUID]models.DatePollAnswer `json:"votes"`
+}
+
+// FinalizeDatePollRequest names the winning option
+type FinalizeDatePollRequest struct {
+	OptionID uuid.UUID `json:"option_id" binding:"required"`
+}
+
+// respondDatePollError maps date poll service errors to responses
+func respondDatePollError(c *gin.Context, err error) {
+	var validationErrs services.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+	case errors.Is(err, services.ErrDatePollNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDatePollExists),
+		errors.Is(err, services.ErrDatePollClosed),
+		errors.Is(err, services.ErrDatePollNotDraft),
+		errors.Is(err, services.ErrIllegalTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetDatePoll handles GET /api/events/:id/poll (viewers only)
+func (pc *DatePollController) GetDatePoll(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	var viewerID *uuid.UUID
+	if userInterface, exists := c.Get("user"); exists {
+		user := userInterface.(models.User)
+		viewerID = &user.ID
+	}
+
+	poll, err := pc.pollService.GetPoll(event.ID, viewerID)
+	if err != nil {
+		respondDatePollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": poll})
+}
+
+// CreateDatePoll handles POST /api/events/:id/poll (organizer only)
+func (pc *DatePollController) CreateDatePoll(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	user := c.MustGet("user").(models.User)
+
+	var req CreateDatePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poll, err := pc.pollService.CreatePoll(&event, user.ID, req.Options)
+	if err != nil {
+		respondDatePollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": poll})
+}
+
+// DeleteDatePoll handles DELETE /api/events/:id/poll (organizer only)
+func (pc *DatePollController) DeleteDatePoll(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	if err := pc.pollService.DeletePoll(event.ID); err != nil {
+		respondDatePollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Date poll deleted successfully"})
+}
+
+// VoteDatePoll handles PUT /api/events/:id/poll/votes (viewers only)
+func (pc *DatePollController) VoteDatePoll(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	user := c.MustGet("user").(models.User)
+
+	var req DatePollVoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poll, err := pc.pollService.Vote(event.ID, user.ID, req.Votes)
+	if err != nil {
+		respondDatePollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": poll})
+}
+
+// FinalizeDatePoll handles POST /api/events/:id/poll/finalize (organizer only)
+func (pc *DatePollController) FinalizeDatePoll(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	user := c.MustGet("user").(models.User)
+
+	var req FinalizeDatePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updatedEvent, poll, err := pc.pollService.FinalizePoll(event.ID, user.ID, req.OptionID)
+	if err != nil {
+		respondDatePollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": updatedEvent, "poll": poll})
+}
@@ -1,9 +1,8 @@
 package controllers
 
 import (
-	"context"
-	"fmt"
-	"log"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -16,90 +15,166 @@ import (
 )
 
 type EventController struct {
-	eventService        *services.EventService
-	googlePhotosService *services.GooglePhotosService
+	eventService  *services.EventService
+	jobService    *services.JobService
+	memberService *services.MemberService
 }
 
 // NewEventController creates a new event controller
 func NewEventController() *EventController {
 	return &EventController{
-		eventService:        services.NewEventService(),
-		googlePhotosService: services.NewGooglePhotosService(),
+		eventService:  services.NewEventService(),
+		jobService:    services.NewJobService(),
+		memberService: services.NewMemberService(),
 	}
 }
 
+// CreateEventRequest is a new event, optionally with a duration instead of
+// an end date. Only the fields an organizer may set are listed, the same as
+// services.EventUpdate, and they are validated the same way by
+// EventService.CreateEvent; the rest are the server's to fill in.
+type CreateEventRequest struct {
+	Title               string      `json:"title"`
+	Description         string      `json:"description"`
+	Venue               string      `json:"venue"`
+	VenueName           string      `json:"venue_name"`
+	VenuePlaceID        string      `json:"venue_place_id"`
+	VenueLat            float64     `json:"venue_lat"`
+	VenueLng            float64     `json:"venue_lng"`
+	EventDate           time.Time   `json:"event_date"`
+	EndDate             *time.Time  `json:"end_date"`
+	DurationMinutes     int         `json:"duration_minutes"`
+	TimeZone            string      `json:"time_zone"`
+	Image               string      `json:"image"`
+	EventType           string      `json:"event_type"`
+	IsPublic            *bool       `json:"is_public"` // Public unless sent as false
+	MaxAttendees        int         `json:"max_attendees"`
+	MaxPlusOnes         int         `json:"max_plus_ones"`
+	GooglePhotosEnabled bool        `json:"google_photos_enabled"`
+	RecurrenceRule      string      `json:"recurrence_rule"`
+	RecurrenceExDates   []time.Time `json:"recurrence_exdates"`
+	ReminderOffsets     []int       `json:"reminder_offsets"`
+	Status              string      `json:"status"` // draft (default) or published
+}
+
+// event builds the new event the request describes
+func (r *CreateEventRequest) event() models.Event {
+	isPublic := true
+	if r.IsPublic != nil {
+		isPublic = *r.IsPublic
+	}
+	return models.Event{
+		Title:               r.Title,
+		Description:         r.Description,
+		Venue:               r.Venue,
+		VenueName:           r.VenueName,
+		VenuePlaceID:        r.VenuePlaceID,
+		VenueLat:            r.VenueLat,
+		VenueLng:            r.VenueLng,
+		EventDate:           r.EventDate,
+		EndDate:             r.EndDate,
+		TimeZone:            r.TimeZone,
+		Image:               r.Image,
+		EventType:           r.EventType,
+		IsPublic:            isPublic,
+		MaxAttendees:        r.MaxAttendees,
+		MaxPlusOnes:         r.MaxPlusOnes,
+		GooglePhotosEnabled: r.GooglePhotosEnabled,
+		RecurrenceRule:      r.RecurrenceRule,
+		RecurrenceExDates:   r.RecurrenceExDates,
+		ReminderOffsets:     r.ReminderOffsets,
+		Status:              r.Status,
+	}
+}
+
+// locationParam parses the optional tz query parameter (IANA name), defaulting to UTC
+func locationParam(c *gin.Context) (*time.Location, error) {
+	tz := c.Query("tz")
+	if tz == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(tz)
+}
+
+// viewerID returns the ID of the signed-in user, or nil for signed-out visitors
+func viewerID(c *gin.Context) *uuid.UUID {
+	if userInterface, exists := c.Get("user"); exists {
+		id := userInterface.(models.User).ID
+		return &id
+	}
+	return nil
+}
+
 // CreateEvent handles POST /api/events
 func (ec *EventController) CreateEvent(c *gin.Context) {
-	var event models.Event
-	if err := c.ShouldBindJSON(&event); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Get user from context (set by auth middleware)
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
+	user := userInterface.(models.User)
 
-	// Create the event first
-	if err := ec.eventService.CreateEvent(&event); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	var req CreateEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	event := req.event()
 
-	// Try to create Google Photos album if user wants it AND has connected Google Photos
-	ctx := context.Background()
-	if event.GooglePhotosEnabled {
-		log.Printf("User requested Google Photos album for event %v", event.ID)
-
-		hasGooglePhotos, err := ec.googlePhotosService.CheckUserHasGooglePhotos(event.UserID)
-		if err != nil {
-			log.Printf("❌ ERROR: Error checking Google Photos connection for user %v: %v", event.UserID, err)
-		} else if hasGooglePhotos {
-			log.Printf("User has Google Photos connected, creating album for event %v", event.ID)
-
-			// Create album title based on event title
-			albumTitle := fmt.Sprintf("%s - Photos", event.Title)
-
-			albumID, shareableURL, err := ec.googlePhotosService.CreateSharedAlbum(ctx, event.UserID, albumTitle)
-			if err != nil {
-				log.Printf("Failed to create Google Photos album for event %v: %v", event.ID, err)
-				// Continue without Google Photos album - don't fail the event creation
-			} else {
-				log.Printf("Successfully created Google Photos album %v for event %v", albumID, event.ID)
-
-				// Update the event with Google Photos album information
-				updates := map[string]interface{}{
-					"google_photos_album_id":  albumID,
-					"google_photos_album_url": shareableURL,
-				}
-
-				updatedEvent, updateErr := ec.eventService.UpdateEvent(event.ID, updates)
-				if updateErr != nil {
-					log.Printf("Failed to update event with Google Photos album info: %v", updateErr)
-				} else {
-					event = *updatedEvent // Use the updated event for response
-				}
-			}
-		} else {
-			log.Printf("⚠️  WARNING: User requested Google Photos album but hasn't connected Google Photos for event %v", event.ID)
+	if req.DurationMinutes != 0 {
+		if req.DurationMinutes < 0 || event.EndDate != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": services.ValidationErrors{
+				"duration_minutes": "must be positive and not sent together with end_date",
+			}})
+			return
 		}
+		end := event.EventDate.Add(time.Duration(req.DurationMinutes) * time.Minute)
+		event.EndDate = &end
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"data": event})
-}
+	// The organizer is always the authenticated user
+	event.UserID = user.ID
 
-// GetEvent handles GET /api/events/:id
-func (ec *EventController) GetEvent(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := uuid.Parse(idParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+	// New events start as a draft or are published right away, which is
+	// recorded in the status history; anything else is a transition
+	if event.Status == "" {
+		event.Status = models.EventStatusDraft
+	}
+	if event.Status != models.EventStatusDraft && event.Status != models.EventStatusPublished {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be draft or published"})
 		return
 	}
 
-	event, err := ec.eventService.GetEventByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	// A Google Photos album is created in the background, see GET /api/events/:id/jobs
+	if err := ec.eventService.CreateEvent(&event); err != nil {
+		var validationErrs services.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": event})
+	c.JSON(http.StatusCreated, gin.H{"data": event})
+}
+
+// GetEvent handles GET /api/events/:id (viewers only)
+func (ec *EventController) GetEvent(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	// Include the viewer's role so clients know which actions to offer
+	var role models.EventRole
+	if id := viewerID(c); id != nil {
+		var err error
+		if role, err = ec.memberService.GetRole(event.ID, *id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": event, "role": role})
 }
 
 // GetEvents handles GET /api/events
@@ -131,7 +206,7 @@ func (ec *EventController) GetEvents(c *gin.Context) {
 		userID = &id
 	}
 
-	events, total, err := ec.eventService.GetAllEvents(page, pageSize, eventType, status, userID)
+	events, total, err := ec.eventService.GetAllEvents(page, pageSize, eventType, status, userID, viewerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -210,7 +285,7 @@ func (ec *EventController) GetUpcomingEvents(c *gin.Context) {
 		userID = &id
 	}
 
-	events, total, err := ec.eventService.GetUpcomingEvents(page, pageSize, eventType, userID)
+	events, total, err := ec.eventService.GetUpcomingEvents(page, pageSize, eventType, userID, viewerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -249,7 +324,7 @@ func (ec *EventController) SearchEvents(c *gin.Context) {
 		pageSize = 10
 	}
 
-	events, total, err := ec.eventService.SearchEvents(searchTerm, page, pageSize)
+	events, total, err := ec.eventService.SearchEvents(searchTerm, page, pageSize, viewerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -277,17 +352,25 @@ func (ec *EventController) GetEventsByDateRange(c *gin.Context) {
 		return
 	}
 
-	startDate, err := time.Parse("2006-01-02", startDateStr)
+	loc, err := locationParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tz. Use an IANA time zone such as Europe/Berlin"})
+		return
+	}
+
+	// Dates are whole days on the caller's wall clock, end_date included
+	startDate, err := time.ParseInLocation("2006-01-02", startDateStr, loc)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format. Use YYYY-MM-DD"})
 		return
 	}
 
-	endDate, err := time.Parse("2006-01-02", endDateStr)
+	endDate, err := time.ParseInLocation("2006-01-02", endDateStr, loc)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format. Use YYYY-MM-DD"})
 		return
 	}
+	endDate = endDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
 
 	// Parse pagination parameters
 	pageStr := c.DefaultQuery("page", "1")
@@ -313,7 +396,7 @@ func (ec *EventController) GetEventsByDateRange(c *gin.Context) {
 		userID = &id
 	}
 
-	events, total, err := ec.eventService.GetEventsByDateRange(startDate, endDate, page, pageSize, userID)
+	events, total, err := ec.eventService.GetEventsByDateRange(startDate, endDate, page, pageSize, userID, viewerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -330,7 +413,7 @@ func (ec *EventController) GetEventsByDateRange(c *gin.Context) {
 	})
 }
 
-// UpdateEvent handles PUT /api/events/:id
+// UpdateEvent handles PUT and PATCH /api/events/:id (organizer only)
 func (ec *EventController) UpdateEvent(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
@@ -339,19 +422,27 @@ func (ec *EventController) UpdateEvent(c *gin.Context) {
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := c.ShouldBindJSON(&updates); err != nil {
+	var update services.EventUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": services.ValidationErrors{typeErr.Field: "has an invalid type"},
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Remove fields that shouldn't be updated directly
-	delete(updates, "id")
-	delete(updates, "created_at")
-	delete(updates, "user")
-
-	event, err := ec.eventService.UpdateEvent(id, updates)
+	event, err := ec.eventService.UpdateEvent(id, &update)
 	if err != nil {
+		var validationErrs services.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
@@ -359,7 +450,7 @@ func (ec *EventController) UpdateEvent(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": event})
 }
 
-// DeleteEvent handles DELETE /api/events/:id
+// DeleteEvent handles DELETE /api/events/:id (organizer only)
 func (ec *EventController) DeleteEvent(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
@@ -376,6 +467,217 @@ func (ec *EventController) DeleteEvent(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Event deleted successfully"})
 }
 
+// EventTransitionRequest is the optional body of the lifecycle endpoints
+type EventTransitionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// PublishEvent handles POST /api/events/:id/publish (organizer only)
+func (ec *EventController) PublishEvent(c *gin.Context) {
+	ec.transitionEvent(c, services.EventActionPublish)
+}
+
+// CancelEvent handles POST /api/events/:id/cancel (organizer only)
+func (ec *EventController) CancelEvent(c *gin.Context) {
+	ec.transitionEvent(c, services.EventActionCancel)
+}
+
+// ReopenEvent handles POST /api/events/:id/reopen (organizer only)
+func (ec *EventController) ReopenEvent(c *gin.Context) {
+	ec.transitionEvent(c, services.EventActionReopen)
+}
+
+func (ec *EventController) transitionEvent(c *gin.Context, action string) {
+	event := c.MustGet("event").(models.Event)
+	user := c.MustGet("user").(models.User)
+
+	// The body is optional, so an empty request is fine
+	var req EventTransitionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	updatedEvent, err := ec.eventService.TransitionEvent(event.ID, user.ID, action, req.Reason)
+	if err != nil {
+		if errors.Is(err, services.ErrIllegalTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": updatedEvent})
+}
+
+// GetEventStatusHistory handles GET /api/events/:id/status-history (organizer only)
+func (ec *EventController) GetEventStatusHistory(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	history, err := ec.eventService.GetStatusHistory(event.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": history})
+}
+
+// GetEventReminders handles GET /api/events/:id/reminders (organizer only)
+func (ec *EventController) GetEventReminders(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	reminders, err := ec.eventService.GetReminders(event.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": reminders})
+}
+
+// GetEventJobs handles GET /api/events/:id/jobs (organizer only)
+func (ec *EventController) GetEventJobs(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	jobs, err := ec.jobService.GetJobs(event.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": jobs})
+}
+
+// RetryEventJob handles POST /api/events/:id/jobs/:job/retry (organizer only)
+func (ec *EventController) RetryEventJob(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	jobID, err := uuid.Parse(c.Param("job"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := ec.jobService.RetryJob(event.ID, jobID)
+	if err != nil {
+		if errors.Is(err, services.ErrJobNotRetryable) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": job})
+}
+
+// OccurrenceOverrideRequest changes a single occurrence of a recurring event
+type OccurrenceOverrideRequest struct {
+	EventDate   *time.Time `json:"event_date"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Venue       string     `json:"venue"`
+}
+
+// GetEventOccurrences handles GET /api/events/:id/occurrences
+func (ec *EventController) GetEventOccurrences(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	loc, err := locationParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tz. Use an IANA time zone such as Europe/Berlin"})
+		return
+	}
+
+	startDate := time.Now()
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate, err = time.ParseInLocation("2006-01-02", startDateStr, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+	endDate := startDate.AddDate(1, 0, 0)
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate, err = time.ParseInLocation("2006-01-02", endDateStr, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format. Use YYYY-MM-DD"})
+			return
+		}
+		endDate = endDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	event, err := ec.eventService.GetEventByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	occurrences, err := ec.eventService.ExpandOccurrences([]models.Event{*event}, startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": occurrences})
+}
+
+// SetOccurrenceOverride handles PUT /api/events/:id/occurrences/:occurrence (organizer only)
+func (ec *EventController) SetOccurrenceOverride(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	occurrenceDate, err := time.Parse(time.RFC3339, c.Param("occurrence"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid occurrence. Use RFC 3339"})
+		return
+	}
+
+	var req OccurrenceOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	override, err := ec.eventService.SetOccurrenceOverride(&event, occurrenceDate, services.OccurrenceOverride{
+		EventDate:   req.EventDate,
+		Title:       req.Title,
+		Description: req.Description,
+		Venue:       req.Venue,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": override})
+}
+
+// DeleteOccurrenceOverride handles DELETE /api/events/:id/occurrences/:occurrence (organizer only)
+func (ec *EventController) DeleteOccurrenceOverride(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	occurrenceDate, err := time.Parse(time.RFC3339, c.Param("occurrence"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid occurrence. Use RFC 3339"})
+		return
+	}
+
+	if err := ec.eventService.DeleteOccurrenceOverride(event.ID, occurrenceDate); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Occurrence override removed"})
+}
+
 // GetUserEvents handles GET /api/users/:id/events
 func (ec *EventController) GetUserEvents(c *gin.Context) {
 	userIDParam := c.Param("id")
@@ -399,7 +701,7 @@ func (ec *EventController) GetUserEvents(c *gin.Context) {
 		pageSize = 10
 	}
 
-	events, total, err := ec.eventService.GetEventsByUser(userID, page, pageSize)
+	events, total, err := ec.eventService.GetEventsByUser(userID, page, pageSize, viewerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -440,7 +742,7 @@ func (ec *EventController) GetCurrentUserEvents(c *gin.Context) {
 		pageSize = 50
 	}
 
-	events, total, err := ec.eventService.GetEventsByUser(user.ID, page, pageSize)
+	events, total, err := ec.eventService.GetEventsByUser(user.ID, page, pageSize, &user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type InvitationController struct {
+	invitationService *services.InvitationService
+	eventService      *services.EventService
+}
+
+// NewInvitationController creates a new invitation controller
+func NewInvitationController() *InvitationController {
+	return &InvitationController{
+		invitationService: services.NewInvitationService(),
+		eventService:      services.NewEventService(),
+	}
+}
+
+// InviteRequest lists the addresses to invite
+type InviteRequest struct {
+	Emails []string `json:"emails"`
+}
+
+// AcceptInvitationRequest carries the token of an invite link
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// InvitationResponse is an invitation with the personal link to send to the invitee
+type InvitationResponse struct {
+	models.EventInvitation
+	InviteURL string `json:"invite_url,omitempty"`
+}
+
+// inviteURL links to the event page with an invite token
+func inviteURL(c *gin.Context, eventID uuid.UUID, token string) string {
+	return baseURL(c) + "/events/" + eventID.String() + "?invite=" + token
+}
+
+func (ic *InvitationController) withLinks(c *gin.Context, invitations []models.EventInvitation) []InvitationResponse {
+	responses := make([]InvitationResponse, 0, len(invitations))
+	for i := range invitations {
+		response := InvitationResponse{EventInvitation: invitations[i]}
+		if invitations[i].Via == models.InvitationViaEmail {
+			response.InviteURL = inviteURL(c, invitations[i].EventID, ic.invitationService.InvitationToken(&invitations[i]))
+		}
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+// InviteByEmail handles POST /api/events/:id/invitations (organizer only)
+func (ic *InvitationController) InviteByEmail(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	user := c.MustGet("user").(models.User)
+
+	var req InviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitations, err := ic.invitationService.InviteByEmail(&event, user.ID, req.Emails)
+	if err != nil {
+		var validationErrs services.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": ic.withLinks(c, invitations)})
+}
+
+// GetEventInvitations handles GET /api/events/:id/invitations (organizer only)
+func (ic *InvitationController) GetEventInvitations(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	invitations, err := ic.invitationService.GetEventInvitations(event.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	counts := map[models.InvitationStatus]int{
+		models.InvitationStatusPending:   0,
+		models.InvitationStatusSent:      0,
+		models.InvitationStatusOpened:    0,
+		models.InvitationStatusResponded: 0,
+	}
+	for _, invitation := range invitations {
+		counts[invitation.Status]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   ic.withLinks(c, invitations),
+		"counts": counts,
+	})
+}
+
+// DeleteInvitation handles DELETE /api/events/:id/invitations/:invitation (organizer only)
+func (ic *InvitationController) DeleteInvitation(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	invitationID, err := uuid.Parse(c.Param("invitation"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	if err := ic.invitationService.DeleteInvitation(event.ID, invitationID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation deleted successfully"})
+}
+
+// GetInviteLink handles GET /api/events/:id/invite-link (organizer only)
+func (ic *InvitationController) GetInviteLink(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	c.JSON(http.StatusOK, gin.H{"url": inviteURL(c, event.ID, ic.invitationService.InviteLinkToken(&event))})
+}
+
+// ResetInviteLink handles POST /api/events/:id/invite-link/reset (organizer only)
+func (ic *InvitationController) ResetInviteLink(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	updated, err := ic.invitationService.ResetInviteLink(event.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"url": inviteURL(c, updated.ID, ic.invitationService.InviteLinkToken(updated))})
+}
+
+// AcceptInvitation handles POST /api/events/:id/invitations/accept
+func (ic *InvitationController) AcceptInvitation(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	eventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event, err := ic.eventService.GetEventByID(eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	invitation, err := ic.invitationService.AcceptInvitation(&user, event, req.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInvitation) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": invitation, "event": event})
+}
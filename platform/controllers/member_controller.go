@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type MemberController struct {
+	memberService *services.MemberService
+	userService   *services.UserService
+}
+
+// NewMemberController creates a new member controller
+func NewMemberController() *MemberController {
+	return &MemberController{
+		memberService: services.NewMemberService(),
+		userService:   services.NewUserService(),
+	}
+}
+
+// MemberRequest names a user by ID or email and the role to give them
+type MemberRequest struct {
+	UserID *uuid.UUID       `json:"user_id"`
+	Email  string           `json:"email"`
+	Role   models.EventRole `json:"role" binding:"required"`
+}
+
+// TransferOwnershipRequest names the new owner by ID or email
+type TransferOwnershipRequest struct {
+	UserID *uuid.UUID `json:"user_id"`
+	Email  string     `json:"email"`
+}
+
+// resolveUser finds the user a request refers to by ID or email
+func (mc *MemberController) resolveUser(userID *uuid.UUID, email string) (*models.User, error) {
+	switch {
+	case userID != nil:
+		return mc.userService.GetUserByID(*userID)
+	case email != "":
+		return mc.userService.GetUserByEmail(email)
+	default:
+		return nil, errors.New("user_id or email is required")
+	}
+}
+
+// respondMemberError maps member service errors to responses
+func respondMemberError(c *gin.Context, err error) {
+	var validationErrs services.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+	case errors.Is(err, services.ErrOwnerMembership):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetMembers handles GET /api/events/:id/members (owner and co-hosts)
+func (mc *MemberController) GetMembers(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	members, err := mc.memberService.GetMembers(event.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": members})
+}
+
+// SetMember handles POST /api/events/:id/members (owner only)
+func (mc *MemberController) SetMember(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	user := c.MustGet("user").(models.User)
+
+	var req MemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := mc.resolveUser(req.UserID, req.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": gin.H{"user": "not found"}})
+		return
+	}
+
+	created, err := mc.memberService.SetMember(event.ID, member.ID, req.Role, user.ID)
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": created})
+}
+
+// RemoveMember handles DELETE /api/events/:id/members/:user (owner only)
+func (mc *MemberController) RemoveMember(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	userID, err := uuid.Parse(c.Param("user"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := mc.memberService.RemoveMember(event.ID, userID); err != nil {
+		if errors.Is(err, services.ErrOwnerMembership) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// TransferOwnership handles POST /api/events/:id/transfer-ownership (owner only)
+func (mc *MemberController) TransferOwnership(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+	user := c.MustGet("user").(models.User)
+
+	var req TransferOwnershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	owner, err := mc.resolveUser(req.UserID, req.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": gin.H{"user": "not found"}})
+		return
+	}
+
+	updated, err := mc.memberService.TransferOwnership(event.ID, owner.ID, user.ID)
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": updated})
+}
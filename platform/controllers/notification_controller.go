@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationController struct {
+	notificationService *services.NotificationService
+}
+
+// NewNotificationController creates a new notification controller
+func NewNotificationController() *NotificationController {
+	return &NotificationController{
+		notificationService: services.NewNotificationService(),
+	}
+}
+
+// GetSettings handles GET /api/user/notifications
+func (nc *NotificationController) GetSettings(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	settings, err := nc.notificationService.GetSettings(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": settings})
+}
+
+// UpdateSettings handles PUT /api/user/notifications. Only the settings sent are changed.
+func (nc *NotificationController) UpdateSettings(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var req services.NotificationSettingsUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := nc.notificationService.UpdateSettings(user.ID, &req)
+	if err != nil {
+		var validationErrs services.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": settings})
+}
+
+// Unsubscribe handles POST /api/notifications/unsubscribe, the one-click
+// unsubscribe mail clients call from the List-Unsubscribe header. The signed
+// token is the only credential, so no session is needed.
+func (nc *NotificationController) Unsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.PostForm("token")
+	}
+
+	category, err := nc.notificationService.Unsubscribe(token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUnsubscribe) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed successfully", "category": category})
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type QuestionController struct {
+	questionService *services.QuestionService
+}
+
+// NewQuestionController creates a new question controller
+func NewQuestionController() *QuestionController {
+	return &QuestionController{
+		questionService: services.NewQuestionService(),
+	}
+}
+
+// QuestionsRequest is the full question schema of an event
+type QuestionsRequest struct {
+	Questions []models.EventQuestion `json:"questions"`
+}
+
+// GetEventQuestions handles GET /api/events/:id/questions
+func (qc *QuestionController) GetEventQuestions(c *gin.Context) {
+	eventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	questions, err := qc.questionService.GetEventQuestions(eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": questions})
+}
+
+// ReplaceEventQuestions handles PUT /api/events/:id/questions (organizer only)
+func (qc *QuestionController) ReplaceEventQuestions(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	var req QuestionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	questions, err := qc.questionService.ReplaceEventQuestions(event.ID, req.Questions)
+	if err != nil {
+		var validationErrs services.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": questions})
+}
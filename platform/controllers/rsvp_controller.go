@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
+	"01-Login/platform/authorization"
 	"01-Login/platform/models"
 	"01-Login/platform/services"
 
@@ -11,19 +14,41 @@ import (
 )
 
 type RSVPController struct {
-	rsvpService  *services.RSVPService
-	eventService *services.EventService
+	rsvpService     *services.RSVPService
+	eventService    *services.EventService
+	questionService *services.QuestionService
+	memberService   *services.MemberService
 }
 
 func NewRSVPController() *RSVPController {
 	return &RSVPController{
-		rsvpService:  services.NewRSVPService(),
-		eventService: services.NewEventService(),
+		rsvpService:     services.NewRSVPService(),
+		eventService:    services.NewEventService(),
+		questionService: services.NewQuestionService(),
+		memberService:   services.NewMemberService(),
 	}
 }
 
 type RSVPRequest struct {
-	Response string `json:"response" binding:"required"`
+	Response   string             `json:"response" binding:"required"`
+	GuestCount int                `json:"guest_count"` // Defaults to 1, the guest alone
+	Companions []string           `json:"companions"`
+	Answers    models.RSVPAnswers `json:"answers"` // Keyed by question ID
+	// Required for recurring events: the original start of the occurrence
+	OccurrenceDate *time.Time `json:"occurrence_date"`
+}
+
+// occurrenceParam parses the optional occurrence_date query parameter (RFC 3339)
+func occurrenceParam(c *gin.Context) (*time.Time, error) {
+	value := c.Query("occurrence_date")
+	if value == "" {
+		return nil, nil
+	}
+	occurrence, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &occurrence, nil
 }
 
 // SubmitRSVP handles RSVP submission
@@ -65,72 +90,114 @@ func (rc *RSVPController) SubmitRSVP(c *gin.Context) {
 		return
 	}
 
-	// Check if user is not the organizer
-	if event.UserID == user.ID {
+	// Only published events take RSVPs
+	switch event.Status {
+	case models.EventStatusPublished:
+	case models.EventStatusCancelled:
+		c.JSON(http.StatusConflict, gin.H{"error": "This event has been cancelled"})
+		return
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "This event is not published"})
+		return
+	}
+
+	// Check if user is not one of the organizers
+	role, err := rc.memberService.GetRole(event.ID, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit RSVP"})
+		return
+	}
+	if authorization.CanManageEvent(role) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Event organizers cannot RSVP to their own events"})
 		return
 	}
 
 	// Create or update RSVP
-	rsvp, err := rc.rsvpService.CreateOrUpdateRSVP(user.ID, eventID, response)
+	rsvp, err := rc.rsvpService.CreateOrUpdateRSVP(user.ID, eventID, services.RSVPSubmission{
+		Response:       response,
+		GuestCount:     req.GuestCount,
+		Companions:     req.Companions,
+		Answers:        req.Answers,
+		OccurrenceDate: req.OccurrenceDate,
+	})
 	if err != nil {
+		var validationErrs services.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+			return
+		}
+		if errors.Is(err, services.ErrNotEnoughSeats) || errors.Is(err, services.ErrEventNotOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit RSVP"})
 		return
 	}
 
+	if rsvp.Response == models.RSVPResponseWaitlisted {
+		position, err := rc.rsvpService.GetWaitlistPosition(rsvp)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get waitlist position"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":           "Event is full, you have been added to the waitlist",
+			"rsvp":              rsvp,
+			"waitlist_position": position,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "RSVP submitted successfully",
 		"rsvp":    rsvp,
 	})
 }
 
-// GetEventRSVPs gets all RSVPs for an event (organizer only)
+// GetEventRSVPs gets all RSVPs for an event (organizers and check-in staff, enforced by middleware)
 func (rc *RSVPController) GetEventRSVPs(c *gin.Context) {
-	// Get event ID from URL
-	eventIDStr := c.Param("id")
-	eventID, err := uuid.Parse(eventIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
-		return
-	}
-
-	// Get user from context
-	userInterface, exists := c.Get("user")
+	// Get event from context (set by event permission middleware)
+	eventInterface, exists := c.Get("event")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only event organizers and check-in staff can view RSVPs"})
 		return
 	}
-	user := userInterface.(models.User)
+	event := eventInterface.(models.Event)
+	eventID := event.ID
 
-	// Check if event exists and user is organizer
-	event, err := rc.eventService.GetEventByID(eventID)
+	occurrence, err := occurrenceParam(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
-		return
-	}
-
-	if event.UserID != user.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event organizers can view RSVPs"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid occurrence_date. Use RFC 3339"})
 		return
 	}
 
 	// Get RSVPs
-	rsvps, err := rc.rsvpService.GetEventRSVPs(eventID)
+	rsvps, err := rc.rsvpService.GetEventRSVPs(eventID, occurrence)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get RSVPs"})
 		return
 	}
 
 	// Get RSVP counts
-	counts, err := rc.rsvpService.GetRSVPCounts(eventID)
+	counts, err := rc.rsvpService.GetRSVPCounts(eventID, occurrence)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get RSVP counts"})
 		return
 	}
 
+	// Get custom questions and aggregate the choice answers
+	questions, err := rc.questionService.GetEventQuestions(eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get questions"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"rsvps":  rsvps,
-		"counts": counts,
+		"rsvps":          rsvps,
+		"counts":         counts.Responses,
+		"headcount":      counts.Headcount,
+		"questions":      questions,
+		"answer_summary": rc.questionService.SummarizeAnswers(questions, rsvps),
 	})
 }
 
@@ -152,8 +219,14 @@ func (rc *RSVPController) GetUserRSVP(c *gin.Context) {
 	}
 	user := userInterface.(models.User)
 
+	occurrence, err := occurrenceParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid occurrence_date. Use RFC 3339"})
+		return
+	}
+
 	// Get user's RSVP for this event
-	rsvp, err := rc.rsvpService.GetRSVP(user.ID, eventID)
+	rsvp, err := rc.rsvpService.GetRSVP(user.ID, eventID, occurrence)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get RSVP"})
 		return
@@ -164,7 +237,17 @@ func (rc *RSVPController) GetUserRSVP(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"rsvp": rsvp})
+	position, err := rc.rsvpService.GetWaitlistPosition(rsvp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get waitlist position"})
+		return
+	}
+
+	response := gin.H{"rsvp": rsvp}
+	if position > 0 {
+		response["waitlist_position"] = position
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetUserRSVPs gets all RSVPs for the authenticated user
@@ -185,7 +268,7 @@ func (rc *RSVPController) GetUserRSVPs(c *gin.Context) {
 
 	if responseFilter != "" {
 		response := models.RSVPResponse(responseFilter)
-		if response != models.RSVPResponseYes && response != models.RSVPResponseNo && response != models.RSVPResponseMaybe {
+		if response != models.RSVPResponseYes && response != models.RSVPResponseNo && response != models.RSVPResponseMaybe && response != models.RSVPResponseWaitlisted {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid response filter"})
 			return
 		}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"01-Login/platform/models"
+	"01-Login/platform/sessionstore"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SessionController struct {
+	store *sessionstore.Store
+}
+
+// NewSessionController creates a new session controller on the app's session store
+func NewSessionController(store *sessionstore.Store) *SessionController {
+	return &SessionController{store: store}
+}
+
+// SessionResponse is one of the user's signed-in devices
+type SessionResponse struct {
+	models.Session
+	Current bool `json:"current"` // The session making the request
+}
+
+// GetSessions handles GET /api/user/sessions
+func (sc *SessionController) GetSessions(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	list, err := sc.store.ListUserSessions(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentID := sessions.Default(c).ID()
+	response := make([]SessionResponse, 0, len(list))
+	for _, session := range list {
+		response = append(response, SessionResponse{
+			Session: session,
+			Current: session.ID.String() == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// RevokeSession handles DELETE /api/user/sessions/:session
+func (sc *SessionController) RevokeSession(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	sessionID, err := uuid.Parse(c.Param("session"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	if err := sc.store.RevokeUserSession(user.ID, sessionID); err != nil {
+		if errors.Is(err, sessionstore.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// RevokeOtherSessions handles DELETE /api/user/sessions, signing the user
+// out on every device but this one
+func (sc *SessionController) RevokeOtherSessions(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	currentID, _ := uuid.Parse(sessions.Default(c).ID())
+	revoked, err := sc.store.RevokeOtherUserSessions(user.ID, currentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"revoked": revoked}})
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"01-Login/platform/authorization"
+	"01-Login/platform/models"
+	"01-Login/platform/realtime"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// streamHeartbeat is how often an idle stream sends a comment, so proxies
+// keep it open, and checks that the viewer may still see the event
+const streamHeartbeat = 30 * time.Second
+
+type StreamController struct {
+	eventService      *services.EventService
+	rsvpService       *services.RSVPService
+	memberService     *services.MemberService
+	invitationService *services.InvitationService
+}
+
+// NewStreamController creates a new stream controller
+func NewStreamController() *StreamController {
+	return &StreamController{
+		eventService:      services.NewEventService(),
+		rsvpService:       services.NewRSVPService(),
+		memberService:     services.NewMemberService(),
+		invitationService: services.NewInvitationService(),
+	}
+}
+
+// eventStream is one client watching an event
+type eventStream struct {
+	eventID    uuid.UUID
+	user       *models.User
+	occurrence *time.Time
+}
+
+// StreamEvent handles GET /api/events/:id/stream (viewers only), a
+// Server-Sent Events stream sending the event, then again whenever it is
+// edited or changes status. Members who may see the guest list also get the
+// RSVP counts (for one occurrence with occurrence_date). Access is checked
+// again on every change and heartbeat, and the stream ends once the viewer
+// may no longer see the event or it is deleted.
+func (sc *StreamController) StreamEvent(c *gin.Context) {
+	event := c.MustGet("event").(models.Event)
+
+	occurrence, err := occurrenceParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid occurrence_date. Use RFC 3339"})
+		return
+	}
+
+	stream := &eventStream{eventID: event.ID, occurrence: occurrence}
+	if userInterface, exists := c.Get("user"); exists {
+		user := userInterface.(models.User)
+		stream.user = &user
+	}
+
+	// Subscribe before loading, so changes made meanwhile aren't missed
+	sub := realtime.Subscribe(event.ID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+	c.Status(http.StatusOK)
+
+	if !sc.push(c, stream, []realtime.Kind{realtime.KindEvent, realtime.KindRSVPs}) {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-sub.Ready():
+			return sc.push(c, stream, sub.Take())
+		case <-heartbeat.C:
+			if _, ok := sc.viewableEvent(c, stream); !ok {
+				return false
+			}
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		}
+	})
+}
+
+// push sends what the changes call for, reporting whether the stream should go on
+func (sc *StreamController) push(c *gin.Context, stream *eventStream, kinds []realtime.Kind) bool {
+	for _, kind := range kinds {
+		if kind == realtime.KindDeleted {
+			c.SSEvent("deleted", gin.H{"id": stream.eventID})
+			return false
+		}
+	}
+
+	event, ok := sc.viewableEvent(c, stream)
+	if !ok {
+		return false
+	}
+
+	changed := make(map[realtime.Kind]bool, len(kinds))
+	for _, kind := range kinds {
+		changed[kind] = true
+	}
+
+	if changed[realtime.KindStatus] {
+		status := gin.H{"status": event.Status}
+		if event.Status == models.EventStatusCancelled {
+			transition, err := sc.eventService.GetLatestTransition(event.ID, models.EventStatusCancelled)
+			if err != nil {
+				log.Printf("Error loading cancellation for event %v: %v", event.ID, err)
+			} else if transition != nil {
+				status["reason"] = transition.Reason
+			}
+		}
+		c.SSEvent("status", status)
+	}
+	if changed[realtime.KindEvent] || changed[realtime.KindStatus] {
+		c.SSEvent("event", gin.H{"data": event})
+	}
+
+	// Edits can promote waitlisted guests and status changes void RSVPs, so
+	// any change may move the counts
+	if len(kinds) > 0 && stream.user != nil {
+		role, err := sc.memberService.GetRole(event.ID, stream.user.ID)
+		if err != nil {
+			log.Printf("Error loading role on event %v: %v", event.ID, err)
+			return false
+		}
+		if authorization.EventRoleAllows(role, authorization.EventPermissionViewGuests) {
+			counts, err := sc.rsvpService.GetRSVPCounts(event.ID, stream.occurrence)
+			if err != nil {
+				log.Printf("Error loading RSVP counts for event %v: %v", event.ID, err)
+				return false
+			}
+			c.SSEvent("counts", gin.H{"counts": counts.Responses, "headcount": counts.Headcount})
+		}
+	}
+
+	c.Writer.Flush()
+	return true
+}
+
+// viewableEvent reloads the event if the viewer may still see it. If they
+// may not, the client is told the event is gone, the same as when it is
+// deleted, so private events don't reveal that they exist. Errors end the
+// stream, and clients reconnect.
+func (sc *StreamController) viewableEvent(c *gin.Context, stream *eventStream) (*models.Event, bool) {
+	event, err := sc.eventService.GetEventByID(stream.eventID)
+	if err != nil {
+		log.Printf("Error loading streamed event %v: %v", stream.eventID, err)
+		return nil, false
+	}
+
+	canView, err := sc.invitationService.CanView(stream.user, event)
+	if err != nil {
+		log.Printf("Error checking access to event %v: %v", event.ID, err)
+		return nil, false
+	}
+	if !canView {
+		c.SSEvent("deleted", gin.H{"id": stream.eventID})
+		return nil, false
+	}
+	return event, true
+}
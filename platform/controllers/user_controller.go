@@ -2,6 +2,8 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -29,7 +31,8 @@ var (
 )
 
 type UserController struct {
-	userService *services.UserService
+	userService  *services.UserService
+	adminService *services.AdminService
 	// Add oauth2.Config if you prefer to initialize it once
 	googleOAuthConfig *oauth2.Config
 }
@@ -37,7 +40,8 @@ type UserController struct {
 // NewUserController creates a new user controller
 func NewUserController() *UserController {
 	return &UserController{
-		userService: services.NewUserService(),
+		userService:  services.NewUserService(),
+		adminService: services.NewAdminService(),
 		googleOAuthConfig: &oauth2.Config{
 			ClientID:     googleClientID,
 			ClientSecret: googleClientSecret,
@@ -206,20 +210,24 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := c.ShouldBindJSON(&updates); err != nil {
+	// Only the profile fields are bound; role and activation go through the admin API
+	var update services.UserUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": services.ValidationErrors{typeErr.Field: "has an invalid type"},
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Remove fields that shouldn't be updated directly
-	delete(updates, "id")
-	delete(updates, "created_at")
-	delete(updates, "auth_id")
-
-	user, err := uc.userService.UpdateUser(id, updates)
+	user, err := uc.adminService.UpdateUser(auditInfo(c, ""), id, &update)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondAdminError(c, err)
 		return
 	}
 
@@ -235,8 +243,8 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := uc.userService.DeleteUser(id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	if err := uc.adminService.DeleteUser(auditInfo(c, ""), id); err != nil {
+		respondAdminError(c, err)
 		return
 	}
 
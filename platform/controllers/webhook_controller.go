@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WebhookController struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookController creates a new webhook controller
+func NewWebhookController() *WebhookController {
+	return &WebhookController{
+		webhookService: services.NewWebhookService(),
+	}
+}
+
+// respondWebhookError maps webhook service errors to responses
+func respondWebhookError(c *gin.Context, err error) {
+	var validationErrs services.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": validationErrs})
+	case errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// webhookParam parses the :webhook path parameter
+func webhookParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("webhook"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// CreateWebhook handles POST /api/user/webhooks. The response carries the
+// signing secret, which can't be fetched again.
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var req services.WebhookInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, secret, err := wc.webhookService.CreateWebhook(user.ID, &req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": webhook, "secret": secret})
+}
+
+// GetWebhooks handles GET /api/user/webhooks
+func (wc *WebhookController) GetWebhooks(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	webhooks, err := wc.webhookService.GetWebhooks(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhooks, "event_types": models.WebhookEventTypes})
+}
+
+// GetWebhook handles GET /api/user/webhooks/:webhook
+func (wc *WebhookController) GetWebhook(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	id, ok := webhookParam(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := wc.webhookService.GetWebhook(user.ID, id)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhook})
+}
+
+// UpdateWebhook handles PATCH /api/user/webhooks/:webhook. Only the fields sent are changed.
+func (wc *WebhookController) UpdateWebhook(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	id, ok := webhookParam(c)
+	if !ok {
+		return
+	}
+
+	var req services.WebhookInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := wc.webhookService.UpdateWebhook(user.ID, id, &req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhook})
+}
+
+// DeleteWebhook handles DELETE /api/user/webhooks/:webhook
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	id, ok := webhookParam(c)
+	if !ok {
+		return
+	}
+
+	if err := wc.webhookService.DeleteWebhook(user.ID, id); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetDeliveries handles GET /api/user/webhooks/:webhook/deliveries
+func (wc *WebhookController) GetDeliveries(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	id, ok := webhookParam(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	deliveries, total, err := wc.webhookService.GetDeliveries(user.ID, id, page, pageSize)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": deliveries,
+		"pagination": gin.H{
+			"page":        page,
+			"page_size":   pageSize,
+			"total":       total,
+			"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Redeliver handles POST /api/user/webhooks/:webhook/deliveries/:delivery/redeliver
+func (wc *WebhookController) Redeliver(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	id, ok := webhookParam(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := uuid.Parse(c.Param("delivery"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	delivery, err := wc.webhookService.Redeliver(user.ID, id, deliveryID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"data": delivery})
+}
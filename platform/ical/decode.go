@@ -0,0 +1,337 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotCalendar is returned when the input has no VCALENDAR object
+var ErrNotCalendar = errors.New("not an iCalendar file")
+
+// property is one unfolded content line: NAME;PARAM=value:VALUE
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Decode parses the VEVENTs of a calendar. Components other than VEVENT
+// (VTIMEZONE, VTODO, VALARM, ...) are skipped. Date-times are resolved to
+// instants using their TZID, falling back to the calendar's X-WR-TIMEZONE and
+// then UTC for zones Go doesn't know and for floating times.
+func Decode(r io.Reader) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var calendar Calendar
+	defaultZone := time.UTC
+	var raw [][]property // properties of each VEVENT, resolved once the whole calendar is read
+	var current []property
+	var stack []string
+	found := false
+
+	for _, line := range lines {
+		prop, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			component := strings.ToUpper(prop.value)
+			if component == "VCALENDAR" {
+				found = true
+			}
+			if component == "VEVENT" && len(stack) == 1 {
+				current = []property{}
+			}
+			stack = append(stack, component)
+			continue
+		case "END":
+			if len(stack) == 0 {
+				continue
+			}
+			if stack[len(stack)-1] == "VEVENT" && len(stack) == 2 {
+				raw = append(raw, current)
+				current = nil
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		switch {
+		case len(stack) == 1 && stack[0] == "VCALENDAR":
+			switch prop.name {
+			case "PRODID":
+				calendar.ProdID = prop.value
+			case "METHOD":
+				calendar.Method = prop.value
+			case "X-WR-CALNAME":
+				calendar.Name = unescapeText(prop.value)
+			case "X-WR-TIMEZONE":
+				if loc, err := time.LoadLocation(prop.value); err == nil {
+					defaultZone = loc
+				}
+			}
+		case len(stack) == 2 && stack[1] == "VEVENT":
+			current = append(current, prop)
+		}
+	}
+
+	if !found {
+		return nil, ErrNotCalendar
+	}
+
+	for _, props := range raw {
+		calendar.Events = append(calendar.Events, decodeEvent(props, defaultZone))
+	}
+	return &calendar, nil
+}
+
+func decodeEvent(props []property, defaultZone *time.Location) Event {
+	var event Event
+	var duration time.Duration
+
+	for _, prop := range props {
+		switch prop.name {
+		case "UID":
+			event.UID = prop.value
+		case "SUMMARY":
+			event.Summary = unescapeText(prop.value)
+		case "DESCRIPTION":
+			event.Description = unescapeText(prop.value)
+		case "LOCATION":
+			event.Location = unescapeText(prop.value)
+		case "URL":
+			event.URL = prop.value
+		case "STATUS":
+			event.Status = strings.ToUpper(prop.value)
+		case "CLASS":
+			event.Class = strings.ToUpper(prop.value)
+		case "RRULE":
+			event.RRule = prop.value
+		case "DTSTART":
+			event.Start, event.AllDay, event.TZID = parseDateTime(prop, defaultZone)
+		case "DTEND":
+			event.End, _, _ = parseDateTime(prop, defaultZone)
+		case "DURATION":
+			duration, _ = parseDuration(prop.value)
+		case "RECURRENCE-ID":
+			event.RecurrenceID, _, _ = parseDateTime(prop, defaultZone)
+		case "EXDATE":
+			for _, value := range strings.Split(prop.value, ",") {
+				single := prop
+				single.value = value
+				if t, _, _ := parseDateTime(single, defaultZone); !t.IsZero() {
+					event.ExDates = append(event.ExDates, t)
+				}
+			}
+		case "GEO":
+			parts := strings.Split(prop.value, ";")
+			if len(parts) == 2 {
+				lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+				lng, errLng := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+				if errLat == nil && errLng == nil {
+					event.Geo = &Geo{Lat: lat, Lng: lng}
+				}
+			}
+		case "ORGANIZER":
+			event.Organizer = &Organizer{
+				Name:  prop.params["CN"],
+				Email: strings.TrimPrefix(strings.TrimPrefix(prop.value, "mailto:"), "MAILTO:"),
+			}
+		case "CREATED":
+			event.Created, _, _ = parseDateTime(prop, defaultZone)
+		case "LAST-MODIFIED":
+			event.LastModified, _, _ = parseDateTime(prop, defaultZone)
+		case "SEQUENCE":
+			event.Sequence, _ = strconv.Atoi(prop.value)
+		}
+	}
+
+	if event.End.IsZero() && duration > 0 && !event.Start.IsZero() {
+		event.End = event.Start.Add(duration)
+	}
+	return event
+}
+
+// unfold reads content lines, joining folded continuation lines
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits a content line into name, parameters and value. Colons
+// and semicolons inside quoted parameter values don't count as separators.
+func parseLine(line string) (property, bool) {
+	quoted := false
+	valueAt := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ':' && !quoted {
+			valueAt = i
+			break
+		}
+	}
+	if valueAt < 0 {
+		return property{}, false
+	}
+
+	prop := property{value: line[valueAt+1:], params: map[string]string{}}
+	head := splitUnquoted(line[:valueAt], ';')
+	prop.name = strings.ToUpper(head[0])
+	for _, param := range head[1:] {
+		if eq := strings.IndexByte(param, '='); eq > 0 {
+			prop.params[strings.ToUpper(param[:eq])] = strings.Trim(param[eq+1:], `"`)
+		}
+	}
+	return prop, true
+}
+
+func splitUnquoted(s string, sep rune) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseDateTime parses a DATE or DATE-TIME value. It returns the instant,
+// whether it was a whole-day DATE, and the zone it was given in if Go knows it.
+func parseDateTime(prop property, defaultZone *time.Location) (time.Time, bool, string) {
+	value := strings.TrimSpace(prop.value)
+
+	loc := defaultZone
+	tzid := ""
+	if defaultZone != time.UTC {
+		tzid = defaultZone.String()
+	}
+	if name := prop.params["TZID"]; name != "" {
+		if zone, err := time.LoadLocation(strings.TrimPrefix(name, "/")); err == nil {
+			loc = zone
+			tzid = zone.String()
+		}
+	}
+
+	if prop.params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		if err != nil {
+			return time.Time{}, false, ""
+		}
+		return t, true, tzid
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, false, ""
+		}
+		return t, false, tzid
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false, ""
+	}
+	return t, false, tzid
+}
+
+// parseDuration parses a DURATION value such as PT1H30M, P1D or P2W
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	if s == value || strings.HasPrefix(value, "-") {
+		return 0, errors.New("invalid duration")
+	}
+
+	var total time.Duration
+	inTime := false
+	number := ""
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, errors.New("invalid duration")
+		}
+		number = ""
+
+		switch {
+		case c == 'W' && !inTime:
+			total += time.Duration(n) * 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			total += time.Duration(n) * 24 * time.Hour
+		case c == 'H' && inTime:
+			total += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			total += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			total += time.Duration(n) * time.Second
+		default:
+			return 0, errors.New("invalid duration")
+		}
+	}
+	if number != "" {
+		return 0, errors.New("invalid duration")
+	}
+	return total, nil
+}
+
+// unescapeText reverses escapeText
+func unescapeText(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, c := range s {
+		if escaped {
+			switch c {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteRune(c)
+			}
+			escaped = false
+			continue
+		}
+		if c == '\\' {
+			escaped = true
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,222 @@
+// Package ical reads and writes the subset of iCalendar (RFC 5545) used for
+// exchanging events with calendar apps: VCALENDAR objects holding VEVENTs.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event statuses
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Access classifications
+const (
+	ClassPublic       = "PUBLIC"
+	ClassPrivate      = "PRIVATE"
+	ClassConfidential = "CONFIDENTIAL"
+)
+
+// Calendar is a VCALENDAR object
+type Calendar struct {
+	ProdID string
+	Name   string // X-WR-CALNAME, shown by calendar apps for subscribed feeds
+	Method string // e.g. PUBLISH; empty to omit
+	Events []Event
+}
+
+// Event is a VEVENT
+type Event struct {
+	UID          string
+	Summary      string
+	Description  string
+	Location     string
+	URL          string
+	Start        time.Time
+	End          time.Time // zero to omit
+	AllDay       bool      // Start and End are whole days (VALUE=DATE)
+	TZID         string    // when set, times are written on this zone's wall clock
+	Geo          *Geo
+	Organizer    *Organizer
+	Status       string
+	Class        string // empty to omit, which means PUBLIC
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID time.Time // set on a changed occurrence of a series, sharing the series' UID
+	Created      time.Time
+	LastModified time.Time
+	Sequence     int
+}
+
+// Geo is the GEO property
+type Geo struct {
+	Lat float64
+	Lng float64
+}
+
+// Organizer is the ORGANIZER property
+type Organizer struct {
+	Name  string
+	Email string
+}
+
+// Encode writes the calendar in iCalendar format with CRLF line endings and
+// lines folded at 75 octets, along with a VTIMEZONE for each TZID used
+func (c *Calendar) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	lw := &lineWriter{w: bw}
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + c.ProdID)
+	lw.line("CALSCALE:GREGORIAN")
+	if c.Method != "" {
+		lw.line("METHOD:" + c.Method)
+	}
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+
+	tzids, earliest := c.timeZones()
+	for _, tzid := range tzids {
+		encodeTimeZone(lw, tzid, earliest[tzid])
+	}
+
+	stamp := time.Now()
+	for i := range c.Events {
+		c.Events[i].encode(lw, stamp)
+	}
+
+	lw.line("END:VCALENDAR")
+	if lw.err != nil {
+		return lw.err
+	}
+	return bw.Flush()
+}
+
+func (e *Event) encode(lw *lineWriter, stamp time.Time) {
+	lw.line("BEGIN:VEVENT")
+	lw.line("UID:" + e.UID)
+	lw.line("DTSTAMP:" + formatUTC(stamp))
+	lw.line(e.dateTime("DTSTART", e.Start))
+	if !e.End.IsZero() {
+		lw.line(e.dateTime("DTEND", e.End))
+	}
+	if !e.RecurrenceID.IsZero() {
+		lw.line(e.dateTime("RECURRENCE-ID", e.RecurrenceID))
+	}
+	if e.RRule != "" {
+		lw.line("RRULE:" + e.RRule)
+	}
+	for _, ex := range e.ExDates {
+		lw.line(e.dateTime("EXDATE", ex))
+	}
+	lw.line("SUMMARY:" + escapeText(e.Summary))
+	if e.Description != "" {
+		lw.line("DESCRIPTION:" + escapeText(e.Description))
+	}
+	if e.Location != "" {
+		lw.line("LOCATION:" + escapeText(e.Location))
+	}
+	if e.Geo != nil {
+		lw.line(fmt.Sprintf("GEO:%f;%f", e.Geo.Lat, e.Geo.Lng))
+	}
+	if e.Organizer != nil && e.Organizer.Email != "" {
+		organizer := "ORGANIZER"
+		if e.Organizer.Name != "" {
+			organizer += ";CN=" + quoteParam(e.Organizer.Name)
+		}
+		lw.line(organizer + ":mailto:" + e.Organizer.Email)
+	}
+	if e.URL != "" {
+		lw.line("URL:" + e.URL)
+	}
+	if e.Status != "" {
+		lw.line("STATUS:" + e.Status)
+	}
+	if e.Class != "" {
+		lw.line("CLASS:" + e.Class)
+	}
+	if !e.Created.IsZero() {
+		lw.line("CREATED:" + formatUTC(e.Created))
+	}
+	if !e.LastModified.IsZero() {
+		lw.line("LAST-MODIFIED:" + formatUTC(e.LastModified))
+	}
+	lw.line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+	lw.line("END:VEVENT")
+}
+
+// dateTime formats a date-time property in UTC, or on the TZID wall clock when
+// set. Whole-day events are written as dates.
+func (e *Event) dateTime(name string, t time.Time) string {
+	if e.AllDay {
+		return name + ";VALUE=DATE:" + t.Format("20060102")
+	}
+	if e.TZID != "" {
+		if loc, err := time.LoadLocation(e.TZID); err == nil {
+			return name + ";TZID=" + e.TZID + ":" + t.In(loc).Format("20060102T150405")
+		}
+	}
+	return name + ":" + formatUTC(t)
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeText escapes a TEXT value
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, ";", "\\;")
+	s = strings.ReplaceAll(s, ",", "\\,")
+	s = strings.ReplaceAll(s, "\r\n", "\\n")
+	s = strings.ReplaceAll(s, "\r", "\\n")
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	return s
+}
+
+// quoteParam quotes a parameter value if it contains characters that need it
+func quoteParam(s string) string {
+	s = strings.ReplaceAll(s, "\"", "'")
+	s = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(s)
+	if strings.ContainsAny(s, ":;,") {
+		return "\"" + s + "\""
+	}
+	return s
+}
+
+// lineWriter writes content lines, folding them at 75 octets without
+// splitting UTF-8 sequences
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+
+	// The leading space of a continuation line counts towards its 75 octets
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, lw.err = lw.w.WriteString(s[:cut] + "\r\n "); lw.err != nil {
+			return
+		}
+		s = s[cut:]
+		limit = 74
+	}
+	_, lw.err = lw.w.WriteString(s + "\r\n")
+}
@@ -0,0 +1,381 @@
+package ical
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+
+	tests := []struct {
+		name  string
+		event Event
+	}{
+		{
+			name: "utc",
+			event: Event{
+				UID:     "utc@example.com",
+				Summary: "Board games",
+				Start:   time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC),
+				End:     time.Date(2024, 5, 1, 21, 0, 0, 0, time.UTC),
+				Status:  StatusConfirmed,
+			},
+		},
+		{
+			name: "escaped and folded text",
+			event: Event{
+				UID:         "text@example.com",
+				Summary:     "Picnic; bring food, drinks \\ blankets",
+				Description: "Line one\nLine two with ünïcödé " + strings.Repeat("long text ", 20),
+				Location:    "Park, Main St; Gate 3",
+				Start:       time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "time zone, recurrence and exdates",
+			event: Event{
+				UID:     "series@example.com",
+				Summary: "Weekly run",
+				Start:   time.Date(2024, 3, 24, 19, 0, 0, 0, berlin),
+				End:     time.Date(2024, 3, 24, 20, 0, 0, 0, berlin),
+				TZID:    "Europe/Berlin",
+				RRule:   "FREQ=WEEKLY;BYDAY=SU",
+				ExDates: []time.Time{time.Date(2024, 3, 31, 19, 0, 0, 0, berlin)},
+			},
+		},
+		{
+			name: "changed occurrence",
+			event: Event{
+				UID:          "series@example.com",
+				Summary:      "Weekly run (moved)",
+				Start:        time.Date(2024, 4, 8, 19, 0, 0, 0, time.UTC),
+				RecurrenceID: time.Date(2024, 4, 7, 17, 0, 0, 0, time.UTC),
+				Sequence:     2,
+			},
+		},
+		{
+			name: "all day",
+			event: Event{
+				UID:     "allday@example.com",
+				Summary: "Festival",
+				Start:   time.Date(2024, 7, 5, 0, 0, 0, 0, time.UTC),
+				End:     time.Date(2024, 7, 8, 0, 0, 0, 0, time.UTC),
+				AllDay:  true,
+			},
+		},
+		{
+			name: "geo, organizer and metadata",
+			event: Event{
+				UID:          "meta@example.com",
+				Summary:      "Meetup",
+				URL:          "https://example.com/events/1",
+				Start:        time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC),
+				Geo:          &Geo{Lat: 52.520008, Lng: 13.404954},
+				Organizer:    &Organizer{Name: "Doe, Jane", Email: "jane@example.com"},
+				Created:      time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC),
+				LastModified: time.Date(2024, 4, 2, 9, 0, 0, 0, time.UTC),
+				Sequence:     3,
+			},
+		},
+		{
+			name: "private",
+			event: Event{
+				UID:     "private@example.com",
+				Summary: "Family dinner",
+				Start:   time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC),
+				Class:   ClassPrivate,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendar := Calendar{ProdID: "-//Test//EN", Name: "Events, etc.", Method: "PUBLISH", Events: []Event{tt.event}}
+
+			var buf bytes.Buffer
+			if err := calendar.Encode(&buf); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+				if len(line) > 75 {
+					t.Fatalf("line longer than 75 octets: %q", line)
+				}
+			}
+
+			decoded, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if decoded.ProdID != calendar.ProdID || decoded.Name != calendar.Name || decoded.Method != calendar.Method {
+				t.Fatalf("calendar = %+v, want %+v", decoded, calendar)
+			}
+			if len(decoded.Events) != 1 {
+				t.Fatalf("decoded %d events, want 1", len(decoded.Events))
+			}
+			assertEventEqual(t, decoded.Events[0], tt.event)
+		})
+	}
+}
+
+func assertEventEqual(t *testing.T, got, want Event) {
+	t.Helper()
+
+	times := []struct {
+		name      string
+		got, want time.Time
+	}{
+		{"Start", got.Start, want.Start},
+		{"End", got.End, want.End},
+		{"RecurrenceID", got.RecurrenceID, want.RecurrenceID},
+		{"Created", got.Created, want.Created},
+		{"LastModified", got.LastModified, want.LastModified},
+	}
+	for _, tt := range times {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if len(got.ExDates) != len(want.ExDates) {
+		t.Errorf("ExDates = %v, want %v", got.ExDates, want.ExDates)
+	} else {
+		for i := range got.ExDates {
+			if !got.ExDates[i].Equal(want.ExDates[i]) {
+				t.Errorf("ExDates[%d] = %v, want %v", i, got.ExDates[i], want.ExDates[i])
+			}
+		}
+	}
+
+	got.Start, got.End, got.RecurrenceID, got.Created, got.LastModified, got.ExDates = time.Time{}, time.Time{}, time.Time{}, time.Time{}, time.Time{}, nil
+	want.Start, want.End, want.RecurrenceID, want.Created, want.LastModified, want.ExDates = time.Time{}, time.Time{}, time.Time{}, time.Time{}, time.Time{}, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("event = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"X-WR-TIMEZONE:America/New_York",
+		"BEGIN:VTIMEZONE",
+		"TZID:Custom",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:floating",
+		"DTSTART:20240501T180000",
+		"DURATION:PT1H30M",
+		"SUMMARY:Floating time in the calendar's zone",
+		"BEGIN:VALARM",
+		"SUMMARY:Alarm summary is ignored",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"SUMMARY:Not an event",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\n")
+
+	calendar, err := Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(calendar.Events) != 1 {
+		t.Fatalf("decoded %d events, want 1", len(calendar.Events))
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	event := calendar.Events[0]
+	if want := time.Date(2024, 5, 1, 18, 0, 0, 0, newYork); !event.Start.Equal(want) {
+		t.Errorf("Start = %v, want %v", event.Start, want)
+	}
+	if want := event.Start.Add(90 * time.Minute); !event.End.Equal(want) {
+		t.Errorf("End = %v, want %v", event.End, want)
+	}
+	if event.Summary != "Floating time in the calendar's zone" {
+		t.Errorf("Summary = %q", event.Summary)
+	}
+}
+
+func TestDecodeNotCalendar(t *testing.T) {
+	if _, err := Decode(strings.NewReader("BEGIN:VCARD\nEND:VCARD\n")); err != ErrNotCalendar {
+		t.Fatalf("err = %v, want ErrNotCalendar", err)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "PT1H30M", want: 90 * time.Minute},
+		{value: "P1D", want: 24 * time.Hour},
+		{value: "P2W", want: 14 * 24 * time.Hour},
+		{value: "P1DT2H3M4S", want: 26*time.Hour + 3*time.Minute + 4*time.Second},
+		{value: "+PT15M", want: 15 * time.Minute},
+		{value: "-PT15M", wantErr: true},
+		{value: "1H", wantErr: true},
+		{value: "PT1D", wantErr: true},
+		{value: "P1H", wantErr: true},
+		{value: "PT5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseDuration(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseDuration(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDuration(%q): %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Fatalf("parseDuration(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeEscapesLineBreaks(t *testing.T) {
+	calendar := Calendar{ProdID: "-//Test//EN", Events: []Event{{
+		UID:         "breaks@example.com",
+		Summary:     "One\rTwo",
+		Description: "A\r\nB\nC\rD",
+		Start:       time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC),
+		Organizer:   &Organizer{Name: "Jane\r\nDoe", Email: "jane@example.com"},
+	}}}
+
+	var buf bytes.Buffer
+	if err := calendar.Encode(&buf); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if strings.Contains(strings.ReplaceAll(buf.String(), "\r\n", ""), "\r") {
+		t.Fatalf("output contains a bare CR: %q", buf.String())
+	}
+
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	event := decoded.Events[0]
+	if event.Summary != "One\nTwo" {
+		t.Errorf("Summary = %q, want %q", event.Summary, "One\nTwo")
+	}
+	if event.Description != "A\nB\nC\nD" {
+		t.Errorf("Description = %q, want %q", event.Description, "A\nB\nC\nD")
+	}
+}
+
+func TestEncodeTimeZones(t *testing.T) {
+	tests := []struct {
+		tzid string
+		want []string // Lines expected in the VTIMEZONE
+	}{
+		{
+			tzid: "Europe/Berlin",
+			want: []string{
+				"BEGIN:DAYLIGHT", "DTSTART:20240331T020000", "RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU",
+				"TZOFFSETFROM:+0100", "TZOFFSETTO:+0200", "TZNAME:CEST",
+				"BEGIN:STANDARD", "DTSTART:20241027T030000", "RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+				"TZOFFSETFROM:+0200", "TZOFFSETTO:+0100", "TZNAME:CET",
+			},
+		},
+		{
+			tzid: "America/New_York",
+			want: []string{
+				"DTSTART:20240310T020000", "RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU", "TZOFFSETFROM:-0500", "TZOFFSETTO:-0400",
+				"DTSTART:20241103T020000", "RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU", "TZOFFSETFROM:-0400", "TZOFFSETTO:-0500",
+			},
+		},
+		{
+			tzid: "Asia/Kolkata",
+			want: []string{"BEGIN:STANDARD", "TZOFFSETFROM:+0530", "TZOFFSETTO:+0530", "TZNAME:IST"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tzid, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.tzid)
+			if err != nil {
+				t.Skip("time zone data not available")
+			}
+			calendar := Calendar{ProdID: "-//Test//EN", Events: []Event{
+				{UID: "a", Summary: "Series", Start: time.Date(2024, 6, 2, 19, 0, 0, 0, loc), TZID: tt.tzid, RRule: "FREQ=WEEKLY"},
+				{UID: "a", Summary: "Moved", Start: time.Date(2024, 6, 10, 19, 0, 0, 0, loc), TZID: tt.tzid, RecurrenceID: time.Date(2024, 6, 9, 19, 0, 0, 0, loc)},
+			}}
+
+			var buf bytes.Buffer
+			if err := calendar.Encode(&buf); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			output := buf.String()
+			if n := strings.Count(output, "BEGIN:VTIMEZONE"); n != 1 {
+				t.Fatalf("got %d VTIMEZONEs, want 1:\n%s", n, output)
+			}
+			start, end := strings.Index(output, "BEGIN:VTIMEZONE"), strings.Index(output, "END:VTIMEZONE")
+			if start > strings.Index(output, "BEGIN:VEVENT") {
+				t.Fatalf("VTIMEZONE comes after the events:\n%s", output)
+			}
+			lines := strings.Split(output[start:end], "\r\n")
+			if lines[1] != "TZID:"+tt.tzid {
+				t.Errorf("TZID line = %q", lines[1])
+			}
+			next := 0
+			for _, line := range lines {
+				if next < len(tt.want) && line == tt.want[next] {
+					next++
+				}
+			}
+			if next < len(tt.want) {
+				t.Errorf("VTIMEZONE lacks %q in order:\n%s", tt.want[next], output[start:end])
+			}
+
+			decoded, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if len(decoded.Events) != 2 || !decoded.Events[0].Start.Equal(calendar.Events[0].Start) {
+				t.Errorf("events don't survive the VTIMEZONE: %+v", decoded.Events)
+			}
+		})
+	}
+}
+
+func TestEncodeWithoutTimeZones(t *testing.T) {
+	calendar := Calendar{ProdID: "-//Test//EN", Events: []Event{
+		{UID: "utc", Summary: "UTC", Start: time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)},
+		{UID: "day", Summary: "All day", Start: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), AllDay: true, TZID: "Europe/Berlin"},
+	}}
+
+	var buf bytes.Buffer
+	if err := calendar.Encode(&buf); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if strings.Contains(buf.String(), "VTIMEZONE") {
+		t.Errorf("VTIMEZONE written for a calendar without zoned times:\n%s", buf.String())
+	}
+}
+
+func TestDecodeClass(t *testing.T) {
+	input := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:a\nDTSTART:20240501T180000Z\nCLASS:confidential\nEND:VEVENT\nEND:VCALENDAR\n"
+	calendar, err := Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got := calendar.Events[0].Class; got != ClassConfidential {
+		t.Errorf("Class = %q, want %q", got, ClassConfidential)
+	}
+}
@@ -0,0 +1,130 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// timeZones returns the TZIDs the events are written on with the earliest
+// time each is used for, sorted by TZID
+func (c *Calendar) timeZones() ([]string, map[string]time.Time) {
+	earliest := make(map[string]time.Time)
+	for i := range c.Events {
+		e := &c.Events[i]
+		if e.TZID == "" || e.AllDay {
+			continue
+		}
+		if _, err := time.LoadLocation(e.TZID); err != nil {
+			continue
+		}
+		start := e.Start
+		if !e.RecurrenceID.IsZero() && e.RecurrenceID.Before(start) {
+			start = e.RecurrenceID
+		}
+		if first, ok := earliest[e.TZID]; !ok || start.Before(first) {
+			earliest[e.TZID] = start
+		}
+	}
+
+	tzids := make([]string, 0, len(earliest))
+	for tzid := range earliest {
+		tzids = append(tzids, tzid)
+	}
+	sort.Strings(tzids)
+	return tzids, earliest
+}
+
+// encodeTimeZone writes the VTIMEZONE RFC 5545 requires for each TZID used.
+// Go doesn't expose a zone's rules, so they are read off the transitions in
+// the year of from: zones with daylight saving time get a yearly STANDARD
+// and DAYLIGHT observance on the weekday of the month they switch on, zones
+// without one a single STANDARD observance.
+func encodeTimeZone(lw *lineWriter, tzid string, from time.Time) {
+	loc, err := time.LoadLocation(tzid)
+	if err != nil {
+		return
+	}
+	year := from.In(loc).Year()
+
+	lw.line("BEGIN:VTIMEZONE")
+	lw.line("TZID:" + tzid)
+
+	transitions := yearTransitions(loc, year)
+	if len(transitions) == 0 {
+		name, offset := time.Date(year, 1, 1, 0, 0, 0, 0, loc).Zone()
+		lw.line("BEGIN:STANDARD")
+		lw.line("DTSTART:19700101T000000")
+		lw.line("TZOFFSETFROM:" + formatOffset(offset))
+		lw.line("TZOFFSETTO:" + formatOffset(offset))
+		lw.line("TZNAME:" + name)
+		lw.line("END:STANDARD")
+	}
+	for _, t := range transitions {
+		_, before := t.Add(-time.Second).Zone()
+		name, after := t.Zone()
+		component := "STANDARD"
+		if t.IsDST() {
+			component = "DAYLIGHT"
+		}
+
+		// The onset is given on the wall clock in effect before the change
+		onset := t.In(time.FixedZone("", before))
+		lw.line("BEGIN:" + component)
+		lw.line("DTSTART:" + onset.Format("20060102T150405"))
+		lw.line("RRULE:FREQ=YEARLY;BYMONTH=" + fmt.Sprint(int(onset.Month())) + ";BYDAY=" + weekdayOfMonth(onset))
+		lw.line("TZOFFSETFROM:" + formatOffset(before))
+		lw.line("TZOFFSETTO:" + formatOffset(after))
+		lw.line("TZNAME:" + name)
+		lw.line("END:" + component)
+	}
+
+	lw.line("END:VTIMEZONE")
+}
+
+// yearTransitions finds the instants a zone changes its offset in a year
+func yearTransitions(loc *time.Location, year int) []time.Time {
+	var transitions []time.Time
+	day := time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+	_, offset := day.Zone()
+	for day.Year() == year {
+		next := day.Add(24 * time.Hour)
+		if _, nextOffset := next.Zone(); nextOffset != offset {
+			// Narrow the change down to the second
+			low, high := day, next
+			for high.Sub(low) > time.Second {
+				mid := low.Add(high.Sub(low) / 2)
+				if _, midOffset := mid.Zone(); midOffset == offset {
+					low = mid
+				} else {
+					high = mid
+				}
+			}
+			transitions = append(transitions, high)
+			offset = nextOffset
+		}
+		day = next
+	}
+	return transitions
+}
+
+// weekdayOfMonth formats the BYDAY value matching a date every year, such as
+// 2SU for the second Sunday or -1SU for the last one
+func weekdayOfMonth(t time.Time) string {
+	day := [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}[t.Weekday()]
+	daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if t.Day() > daysInMonth-7 {
+		return "-1" + day
+	}
+	return fmt.Sprint((t.Day()-1)/7+1) + day
+}
+
+// formatOffset formats a UTC offset in seconds as +HHMM
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"01-Login/platform/authenticator"
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requiredScopeKey holds the scope set by RequireScope
+const requiredScopeKey = "required_scope"
+
+// auth0 verifies Auth0-issued access tokens; without it only personal
+// access tokens are accepted
+var auth0 *authenticator.Authenticator
+
+// UseAuthenticator lets API requests authenticate with access tokens Auth0
+// issued for the API, as backend services calling on behalf of users do
+func UseAuthenticator(auth *authenticator.Authenticator) {
+	auth0 = auth
+}
+
+// RequireScope declares the scope an access token, personal or Auth0-issued,
+// needs to use a route. It must come before the middleware that
+// authenticates the request. Routes without it can't be used with access
+// tokens at all, only with a browser session, which isn't limited by scopes.
+func RequireScope(scope models.TokenScope) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(requiredScopeKey, scope)
+		ctx.Next()
+	}
+}
+
+// tokenError is a request's access token being refused
+type tokenError struct {
+	status  int
+	message string
+}
+
+func (e *tokenError) Error() string { return e.message }
+
+// errDeactivatedToken refuses the tokens of users an admin deactivated
+var errDeactivatedToken = &tokenError{status: http.StatusForbidden, message: "Your account has been deactivated"}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, if any
+func bearerToken(ctx *gin.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(ctx.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// tokenUser authenticates a request by its access token, checking that the
+// token grants the scope the route requires. It sets the token in context.
+func tokenUser(ctx *gin.Context, token string) (*models.User, error) {
+	user, accessToken, err := services.NewAccessTokenService().Authenticate(token)
+	if errors.Is(err, services.ErrInvalidAccessToken) {
+		return nil, &tokenError{status: http.StatusUnauthorized, message: "Invalid or expired access token"}
+	}
+	if errors.Is(err, services.ErrUserDeactivated) {
+		return nil, errDeactivatedToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkScope(ctx, accessToken.HasScope); err != nil {
+		return nil, err
+	}
+
+	ctx.Set("access_token", *accessToken)
+	return user, nil
+}
+
+// auth0User authenticates a request by an Auth0-issued access token,
+// mapping its subject to the user who signed in with that Auth0 account.
+// The token's scopes and RBAC permissions are checked against the route's
+// scope; the user's event roles then apply as for a session.
+func auth0User(ctx *gin.Context, token string) (*models.User, error) {
+	if auth0 == nil {
+		return nil, &tokenError{status: http.StatusUnauthorized, message: "Invalid or expired access token"}
+	}
+	claims, err := auth0.VerifyAccessToken(ctx.Request.Context(), token)
+	if err != nil {
+		return nil, &tokenError{status: http.StatusUnauthorized, message: "Invalid or expired access token"}
+	}
+
+	user, err := services.NewUserService().GetUserByAuthID(claims.Subject)
+	if err != nil {
+		return nil, &tokenError{status: http.StatusUnauthorized, message: "Access token is not for a known user"}
+	}
+	if !user.IsActive {
+		return nil, errDeactivatedToken
+	}
+
+	err = checkScope(ctx, func(scope models.TokenScope) bool {
+		return claims.Grants(string(scope))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.Set("auth0_token", *claims)
+	return user, nil
+}
+
+// checkScope checks that a token grants the scope the route requires
+func checkScope(ctx *gin.Context, grants func(models.TokenScope) bool) error {
+	value, ok := ctx.Get(requiredScopeKey)
+	if !ok {
+		return &tokenError{status: http.StatusForbidden, message: "This route can't be used with an access token"}
+	}
+	scope := value.(models.TokenScope)
+	if !grants(scope) {
+		return &tokenError{status: http.StatusForbidden, message: "Access token lacks the " + string(scope) + " scope"}
+	}
+	return nil
+}
+
+// requestUser returns the user making an API request: by access token when
+// an Authorization header is sent, by session otherwise. Personal access
+// tokens are told apart from Auth0 tokens by their prefix.
+func requestUser(ctx *gin.Context) (*models.User, error) {
+	if token, ok := bearerToken(ctx); ok {
+		if services.IsPersonalAccessToken(token) {
+			return tokenUser(ctx, token)
+		}
+		return auth0User(ctx, token)
+	}
+	return sessionUser(ctx)
+}
+
+// abortTokenError answers a request whose access token was refused,
+// reporting whether it did
+func abortTokenError(ctx *gin.Context, err error) bool {
+	var tokenErr *tokenError
+	if !errors.As(err, &tokenErr) {
+		return false
+	}
+	if tokenErr.status == http.StatusUnauthorized {
+		ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	ctx.JSON(tokenErr.status, gin.H{"error": tokenErr.message})
+	ctx.Abort()
+	return true
+}
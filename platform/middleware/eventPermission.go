@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+
+	"01-Login/platform/authorization"
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequireEventPermission returns a middleware for API routes that loads the
+// event from the :id parameter and only lets the request through if the
+// authenticated user's role on the event grants the permission. It must run
+// after IsAuthenticatedAPI and sets the event and the user's role in context
+// for controllers to use.
+func RequireEventPermission(permission authorization.EventPermission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		eventID, err := uuid.Parse(ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+			ctx.Abort()
+			return
+		}
+
+		userInterface, exists := ctx.Get("user")
+		if !exists {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			ctx.Abort()
+			return
+		}
+		user := userInterface.(models.User)
+
+		eventService := services.NewEventService()
+		event, err := eventService.GetEventByID(eventID)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			ctx.Abort()
+			return
+		}
+
+		memberService := services.NewMemberService()
+		role, err := memberService.GetRole(event.ID, user.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			ctx.Abort()
+			return
+		}
+
+		if !authorization.EventRoleAllows(role, permission) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Your role on this event does not allow this action"})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set("event", *event)
+		ctx.Set("event_role", role)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequireEventViewer is a middleware for API routes that loads the event from
+// the :id parameter and only lets the request through if the user may see it.
+// Signed-out visitors get through for public events, but not requests with a
+// refused access token. Events the user may not
+// see are reported as not found, so private events don't reveal that they
+// exist. It sets the event in context for controllers to use.
+func RequireEventViewer(ctx *gin.Context) {
+	eventID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		ctx.Abort()
+		return
+	}
+
+	var user *models.User
+	if userInterface, exists := ctx.Get("user"); exists {
+		current := userInterface.(models.User)
+		user = &current
+	} else if current, err := requestUser(ctx); err == nil {
+		user = current
+		ctx.Set("user", *current)
+	} else if abortTokenError(ctx, err) {
+		return
+	}
+
+	eventService := services.NewEventService()
+	event, err := eventService.GetEventByID(eventID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		ctx.Abort()
+		return
+	}
+
+	invitationService := services.NewInvitationService()
+	canView, err := invitationService.CanView(user, event)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Abort()
+		return
+	}
+	if !canView {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set("event", *event)
+	ctx.Next()
+}
@@ -1,42 +1,54 @@
 package middleware
 
 import (
+	"errors"
+	"log"
 	"net/http"
 
+	"01-Login/platform/models"
 	"01-Login/platform/services"
+	"01-Login/platform/sessionstore"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
 
 // IsAuthenticated is a middleware that checks if
-// the user has already been authenticated previously.
+// the user has already been authenticated previously
+// and hasn't been deactivated since.
 func IsAuthenticated(ctx *gin.Context) {
-	if sessions.Default(ctx).Get("profile") == nil {
+	_, err := signedInUser(ctx)
+	if errors.Is(err, services.ErrUserDeactivated) {
+		ctx.String(http.StatusForbidden, "Your account has been deactivated.")
+		ctx.Abort()
+		return
+	}
+	if err != nil {
 		ctx.Redirect(http.StatusSeeOther, "/login")
-	} else {
-		ctx.Next()
+		ctx.Abort()
+		return
 	}
+	ctx.Next()
 }
 
-// IsAuthenticatedAPI is a middleware for API routes that checks authentication
-// and returns JSON responses instead of redirecting. It also sets the user in context.
+// IsAuthenticatedAPI is a middleware for API routes that checks authentication,
+// by session or by access token, and returns JSON responses instead of
+// redirecting. It also sets the user in context.
 func IsAuthenticatedAPI(ctx *gin.Context) {
-	session := sessions.Default(ctx)
-	profile := session.Get("profile")
-
-	if profile == nil {
+	user, err := requestUser(ctx)
+	if abortTokenError(ctx, err) {
+		return
+	}
+	if errors.Is(err, errNotSignedIn) {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 		ctx.Abort()
 		return
 	}
-
-	// Extract user info from session and get from database
-	profileMap := profile.(map[string]interface{})
-	authID := profileMap["sub"].(string)
-
-	userService := services.NewUserService()
-	user, err := userService.GetUserByAuthID(authID)
+	if errors.Is(err, services.ErrUserDeactivated) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Your account has been deactivated"})
+		ctx.Abort()
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		ctx.Abort()
@@ -47,3 +59,59 @@ func IsAuthenticatedAPI(ctx *gin.Context) {
 	ctx.Set("user", *user)
 	ctx.Next()
 }
+
+// LoadUser is a middleware for API routes open to signed-out visitors that
+// sets the user in context when there is one, so results can depend on who
+// is asking. A refused access token is still an error.
+func LoadUser(ctx *gin.Context) {
+	user, err := requestUser(ctx)
+	if abortTokenError(ctx, err) {
+		return
+	}
+	if err == nil {
+		ctx.Set("user", *user)
+	}
+	ctx.Next()
+}
+
+// errNotSignedIn is returned for requests without a signed-in session
+var errNotSignedIn = errors.New("not signed in")
+
+// sessionUser looks up the database user of the signed-in session, or the
+// user its admin is impersonating
+func sessionUser(ctx *gin.Context) (*models.User, error) {
+	user, err := signedInUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session := sessions.Default(ctx)
+	if session.Get(sessionstore.ImpersonatedUserIDKey) == nil {
+		return user, nil
+	}
+	return impersonatedUser(ctx, session, user)
+}
+
+// signedInUser looks up the database user who signed in to the session.
+// The session of a user who has been deactivated since is ended.
+func signedInUser(ctx *gin.Context) (*models.User, error) {
+	session := sessions.Default(ctx)
+	profile, ok := session.Get("profile").(map[string]interface{})
+	if !ok {
+		return nil, errNotSignedIn
+	}
+	authID, _ := profile["sub"].(string)
+
+	userService := services.NewUserService()
+	user, err := userService.GetUserByAuthID(authID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		session.Clear()
+		if err := session.Save(); err != nil {
+			log.Printf("Error ending session of deactivated user %v: %v", user.ID, err)
+		}
+		return nil, services.ErrUserDeactivated
+	}
+	return user, nil
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TokenScope is a part of the API a personal access token may use
+type TokenScope string
+
+const (
+	// TokenScopeReadEvents covers reading events and everything on them
+	TokenScopeReadEvents TokenScope = "read:events"
+	// TokenScopeWriteEvents covers creating, changing and deleting events and everything on them
+	TokenScopeWriteEvents TokenScope = "write:events"
+	// TokenScopeRSVP covers answering events: RSVPs, date poll votes and accepting invitations
+	TokenScopeRSVP TokenScope = "rsvp"
+)
+
+// TokenScopes lists every scope
+var TokenScopes = []TokenScope{
+	TokenScopeReadEvents,
+	TokenScopeWriteEvents,
+	TokenScopeRSVP,
+}
+
+// IsValid reports whether the scope is one of the known scopes
+func (s TokenScope) IsValid() bool {
+	for _, scope := range TokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PersonalAccessToken lets scripts call the API as a user, limited to its
+// scopes. Only a hash of the token is stored; the token itself is shown
+// once when it is created.
+type PersonalAccessToken struct {
+	ID         uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID     uuid.UUID    `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name       string       `json:"name" gorm:"not null"`
+	TokenHash  string       `json:"-" gorm:"not null;uniqueIndex"` // SHA-256 of the token, hex encoded
+	Prefix     string       `json:"prefix" gorm:"not null"`        // Start of the token, to tell tokens apart
+	Scopes     []TokenScope `json:"scopes" gorm:"type:jsonb;serializer:json"`
+	ExpiresAt  time.Time    `json:"expires_at" gorm:"not null"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (t *PersonalAccessToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
+
+// HasScope reports whether the token grants a scope
+func (t *PersonalAccessToken) HasScope(scope TokenScope) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AdminAuditAction is something a moderator or admin did
+type AdminAuditAction string
+
+const (
+	AdminAuditUserUpdated          AdminAuditAction = "user.updated"
+	AdminAuditUserDeleted          AdminAuditAction = "user.deleted"
+	AdminAuditUserRoleChanged      AdminAuditAction = "user.role_changed"
+	AdminAuditUserDeactivated      AdminAuditAction = "user.deactivated"
+	AdminAuditUserReactivated      AdminAuditAction = "user.reactivated"
+	AdminAuditEventUnpublished     AdminAuditAction = "event.unpublished"
+	AdminAuditEventHoldLifted      AdminAuditAction = "event.hold_lifted"
+	AdminAuditImpersonationStarted AdminAuditAction = "impersonation.started"
+	AdminAuditImpersonationEnded   AdminAuditAction = "impersonation.ended"
+	AdminAuditImpersonationRequest AdminAuditAction = "impersonation.request" // An API request made while impersonating
+)
+
+// What an audit log entry's target is
+const (
+	AdminAuditTargetUser  = "user"
+	AdminAuditTargetEvent = "event"
+)
+
+// AdminAuditLog records one privileged action, who took it and on what.
+// Entries are never changed or deleted.
+type AdminAuditLog struct {
+	ID         uuid.UUID              `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ActorID    uuid.UUID              `json:"actor_id" gorm:"type:uuid;not null;index"`
+	Action     AdminAuditAction       `json:"action" gorm:"type:varchar(50);not null;index"`
+	TargetType string                 `json:"target_type" gorm:"type:varchar(20);not null"` // user or event
+	TargetID   uuid.UUID              `json:"target_id" gorm:"type:uuid;not null;index"`
+	Reason     string                 `json:"reason"`
+	Details    map[string]interface{} `json:"details" gorm:"type:jsonb;serializer:json"`
+	IPAddress  string                 `json:"ip_address"`
+	CreatedAt  time.Time              `json:"created_at" gorm:"index"`
+
+	// Relationships
+	Actor PublicUser `json:"actor" gorm:"foreignKey:ActorID"`
+}
+
+// BeforeCreate hook to generate UUID
+func (l *AdminAuditLog) BeforeCreate(tx *gorm.DB) (err error) {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return
+}
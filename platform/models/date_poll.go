@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DatePollAnswer is how a voter feels about one candidate date
+type DatePollAnswer string
+
+const (
+	DatePollAnswerYes      DatePollAnswer = "yes"
+	DatePollAnswerIfNeedBe DatePollAnswer = "if_need_be"
+	DatePollAnswerNo       DatePollAnswer = "no"
+)
+
+// IsValid reports whether the answer is one of the known answers
+func (a DatePollAnswer) IsValid() bool {
+	switch a {
+	case DatePollAnswerYes, DatePollAnswerIfNeedBe, DatePollAnswerNo:
+		return true
+	}
+	return false
+}
+
+// DatePoll asks the people who can see a draft event which of a few
+// candidate dates suits them. Finalizing it picks one option, which becomes
+// the event's date, and publishes the event.
+type DatePoll struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EventID           uuid.UUID  `json:"event_id" gorm:"type:uuid;not null;uniqueIndex"` // One poll per event
+	CreatedByID       uuid.UUID  `json:"created_by_id" gorm:"type:uuid;not null"`
+	FinalizedOptionID *uuid.UUID `json:"finalized_option_id" gorm:"type:uuid"`
+	FinalizedAt       *time.Time `json:"finalized_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (p *DatePoll) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
+
+// DatePollOption is one candidate date of a poll
+type DatePollOption struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	PollID    uuid.UUID  `json:"poll_id" gorm:"type:uuid;not null;index"`
+	StartsAt  time.Time  `json:"starts_at" gorm:"not null"`
+	EndsAt    *time.Time `json:"ends_at"` // Optional; the event keeps its duration when empty
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (o *DatePollOption) BeforeCreate(tx *gorm.DB) (err error) {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return
+}
+
+// DatePollVote is one user's answer for one option
+type DatePollVote struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	PollID    uuid.UUID      `json:"poll_id" gorm:"type:uuid;not null;index"`
+	OptionID  uuid.UUID      `json:"option_id" gorm:"type:uuid;not null;uniqueIndex:idx_date_poll_votes_option_user,priority:1"`
+	UserID    uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_date_poll_votes_option_user,priority:2"`
+	Answer    DatePollAnswer `json:"answer" gorm:"type:varchar(10);not null"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+
+	// Relationships
+	User PublicUser `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// BeforeCreate hook to generate UUID
+func (v *DatePollVote) BeforeCreate(tx *gorm.DB) (err error) {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EmailNotificationStatus is how sending a notification email went
+type EmailNotificationStatus string
+
+const (
+	EmailNotificationPending EmailNotificationStatus = "pending"
+	EmailNotificationSent    EmailNotificationStatus = "sent"
+	EmailNotificationSkipped EmailNotificationStatus = "skipped" // No email address, inactive, opted out or no longer relevant
+	EmailNotificationFailed  EmailNotificationStatus = "failed"  // Retried with the job until it runs out of attempts
+)
+
+// EmailNotification is an email about an event to one user, such as an RSVP
+// confirmation or a cancellation notice. It is queued in the transaction of
+// the change it is about and sent by a job of its own, or for reminders by
+// the reminder's job.
+type EmailNotification struct {
+	ID         uuid.UUID               `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID     uuid.UUID               `json:"user_id" gorm:"type:uuid;not null;index"`
+	EventID    uuid.UUID               `json:"event_id" gorm:"type:uuid;not null;index"`
+	ReminderID *uuid.UUID              `json:"reminder_id" gorm:"type:uuid;index"` // Set on the emails of an event reminder, which its job sends
+	Category   NotificationCategory    `json:"category" gorm:"type:varchar(30);not null"`
+	Template   string                  `json:"template" gorm:"type:varchar(30);not null"`
+	Content    EmailContent            `json:"content" gorm:"type:jsonb;serializer:json"`
+	Status     EmailNotificationStatus `json:"status" gorm:"type:varchar(20);not null"`
+	Error      string                  `json:"error"`
+	SentAt     *time.Time              `json:"sent_at"`
+	CreatedAt  time.Time               `json:"created_at"`
+	UpdatedAt  time.Time               `json:"updated_at"`
+
+	// Relationships
+	User User `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// EmailContent is what a notification shows besides the event, fixed when it
+// is queued. Empty fields are left out of the email.
+type EmailContent struct {
+	When      string        `json:"when,omitempty"` // Start shown, when it isn't the event's or the RSVP's occurrence
+	RSVPID    *uuid.UUID    `json:"rsvp_id,omitempty"`
+	CommentID *uuid.UUID    `json:"comment_id,omitempty"`
+	Changes   []EmailChange `json:"changes,omitempty"`
+	Reason    string        `json:"reason,omitempty"`
+	StartsIn  string        `json:"starts_in,omitempty"`
+}
+
+// EmailChange is one changed field of an event, formatted for people
+type EmailChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// BeforeCreate hook to generate UUID
+func (n *EmailNotification) BeforeCreate(tx *gorm.DB) (err error) {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return
+}
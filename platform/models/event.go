@@ -1,12 +1,20 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Event statuses
+const (
+	EventStatusDraft     = "draft"
+	EventStatusPublished = "published"
+	EventStatusCancelled = "cancelled"
+)
+
 // Event represents an event in the system (birthday, anniversary, house party, etc.)
 type Event struct {
 	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -14,28 +22,94 @@ type Event struct {
 	Description string    `json:"description"`
 	Venue       string    `json:"venue"`
 	// Enhanced venue fields for Google Places integration
-	VenueName    string    `json:"venue_name"`     // Business name from Google Places
-	VenuePlaceID string    `json:"venue_place_id"` // Google Place ID for unique identification
-	VenueLat     float64   `json:"venue_lat"`      // Latitude for mapping
-	VenueLng     float64   `json:"venue_lng"`      // Longitude for mapping
-	EventDate    time.Time `json:"event_date" gorm:"not null"`
-	Image        string    `json:"image"`
-	EventType    string    `json:"event_type"` // birthday, anniversary, house_party, wedding, etc.
-	IsPublic     bool      `json:"is_public" gorm:"default:true"`
-	MaxAttendees int       `json:"max_attendees" gorm:"default:0"` // 0 means unlimited
-	Status       string    `json:"status" gorm:"default:'draft'"`  // draft, published, cancelled
+	VenueName    string     `json:"venue_name"`     // Business name from Google Places
+	VenuePlaceID string     `json:"venue_place_id"` // Google Place ID for unique identification
+	VenueLat     float64    `json:"venue_lat"`      // Latitude for mapping
+	VenueLng     float64    `json:"venue_lng"`      // Longitude for mapping
+	EventDate    time.Time  `json:"event_date" gorm:"not null"`
+	EndDate      *time.Time `json:"end_date"`                                // Optional end, after EventDate
+	TimeZone     string     `json:"time_zone" gorm:"not null;default:'UTC'"` // IANA zone the event takes place in
+	Image        string     `json:"image"`
+	EventType    string     `json:"event_type"`                     // birthday, anniversary, house_party, wedding, etc.
+	IsPublic     bool       `json:"is_public" gorm:"default:true"`  // Private events are only visible to the organizer and invitees
+	MaxAttendees int        `json:"max_attendees" gorm:"default:0"` // 0 means unlimited, counted in people rather than RSVPs
+	MaxPlusOnes  int        `json:"max_plus_ones" gorm:"default:0"` // Extra people each guest may bring, 0 means none
+	Status       string     `json:"status" gorm:"default:'draft'"`  // draft, published, cancelled
+
+	// Set when a moderator unpublished the event; it can't be published
+	// again until a moderator lifts the hold
+	ModerationHold bool `json:"moderation_hold" gorm:"not null;default:false"`
+
+	// Recurrence (iCalendar RRULE), empty for one-off events
+	RecurrenceRule    string      `json:"recurrence_rule" gorm:"not null;default:''"`
+	RecurrenceExDates []time.Time `json:"recurrence_exdates" gorm:"type:jsonb;serializer:json"` // Skipped occurrences (EXDATE)
+
+	// Minutes before the start to remind guests who said yes or maybe, largest first
+	ReminderOffsets []int `json:"reminder_offsets" gorm:"type:jsonb;serializer:json"`
+
+	// Set on an expanded occurrence of a recurring event to the occurrence's
+	// original start; EventDate then holds the occurrence's actual start
+	OccurrenceDate *time.Time `json:"occurrence_date,omitempty" gorm:"-"`
 
 	// Google Photos integration
 	GooglePhotosEnabled  bool   `json:"google_photos_enabled"`   // User wants Google Photos album for this event
 	GooglePhotosAlbumID  string `json:"google_photos_album_id"`  // Google Photos album ID
 	GooglePhotosAlbumURL string `json:"google_photos_album_url"` // Shareable URL for the album
 
-	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid"`
+	// Bumped to revoke every shareable invite link issued so far
+	InviteLinkVersion int `json:"-" gorm:"not null;default:0"`
+
+	// UID of the calendar entry the event was imported from, unique per organizer
+	ImportUID string `json:"import_uid,omitempty" gorm:"not null;default:'';uniqueIndex:idx_events_user_import_uid,priority:2,where:import_uid <> ''"`
+
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;uniqueIndex:idx_events_user_import_uid,priority:1"`
 	User      User      `json:"user" gorm:"foreignKey:UserID"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Location returns the event's time zone, falling back to UTC
+func (e *Event) Location() *time.Location {
+	if e.TimeZone != "" {
+		if loc, err := time.LoadLocation(e.TimeZone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// MarshalJSON writes event_date and end_date as UTC instants and adds the
+// same times on the event's local wall clock as event_date_local and
+// end_date_local.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event // drops this method so marshalling doesn't recurse
+
+	loc := e.Location()
+	out := struct {
+		event
+		EventDate      time.Time  `json:"event_date"`
+		EventDateLocal string     `json:"event_date_local"`
+		EndDate        *time.Time `json:"end_date"`
+		EndDateLocal   *string    `json:"end_date_local"`
+	}{
+		event:          event(e),
+		EventDate:      e.EventDate.UTC(),
+		EventDateLocal: e.EventDate.In(loc).Format(time.RFC3339),
+	}
+	if e.EndDate != nil {
+		end := e.EndDate.UTC()
+		endLocal := e.EndDate.In(loc).Format(time.RFC3339)
+		out.EndDate = &end
+		out.EndDateLocal = &endLocal
+	}
+	return json.Marshal(out)
+}
+
+// IsRecurring reports whether the event repeats
+func (e *Event) IsRecurring() bool {
+	return e.RecurrenceRule != ""
+}
+
 // BeforeCreate hook to generate UUID
 func (e *Event) BeforeCreate(tx *gorm.DB) (err error) {
 	if e.ID == uuid.Nil {
@@ -43,3 +117,8 @@ func (e *Event) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+// AfterCreate hook to record the creator as the event's owner member
+func (e *Event) AfterCreate(tx *gorm.DB) (err error) {
+	return tx.Create(&EventMember{EventID: e.ID, UserID: e.UserID, Role: EventRoleOwner}).Error
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AnnouncementAudience picks which guests an announcement is emailed to, by their RSVP
+type AnnouncementAudience string
+
+const (
+	AnnouncementAudienceAll      AnnouncementAudience = "all"       // Every guest who answered, including those who declined
+	AnnouncementAudienceYes      AnnouncementAudience = "yes"       // Guests who are coming
+	AnnouncementAudienceYesMaybe AnnouncementAudience = "yes_maybe" // Guests who are coming or might come
+)
+
+// Responses lists the RSVP responses the audience is made of
+func (a AnnouncementAudience) Responses() []RSVPResponse {
+	switch a {
+	case AnnouncementAudienceAll:
+		return []RSVPResponse{RSVPResponseYes, RSVPResponseMaybe, RSVPResponseWaitlisted, RSVPResponseNo}
+	case AnnouncementAudienceYes:
+		return []RSVPResponse{RSVPResponseYes}
+	case AnnouncementAudienceYesMaybe:
+		return []RSVPResponse{RSVPResponseYes, RSVPResponseMaybe}
+	}
+	return nil
+}
+
+// IsValid reports whether the audience is one of the known audiences
+func (a AnnouncementAudience) IsValid() bool {
+	return a.Responses() != nil
+}
+
+// EventAnnouncement is news an organizer posted to an event's guests. It is
+// shown on the event page and emailed to its audience.
+type EventAnnouncement struct {
+	ID        uuid.UUID            `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EventID   uuid.UUID            `json:"event_id" gorm:"type:uuid;not null;index"`
+	AuthorID  uuid.UUID            `json:"author_id" gorm:"type:uuid;not null"`
+	Body      string               `json:"body" gorm:"type:text;not null"`
+	Audience  AnnouncementAudience `json:"audience" gorm:"type:varchar(20);not null"`
+	CreatedAt time.Time            `json:"created_at"`
+
+	// Relationships
+	Author PublicUser `json:"author" gorm:"foreignKey:AuthorID"`
+}
+
+// BeforeCreate hook to generate UUID
+func (a *EventAnnouncement) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
+
+// AnnouncementDeliveryStatus is how emailing an announcement to one guest went
+type AnnouncementDeliveryStatus string
+
+const (
+	AnnouncementDeliveryPending AnnouncementDeliveryStatus = "pending"
+	AnnouncementDeliverySent    AnnouncementDeliveryStatus = "sent"
+	AnnouncementDeliverySkipped AnnouncementDeliveryStatus = "skipped" // No email address, inactive or opted out
+	AnnouncementDeliveryFailed  AnnouncementDeliveryStatus = "failed"  // Retried with the job until it runs out of attempts
+)
+
+// AnnouncementDelivery records an announcement being emailed to one guest.
+// Recipients are fixed when the announcement is posted.
+type AnnouncementDelivery struct {
+	ID             uuid.UUID                  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AnnouncementID uuid.UUID                  `json:"announcement_id" gorm:"type:uuid;not null;uniqueIndex:idx_announcement_deliveries_recipient,priority:1"`
+	UserID         uuid.UUID                  `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_announcement_deliveries_recipient,priority:2"`
+	Response       RSVPResponse               `json:"response" gorm:"type:varchar(10);not null"` // The guest's RSVP when the announcement was posted
+	Status         AnnouncementDeliveryStatus `json:"status" gorm:"type:varchar(20);not null"`
+	Error          string                     `json:"error"`
+	SentAt         *time.Time                 `json:"sent_at"`
+	CreatedAt      time.Time                  `json:"created_at"`
+	UpdatedAt      time.Time                  `json:"updated_at"`
+
+	// Relationships
+	User User `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// BeforeCreate hook to generate UUID
+func (d *AnnouncementDelivery) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return
+}
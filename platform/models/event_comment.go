@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventComment is a message in an event's discussion. Replies point at the
+// top-level comment they answer, so threads are one level deep.
+type EventComment struct {
+	ID         uuid.UUID   `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EventID    uuid.UUID   `json:"event_id" gorm:"type:uuid;not null;index:idx_event_comments_event,priority:1"`
+	UserID     uuid.UUID   `json:"user_id" gorm:"type:uuid;not null;index"`
+	ParentID   *uuid.UUID  `json:"parent_id" gorm:"type:uuid;index"` // Nil for top-level comments
+	Body       string      `json:"body" gorm:"type:text;not null"`
+	MentionIDs []uuid.UUID `json:"mention_ids" gorm:"type:jsonb;serializer:json"` // Attendees mentioned with @
+	PinnedAt   *time.Time  `json:"pinned_at"`                                     // Set on the one comment an organizer pinned
+	EditedAt   *time.Time  `json:"edited_at"`
+	RemovedAt  *time.Time  `json:"removed_at"` // Set instead of deleting comments that have replies; the body is cleared
+	CreatedAt  time.Time   `json:"created_at" gorm:"index:idx_event_comments_event,priority:2"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+
+	// Relationships
+	Author  PublicUser     `json:"author" gorm:"foreignKey:UserID"`
+	Replies []EventComment `json:"replies,omitempty" gorm:"foreignKey:ParentID"`
+}
+
+// BeforeCreate hook to generate UUID
+func (c *EventComment) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvitationStatus tracks how far an invitee has got
+type InvitationStatus string
+
+const (
+	InvitationStatusPending   InvitationStatus = "pending" // Email invitation whose email hasn't gone out yet
+	InvitationStatusSent      InvitationStatus = "sent"
+	InvitationStatusOpened    InvitationStatus = "opened"
+	InvitationStatusResponded InvitationStatus = "responded"
+)
+
+// Ways an invitation can be issued
+const (
+	InvitationViaEmail = "email"
+	InvitationViaLink  = "link"
+)
+
+// EventInvitation lets one person see and RSVP to a private event. Email
+// invitations are matched to the user with that email, or bound to whoever
+// opens their personal link; link invitations are created for each user who
+// opens the event's shareable link.
+type EventInvitation struct {
+	ID          uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EventID     uuid.UUID        `json:"event_id" gorm:"type:uuid;not null;uniqueIndex:idx_event_invitations_event_email,where:email <> '';uniqueIndex:idx_event_invitations_event_user,where:user_id IS NOT NULL"`
+	Email       string           `json:"email" gorm:"not null;default:'';uniqueIndex:idx_event_invitations_event_email"` // Lowercased, empty for link invitations
+	UserID      *uuid.UUID       `json:"user_id" gorm:"type:uuid;uniqueIndex:idx_event_invitations_event_user"`          // Set once the invitation is opened or answered
+	User        *User            `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Via         string           `json:"via" gorm:"not null"`
+	Status      InvitationStatus `json:"status" gorm:"type:varchar(10);not null;default:'sent'"`
+	InvitedByID uuid.UUID        `json:"invited_by_id" gorm:"type:uuid;not null"`
+	SentAt      *time.Time       `json:"sent_at"` // When the invitation email was delivered
+	OpenedAt    *time.Time       `json:"opened_at"`
+	RespondedAt *time.Time       `json:"responded_at"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (i *EventInvitation) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventRole is a user's role on one event
+type EventRole string
+
+const (
+	EventRoleOwner        EventRole = "owner"
+	EventRoleCoHost       EventRole = "co_host"
+	EventRoleCheckInStaff EventRole = "checkin_staff"
+)
+
+// IsValid reports whether the role is one of the known roles
+func (r EventRole) IsValid() bool {
+	switch r {
+	case EventRoleOwner, EventRoleCoHost, EventRoleCheckInStaff:
+		return true
+	}
+	return false
+}
+
+// EventMember gives a user a role on an event. Every event has exactly one
+// owner, who is also the event's UserID.
+type EventMember struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EventID   uuid.UUID  `json:"event_id" gorm:"type:uuid;not null;uniqueIndex:idx_event_members_event_user"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_event_members_event_user;index"`
+	User      User       `json:"user" gorm:"foreignKey:UserID"`
+	Role      EventRole  `json:"role" gorm:"type:varchar(20);not null"`
+	AddedByID *uuid.UUID `json:"added_by_id" gorm:"type:uuid"` // Nil for the owner who created the event
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (m *EventMember) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return
+}
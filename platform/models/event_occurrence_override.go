@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventOccurrenceOverride changes a single occurrence of a recurring event,
+// e.g. moving this year's birthday party to the Saturday. Empty fields keep
+// the series' value.
+type EventOccurrenceOverride struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EventID        uuid.UUID  `json:"event_id" gorm:"type:uuid;not null;uniqueIndex:idx_occurrence_overrides_event_date"`
+	OccurrenceDate time.Time  `json:"occurrence_date" gorm:"not null;uniqueIndex:idx_occurrence_overrides_event_date"` // Original start of the occurrence
+	EventDate      *time.Time `json:"event_date"`                                                                      // New start, if moved
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	Venue          string     `json:"venue"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (o *EventOccurrenceOverride) BeforeCreate(tx *gorm.DB) (err error) {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return
+}
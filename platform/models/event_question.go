@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type QuestionKind string
+
+const (
+	QuestionKindText         QuestionKind = "text"
+	QuestionKindSingleChoice QuestionKind = "single_choice"
+	QuestionKindMultiChoice  QuestionKind = "multi_choice"
+	QuestionKindNumber       QuestionKind = "number"
+	QuestionKindBoolean      QuestionKind = "boolean"
+)
+
+// EventQuestion is a question the organizer asks guests when they RSVP
+// (meal choice, allergies, song requests, etc.)
+type EventQuestion struct {
+	ID        uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EventID   uuid.UUID    `json:"event_id" gorm:"type:uuid;not null;index"`
+	Position  int          `json:"position" gorm:"not null;default:0"`
+	Kind      QuestionKind `json:"kind" gorm:"type:varchar(20);not null"`
+	Prompt    string       `json:"prompt" gorm:"not null"`
+	Options   []string     `json:"options" gorm:"type:jsonb;serializer:json"` // Only for choice questions
+	Required  bool         `json:"required"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (q *EventQuestion) BeforeCreate(tx *gorm.DB) (err error) {
+	if q.ID == uuid.Nil {
+		q.ID = uuid.New()
+	}
+	return
+}
+
+// IsChoice reports whether the question has a fixed set of options
+func (q *EventQuestion) IsChoice() bool {
+	return q.Kind == QuestionKindSingleChoice || q.Kind == QuestionKindMultiChoice
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventReminder is one reminder to an event's guests, due at SendAt, when a
+// job is queued to send it. Each start time of an event or occurrence is
+// reminded at most once per offset; sent reminders are kept so a reschedule
+// to the same time doesn't repeat them.
+type EventReminder struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EventID        uuid.UUID  `json:"event_id" gorm:"type:uuid;not null;uniqueIndex:idx_event_reminders_slot"`
+	OccurrenceDate *time.Time `json:"occurrence_date"`                                                // Original start of the occurrence, nil for one-off events
+	StartsAt       time.Time  `json:"starts_at" gorm:"not null;uniqueIndex:idx_event_reminders_slot"` // Actual start being reminded of
+	OffsetMinutes  int        `json:"offset_minutes" gorm:"not null;uniqueIndex:idx_event_reminders_slot"`
+	SendAt         time.Time  `json:"send_at" gorm:"not null;index:idx_event_reminders_due,where:sent_at IS NULL"`
+	QueuedAt       *time.Time `json:"queued_at"` // Set when the job sending it is queued
+	SentAt         *time.Time `json:"sent_at"`   // Set once every guest was emailed, or the reminder turned out moot
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (r *EventReminder) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventStatusTransition records a single change of an event's status
+type EventStatusTransition struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EventID    uuid.UUID `json:"event_id" gorm:"type:uuid;not null;index"`
+	FromStatus string    `json:"from_status" gorm:"not null"`
+	ToStatus   string    `json:"to_status" gorm:"not null"`
+	Reason     string    `json:"reason"`
+	ActorID    uuid.UUID `json:"actor_id" gorm:"type:uuid;not null"`
+	Actor      User      `json:"actor" gorm:"foreignKey:ActorID"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (t *EventStatusTransition) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
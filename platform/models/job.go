@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// JobStatus is where a background job is in its life
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"    // Waiting for RunAt, including retries after a failure
+	JobStatusRunning   JobStatus = "running"   // Claimed by a worker
+	JobStatusSucceeded JobStatus = "succeeded" // Done
+	JobStatusDead      JobStatus = "dead"      // Gave up; kept for inspection and manual retry
+)
+
+// JobPayload is the input of a job, as JSON
+type JobPayload map[string]interface{}
+
+// Job is a unit of background work, run by whichever worker claims it first.
+// Jobs with a UniqueKey are only queued once while an earlier one is unfinished.
+type Job struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Kind        string     `json:"kind" gorm:"type:varchar(100);not null"`
+	Payload     JobPayload `json:"payload" gorm:"type:jsonb;serializer:json"`
+	EventID     *uuid.UUID `json:"event_id" gorm:"type:uuid;index"` // Event the job works on, if any
+	UniqueKey   string     `json:"unique_key" gorm:"not null;default:'';uniqueIndex:idx_jobs_unique_key,where:unique_key <> '' AND finished_at IS NULL"`
+	Status      JobStatus  `json:"status" gorm:"type:varchar(20);not null;index:idx_jobs_due,priority:1"`
+	RunAt       time.Time  `json:"run_at" gorm:"not null;index:idx_jobs_due,priority:2"`
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int        `json:"max_attempts" gorm:"not null"`
+	LastError   string     `json:"last_error"`
+	LockedAt    *time.Time `json:"locked_at"` // When the running attempt started
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (j *Job) BeforeCreate(tx *gorm.DB) (err error) {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return
+}
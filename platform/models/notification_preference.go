@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationCategory groups the emails a user can turn off together
+type NotificationCategory string
+
+const (
+	NotificationRSVPConfirmations  NotificationCategory = "rsvp_confirmations"
+	NotificationEventUpdates       NotificationCategory = "event_updates"
+	NotificationEventCancellations NotificationCategory = "event_cancellations"
+	NotificationEventReminders     NotificationCategory = "event_reminders"
+	NotificationCommentMentions    NotificationCategory = "comment_mentions"
+	NotificationAnnouncements      NotificationCategory = "event_announcements"
+)
+
+// NotificationCategories lists every category, in the order settings show them
+var NotificationCategories = []NotificationCategory{
+	NotificationRSVPConfirmations,
+	NotificationEventUpdates,
+	NotificationEventCancellations,
+	NotificationEventReminders,
+	NotificationCommentMentions,
+	NotificationAnnouncements,
+}
+
+// IsValid reports whether the category is one of the known categories
+func (c NotificationCategory) IsValid() bool {
+	for _, category := range NotificationCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationPreference holds a user's email opt-outs. Users without a row
+// get every email, so only opting out needs to be stored.
+type NotificationPreference struct {
+	UserID             uuid.UUID              `json:"user_id" gorm:"type:uuid;primary_key"`
+	EmailDisabled      bool                   `json:"email_disabled"` // Unsubscribed from all email
+	DisabledCategories []NotificationCategory `json:"disabled_categories" gorm:"type:jsonb;serializer:json"`
+	UpdatedAt          time.Time              `json:"updated_at"`
+}
+
+// Allows reports whether the user wants email of a category
+func (p *NotificationPreference) Allows(category NotificationCategory) bool {
+	if p.EmailDisabled {
+		return false
+	}
+	for _, disabled := range p.DisabledCategories {
+		if disabled == category {
+			return false
+		}
+	}
+	return true
+}
@@ -13,15 +13,27 @@ const (
 	RSVPResponseYes   RSVPResponse = "yes"
 	RSVPResponseNo    RSVPResponse = "no"
 	RSVPResponseMaybe RSVPResponse = "maybe"
+
+	// RSVPResponseWaitlisted is assigned by the server to a "yes" that arrives once the event is full
+	RSVPResponseWaitlisted RSVPResponse = "waitlisted"
 )
 
+// RSVPAnswers maps an EventQuestion ID to the guest's answer
+type RSVPAnswers map[string]interface{}
+
 type RSVP struct {
-	ID        uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	UserID    uuid.UUID    `json:"user_id" gorm:"type:uuid;not null;index"`
-	EventID   uuid.UUID    `json:"event_id" gorm:"type:uuid;not null;index"`
-	Response  RSVPResponse `json:"response" gorm:"type:varchar(10);not null"`
-	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
+	ID             uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID         uuid.UUID    `json:"user_id" gorm:"type:uuid;not null;index"`
+	EventID        uuid.UUID    `json:"event_id" gorm:"type:uuid;not null;index"`
+	OccurrenceDate *time.Time   `json:"occurrence_date" gorm:"index"` // Occurrence of a recurring event, nil for one-off events
+	Response       RSVPResponse `json:"response" gorm:"type:varchar(10);not null"`
+	GuestCount     int          `json:"guest_count" gorm:"not null;default:1"`        // People in the party, including the guest
+	Companions     []string     `json:"companions" gorm:"type:jsonb;serializer:json"` // Optional names of the other people in the party
+	Answers        RSVPAnswers  `json:"answers" gorm:"type:jsonb;serializer:json"`    // Answers to the event's questions
+	VoidedAt       *time.Time   `json:"voided_at"`                                    // Set while the event is cancelled
+	WaitlistedAt   *time.Time   `json:"waitlisted_at" gorm:"index"`                   // Orders the waitlist while Response is waitlisted
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
 
 	// Relationships
 	User  User  `json:"user" gorm:"foreignKey:UserID"`
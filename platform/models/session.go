@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session is a browser's session, kept on the server so it can be revoked.
+// The cookie only carries the session token, of which a hash is stored.
+type Session struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TokenHash  string     `json:"-" gorm:"not null;uniqueIndex"`  // SHA-256 of the session token, hex encoded
+	UserID     *uuid.UUID `json:"user_id" gorm:"type:uuid;index"` // Empty until the browser signs in
+	Data       []byte     `json:"-" gorm:"type:bytea"`            // The session's values, gob encoded
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" gorm:"not null;index"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"` // Absolute expiry, however active the session is
+}
+
+// BeforeCreate hook to generate UUID
+func (s *Session) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
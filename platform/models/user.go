@@ -7,6 +7,24 @@ import (
 	"gorm.io/gorm"
 )
 
+// UserRole is a user's role across the whole app, as opposed to their role on an event
+type UserRole string
+
+const (
+	UserRoleUser      UserRole = "user"
+	UserRoleModerator UserRole = "moderator" // Can unpublish abusive events
+	UserRoleAdmin     UserRole = "admin"     // Can also manage users and impersonate them
+)
+
+// IsValid reports whether the role is one of the known roles
+func (r UserRole) IsValid() bool {
+	switch r {
+	case UserRoleUser, UserRoleModerator, UserRoleAdmin:
+		return true
+	}
+	return false
+}
+
 // User represents a user in the system
 type User struct {
 	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -14,7 +32,7 @@ type User struct {
 	Name      string    `json:"name" gorm:"not null"`
 	Picture   string    `json:"picture"`
 	AuthID    string    `json:"auth_id" gorm:"uniqueIndex"` // Auth0 user ID
-	Role      string    `json:"role" gorm:"default:'user'"`
+	Role      UserRole  `json:"role" gorm:"default:'user'"`
 	IsActive  bool      `json:"is_active" gorm:"default:true"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -32,3 +50,16 @@ func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+// PublicUser is the part of a user shown next to what they post on an event,
+// which anyone who can see the event can read
+type PublicUser struct {
+	ID      uuid.UUID `json:"id"`
+	Name    string    `json:"name"`
+	Picture string    `json:"picture"`
+}
+
+// TableName reads public users from the users table
+func (PublicUser) TableName() string {
+	return "users"
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookEventType is something that happened which webhooks can subscribe to
+type WebhookEventType string
+
+const (
+	WebhookEventCreated   WebhookEventType = "event.created"
+	WebhookEventUpdated   WebhookEventType = "event.updated"
+	WebhookEventCancelled WebhookEventType = "event.cancelled"
+	WebhookRSVPSubmitted  WebhookEventType = "rsvp.submitted" // A guest's first answer
+	WebhookRSVPChanged    WebhookEventType = "rsvp.changed"   // A guest changed their answer or party
+)
+
+// WebhookEventTypes lists every event type webhooks can subscribe to
+var WebhookEventTypes = []WebhookEventType{
+	WebhookEventCreated,
+	WebhookEventUpdated,
+	WebhookEventCancelled,
+	WebhookRSVPSubmitted,
+	WebhookRSVPChanged,
+}
+
+// IsValid reports whether the event type is one webhooks can subscribe to
+func (t WebhookEventType) IsValid() bool {
+	for _, known := range WebhookEventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook is an endpoint a user registered to be told about the events they
+// host. Deliveries are signed with the webhook's secret.
+type Webhook struct {
+	ID          uuid.UUID          `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID          `json:"user_id" gorm:"type:uuid;not null;index"`
+	URL         string             `json:"url" gorm:"not null"`
+	Description string             `json:"description"`
+	EventTypes  []WebhookEventType `json:"event_types" gorm:"type:jsonb;serializer:json"`
+	Secret      string             `json:"-" gorm:"not null"` // HMAC key, only shown when the webhook is created
+	Disabled    bool               `json:"disabled" gorm:"not null;default:false"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (w *Webhook) BeforeCreate(tx *gorm.DB) (err error) {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return
+}
+
+// Subscribes reports whether the webhook wants deliveries of an event type
+func (w *Webhook) Subscribes(eventType WebhookEventType) bool {
+	if w.Disabled {
+		return false
+	}
+	for _, subscribed := range w.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus is how sending a delivery went so far
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"   // Not sent yet or being retried
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded" // The endpoint answered with a 2xx
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"    // Out of retries
+)
+
+// WebhookPayload is the data of a delivery, as JSON
+type WebhookPayload map[string]interface{}
+
+// WebhookDelivery is one thing that happened, sent to one webhook. Its
+// payload is fixed when it is created, so retries and redeliveries send the
+// same body.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	WebhookID      uuid.UUID             `json:"webhook_id" gorm:"type:uuid;not null;index:idx_webhook_deliveries_webhook,priority:1"`
+	EventType      WebhookEventType      `json:"event_type" gorm:"type:varchar(50);not null"`
+	Payload        WebhookPayload        `json:"payload" gorm:"type:jsonb;serializer:json"`
+	Status         WebhookDeliveryStatus `json:"status" gorm:"type:varchar(20);not null"`
+	LastStatusCode int                   `json:"last_status_code"` // Response code of the latest attempt, 0 if there was none
+	CreatedAt      time.Time             `json:"created_at" gorm:"index:idx_webhook_deliveries_webhook,priority:2"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+
+	Attempts []WebhookAttempt `json:"attempts,omitempty" gorm:"foreignKey:DeliveryID;constraint:OnDelete:CASCADE"`
+}
+
+// BeforeCreate hook to generate UUID
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return
+}
+
+// WebhookAttempt records one try at sending a delivery
+type WebhookAttempt struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	DeliveryID   uuid.UUID `json:"delivery_id" gorm:"type:uuid;not null;index"`
+	StatusCode   int       `json:"status_code"`   // 0 when no response came back
+	Error        string    `json:"error"`         // Network error, or why the response counts as a failure
+	ResponseBody string    `json:"response_body"` // Start of the response body
+	DurationMs   int64     `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (a *WebhookAttempt) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,207 @@
+// Package notifications renders templated emails and hands them to a
+// pluggable transport.
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+
+	"01-Login/platform/models"
+)
+
+// Template names an email; each has a .txt file defining "subject" and
+// "content" and an .html file defining "content" under templates/
+type Template string
+
+const (
+	TemplateRSVPConfirmation  Template = "rsvp_confirmation"
+	TemplateEventUpdated      Template = "event_updated"
+	TemplateEventCancelled    Template = "event_cancelled"
+	TemplateEventReminder     Template = "event_reminder"
+	TemplateCommentMention    Template = "comment_mention"
+	TemplateAnnouncement      Template = "event_announcement"
+	TemplateDatePollFinalized Template = "date_poll_finalized"
+	TemplateInvitation        Template = "event_invitation"
+)
+
+var templateNames = []Template{
+	TemplateRSVPConfirmation,
+	TemplateEventUpdated,
+	TemplateEventCancelled,
+	TemplateEventReminder,
+	TemplateCommentMention,
+	TemplateAnnouncement,
+	TemplateDatePollFinalized,
+	TemplateInvitation,
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+// Data is what templates are rendered with; each template uses the fields it needs
+type Data struct {
+	RecipientName  string
+	Inviter        string // Who sent an invitation
+	EventURL       string
+	UnsubscribeURL string
+	Event          *models.Event
+	When           string // Start of the event or occurrence in the event's time zone
+	RSVP           *models.RSVP
+	Changes        []Change
+	Reason         string
+	StartsIn       string // How soon a reminded event starts, e.g. "in 1 day"
+	Comment        *models.EventComment
+	Announcement   *models.EventAnnouncement
+}
+
+// Change is one field of an event that changed, formatted for people
+type Change struct {
+	Field string
+	From  string
+	To    string
+}
+
+type emailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// Mailer renders templates into messages and sends them through a transport
+type Mailer struct {
+	transport Transport
+	from      string
+	templates map[Template]emailTemplate
+}
+
+// NewMailer parses the email templates and returns a mailer sending from the given address
+func NewMailer(transport Transport, from string) (*Mailer, error) {
+	templates := make(map[Template]emailTemplate, len(templateNames))
+	for _, name := range templateNames {
+		html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+string(name)+".html")
+		if err != nil {
+			return nil, err
+		}
+		text, err := texttemplate.ParseFS(templateFS, "templates/layout.txt", "templates/"+string(name)+".txt")
+		if err != nil {
+			return nil, err
+		}
+		templates[name] = emailTemplate{html: html, text: text}
+	}
+
+	return &Mailer{transport: transport, from: from, templates: templates}, nil
+}
+
+// Render builds the message for a template without sending it
+func (m *Mailer) Render(to string, name Template, data *Data) (*Message, error) {
+	tmpl, ok := m.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "layout", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		From: m.from,
+		To:   to,
+		// Titles may contain line breaks, which must not reach the header
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// Send renders a template and delivers it, adding the headers given
+func (m *Mailer) Send(to string, name Template, data *Data, headers map[string]string) error {
+	msg, err := m.Render(to, name, data)
+	if err != nil {
+		return err
+	}
+	msg.Headers = headers
+	return m.transport.Send(msg)
+}
+
+var (
+	defaultMailer *Mailer
+	fallbackOnce  sync.Once
+)
+
+// Setup configures the default mailer from the environment:
+//
+//	MAIL_TRANSPORT  smtp, file or log (default)
+//	MAIL_FROM       sender address
+//	MAIL_DIR        directory for the file transport
+//	SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD
+func Setup() error {
+	var transport Transport
+	switch kind := getEnv("MAIL_TRANSPORT", "log"); kind {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return fmt.Errorf("SMTP_HOST is required for the smtp mail transport")
+		}
+		transport = &SMTPTransport{
+			Host:     host,
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	case "file":
+		transport = &FileTransport{Dir: getEnv("MAIL_DIR", "tmp/mail")}
+	case "log":
+		transport = LogTransport{}
+	default:
+		return fmt.Errorf("unknown MAIL_TRANSPORT %q", kind)
+	}
+
+	mailer, err := NewMailer(transport, getEnv("MAIL_FROM", "Events <no-reply@localhost>"))
+	if err != nil {
+		return err
+	}
+	defaultMailer = mailer
+	return nil
+}
+
+// Default returns the mailer configured by Setup, or one that only logs if
+// Setup hasn't run
+func Default() *Mailer {
+	fallbackOnce.Do(func() {
+		if defaultMailer != nil {
+			return
+		}
+		mailer, err := NewMailer(LogTransport{}, "Events <no-reply@localhost>")
+		if err != nil {
+			log.Fatalf("Failed to parse email templates: %v", err)
+		}
+		defaultMailer = mailer
+	})
+	return defaultMailer
+}
+
+// AppURL is the public address of the app that links in emails point to
+func AppURL() string {
+	return strings.TrimSuffix(getEnv("APP_BASE_URL", "http://localhost:3000"), "/")
+}
+
+// getEnv gets environment variable with fallback
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
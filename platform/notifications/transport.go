@@ -0,0 +1,81 @@
+package notifications
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Transport delivers rendered messages
+type Transport interface {
+	Send(msg *Message) error
+}
+
+// SMTPTransport delivers through an SMTP server, upgrading to TLS with
+// STARTTLS when the server offers it
+type SMTPTransport struct {
+	Host     string
+	Port     string
+	Username string // Leave empty for servers without authentication
+	Password string
+}
+
+// Send delivers the message to its recipient
+func (t *SMTPTransport) Send(msg *Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", msg.From, err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if t.Username != "" {
+		auth = smtp.PlainAuth("", t.Username, t.Password, t.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(t.Host, t.Port), auth, from.Address, []string{to.Address}, body)
+}
+
+// FileTransport writes every message to an .eml file in a directory instead
+// of sending it, for local development and tests
+type FileTransport struct {
+	Dir string
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9@._-]+`)
+
+// Send writes the message to a new file named after the time and recipient
+func (t *FileTransport) Send(msg *Message) error {
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	return os.WriteFile(filepath.Join(t.Dir, name), body, 0o644)
+}
+
+// LogTransport logs messages instead of sending them
+type LogTransport struct{}
+
+// Send logs the recipient, subject and text body
+func (LogTransport) Send(msg *Message) error {
+	log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package realtime tells the clients streaming an event when it changes.
+// Changes are published with Postgres NOTIFY in the transaction that makes
+// them, so they only go out once committed, and every server instance
+// LISTENs and hands them to its own subscribers.
+package realtime
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Kind is what changed about an event
+type Kind string
+
+const (
+	KindRSVPs   Kind = "rsvps"   // RSVPs were added, changed or removed
+	KindEvent   Kind = "event"   // The event's details were edited
+	KindStatus  Kind = "status"  // The event was published, cancelled or reopened
+	KindDeleted Kind = "deleted" // The event is gone
+)
+
+var allKinds = []Kind{KindRSVPs, KindEvent, KindStatus}
+
+// Message says that something about an event changed. It carries no data:
+// subscribers load what they show, so they never show anything that wasn't
+// committed or that the viewer may no longer see.
+type Message struct {
+	EventID uuid.UUID `json:"event_id"`
+	Kind    Kind      `json:"kind"`
+}
+
+// Hub hands messages to the subscribers of their event
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[*Subscription]struct{}
+}
+
+// NewHub creates a hub without subscribers
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[uuid.UUID]map[*Subscription]struct{})}
+}
+
+// Subscription collects what changed about one event until it is taken.
+// Changes of the same kind are merged, so a slow subscriber never blocks
+// publishers and never misses a kind of change.
+type Subscription struct {
+	hub     *Hub
+	eventID uuid.UUID
+	mu      sync.Mutex
+	pending map[Kind]bool
+	ready   chan struct{}
+}
+
+// Subscribe starts collecting changes to an event; Close must be called when done
+func (h *Hub) Subscribe(eventID uuid.UUID) *Subscription {
+	sub := &Subscription{
+		hub:     h,
+		eventID: eventID,
+		pending: make(map[Kind]bool),
+		ready:   make(chan struct{}, 1),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[eventID] == nil {
+		h.subscribers[eventID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[eventID][sub] = struct{}{}
+	return sub
+}
+
+// Publish hands a message to the event's subscribers without waiting on them
+func (h *Hub) Publish(msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers[msg.EventID] {
+		sub.add(msg.Kind)
+	}
+}
+
+// resync tells every subscriber that anything may have changed, for when
+// messages may have been missed
+func (h *Hub) resync() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			sub.add(allKinds...)
+		}
+	}
+}
+
+// Ready receives once there are changes to take
+func (s *Subscription) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Take returns the kinds of change since the last call, and forgets them
+func (s *Subscription) Take() []Kind {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kinds := make([]Kind, 0, len(s.pending))
+	for _, kind := range []Kind{KindStatus, KindEvent, KindRSVPs, KindDeleted} {
+		if s.pending[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	s.pending = make(map[Kind]bool)
+	return kinds
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	delete(s.hub.subscribers[s.eventID], s)
+	if len(s.hub.subscribers[s.eventID]) == 0 {
+		delete(s.hub.subscribers, s.eventID)
+	}
+}
+
+func (s *Subscription) add(kinds ...Kind) {
+	s.mu.Lock()
+	for _, kind := range kinds {
+		s.pending[kind] = true
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+		// Already signalled and not taken yet
+	}
+}
+
+var defaultHub = NewHub()
+
+// Subscribe starts collecting changes to an event from every server instance
+func Subscribe(eventID uuid.UUID) *Subscription {
+	return defaultHub.Subscribe(eventID)
+}
@@ -0,0 +1,82 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	// notifyChannel is the Postgres channel changes are sent on
+	notifyChannel = "event_changes"
+	// reconnectDelay is the wait before listening again after the connection failed
+	reconnectDelay = 5 * time.Second
+)
+
+// Notify publishes a change to an event with the transaction making it.
+// Postgres only delivers it once tx commits, and drops it on rollback.
+func Notify(tx *gorm.DB, eventID uuid.UUID, kind Kind) error {
+	payload, err := json.Marshal(Message{EventID: eventID, Kind: kind})
+	if err != nil {
+		return err
+	}
+	return tx.Exec("SELECT pg_notify(?, ?)", notifyChannel, string(payload)).Error
+}
+
+// Listen relays changes published by any server instance to this instance's
+// subscribers until the context is done. It holds its own connection, since
+// pooled ones can't wait for notifications, and reconnects after errors.
+func Listen(ctx context.Context, dsn string) {
+	connected := false
+	for ctx.Err() == nil {
+		err := listen(ctx, dsn, func() {
+			if connected {
+				// Changes made while reconnecting were missed
+				defaultHub.resync()
+			}
+			connected = true
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Error listening for event changes: %v", err)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// listen relays notifications over one connection until it fails
+func listen(ctx context.Context, dsn string, onListening func()) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return err
+	}
+	onListening()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var msg Message
+		if err := json.Unmarshal([]byte(notification.Payload), &msg); err != nil {
+			log.Printf("Ignoring malformed event change %q: %v", notification.Payload, err)
+			continue
+		}
+		defaultHub.Publish(msg)
+	}
+}
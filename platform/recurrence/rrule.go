@@ -0,0 +1,441 @@
+// Package recurrence parses and expands iCalendar (RFC 5545) recurrence rules.
+//
+// The supported subset covers what people use for birthdays, anniversaries and
+// regular parties: FREQ=DAILY/WEEKLY/MONTHLY/YEARLY with INTERVAL, COUNT,
+// UNTIL, BYDAY (with ordinals such as 1MO or -1FR for monthly and yearly
+// rules), BYMONTHDAY and BYMONTH. Weeks start on Monday.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the FREQ part of a rule
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds how far a rule is walked, so a bad window can't spin forever
+const maxPeriods = 50000
+
+// WeekdayNum is a BYDAY entry, e.g. "MO", "2TU" or "-1FR"
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int // 0 means every such weekday in the period
+}
+
+// Rule is a parsed RRULE
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int       // 0 means no limit
+	Until      time.Time // zero means no limit
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+var allMonths = []time.Month{
+	time.January, time.February, time.March, time.April, time.May, time.June,
+	time.July, time.August, time.September, time.October, time.November, time.December,
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Parse parses an RRULE value such as "FREQ=YEARLY;BYMONTH=6;BYMONTHDAY=14".
+// A leading "RRULE:" is accepted.
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, errors.New("empty recurrence rule")
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("malformed rule part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			switch f := Frequency(strings.ToUpper(val)); f {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = f
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, item := range strings.Split(val, ",") {
+				wd, err := parseWeekdayNum(item)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(item)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", item)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, item := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(item)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", item)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			if strings.ToUpper(val) != "MO" {
+				return nil, errors.New("only WKST=MO is supported")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL cannot both be set")
+	}
+	for _, wd := range rule.ByDay {
+		if wd.N != 0 && rule.Freq != Monthly && rule.Freq != Yearly {
+			return nil, errors.New("numbered BYDAY is only allowed in MONTHLY and YEARLY rules")
+		}
+	}
+	if rule.Freq == Yearly && len(rule.ByDay) > 0 && len(rule.ByMonth) == 0 {
+		return nil, errors.New("BYDAY in a YEARLY rule requires BYMONTH")
+	}
+
+	return rule, nil
+}
+
+// String formats the rule back into RRULE syntax (without the "RRULE:" prefix)
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = wd.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (wd WeekdayNum) String() string {
+	for code, day := range weekdays {
+		if day == wd.Weekday {
+			if wd.N != 0 {
+				return strconv.Itoa(wd.N) + code
+			}
+			return code
+		}
+	}
+	return ""
+}
+
+// Between returns the occurrences of a series starting at dtstart that fall
+// within [from, to], skipping any listed in exdates. Occurrences keep the
+// wall-clock time of dtstart in dtstart's location.
+func (r *Rule) Between(dtstart, from, to time.Time, exdates []time.Time) []time.Time {
+	excluded := make(map[int64]bool, len(exdates))
+	for _, ex := range exdates {
+		excluded[ex.Unix()] = true
+	}
+
+	var occurrences []time.Time
+	r.walk(dtstart, to, func(t time.Time) {
+		if !t.Before(from) && !excluded[t.Unix()] {
+			occurrences = append(occurrences, t)
+		}
+	})
+	return occurrences
+}
+
+// Includes reports whether t is an occurrence of the series starting at dtstart
+func (r *Rule) Includes(dtstart, t time.Time) bool {
+	found := false
+	r.walk(dtstart, t, func(o time.Time) {
+		if o.Equal(t) {
+			found = true
+		}
+	})
+	return found
+}
+
+// walk calls fn for every occurrence from dtstart up to and including limit, in order
+func (r *Rule) walk(dtstart, limit time.Time, fn func(time.Time)) {
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if t.After(limit) || (!r.Until.IsZero() && t.After(r.Until)) {
+			return false
+		}
+		if r.Count > 0 && emitted >= r.Count {
+			return false
+		}
+		emitted++
+		fn(t)
+		return true
+	}
+
+	// DTSTART is always the first occurrence
+	if !emit(dtstart) {
+		return
+	}
+
+	for period := 0; period < maxPeriods; period++ {
+		candidates := r.expandPeriod(dtstart, period)
+		if len(candidates) == 0 && r.periodStart(dtstart, period).After(limit) {
+			return
+		}
+		for _, t := range candidates {
+			if !t.After(dtstart) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// periodStart returns the first day of the n-th period of the series
+func (r *Rule) periodStart(dtstart time.Time, n int) time.Time {
+	y, m, d := dtstart.Date()
+	loc := dtstart.Location()
+	step := n * r.Interval
+
+	switch r.Freq {
+	case Daily:
+		return time.Date(y, m, d+step, 0, 0, 0, 0, loc)
+	case Weekly:
+		offset := (int(dtstart.Weekday()) + 6) % 7 // days since Monday
+		return time.Date(y, m, d-offset+7*step, 0, 0, 0, 0, loc)
+	case Monthly:
+		return time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(y+step, 1, 1, 0, 0, 0, 0, loc)
+	}
+}
+
+// expandPeriod returns the sorted candidate occurrences in the n-th period
+func (r *Rule) expandPeriod(dtstart time.Time, n int) []time.Time {
+	start := r.periodStart(dtstart, n)
+	var days []time.Time
+
+	switch r.Freq {
+	case Daily:
+		if r.matchesDay(start, false) {
+			days = append(days, start)
+		}
+	case Weekly:
+		for i := 0; i < 7; i++ {
+			day := start.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && day.Weekday() != dtstart.Weekday() {
+				continue
+			}
+			if r.matchesDay(day, false) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		days = r.daysInMonth(dtstart, start.Year(), start.Month())
+	case Yearly:
+		// Without BYMONTH, BYMONTHDAY picks days in every month of the
+		// year; otherwise the rule repeats in dtstart's month
+		months := r.ByMonth
+		if len(months) == 0 && len(r.ByMonthDay) > 0 {
+			months = allMonths
+		} else if len(months) == 0 {
+			months = []time.Month{dtstart.Month()}
+		}
+		for _, m := range months {
+			days = append(days, r.daysInMonth(dtstart, start.Year(), m)...)
+		}
+	}
+
+	occurrences := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		y, m, d := day.Date()
+		occurrences = append(occurrences, time.Date(y, m, d,
+			dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location()))
+	}
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].Before(occurrences[j]) })
+	return occurrences
+}
+
+// daysInMonth returns the days of a month selected by BYMONTHDAY/BYDAY, or
+// dtstart's day of the month when neither is set
+func (r *Rule) daysInMonth(dtstart time.Time, year int, month time.Month) []time.Time {
+	loc := dtstart.Location()
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	length := first.AddDate(0, 1, -1).Day()
+
+	var days []time.Time
+	for d := 1; d <= length; d++ {
+		day := time.Date(year, month, d, 0, 0, 0, 0, loc)
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 && d != dtstart.Day() {
+			continue
+		}
+		if r.matchesDay(day, true) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// matchesDay applies the BYMONTH, BYMONTHDAY and BYDAY filters to a day.
+// Numbered BYDAY entries count within the month when inMonth is set.
+func (r *Rule) matchesDay(day time.Time, inMonth bool) bool {
+	if len(r.ByMonth) > 0 {
+		found := false
+		for _, m := range r.ByMonth {
+			if day.Month() == m {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	length := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+
+	if len(r.ByMonthDay) > 0 {
+		found := false
+		for _, md := range r.ByMonthDay {
+			if md == day.Day() || (md < 0 && length+md+1 == day.Day()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(r.ByDay) > 0 {
+		found := false
+		for _, wd := range r.ByDay {
+			if wd.Weekday != day.Weekday() {
+				continue
+			}
+			if wd.N == 0 || !inMonth {
+				found = true
+				break
+			}
+			nth := (day.Day()-1)/7 + 1
+			nthFromEnd := -((length-day.Day())/7 + 1)
+			if wd.N == nth || wd.N == nthFromEnd {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseWeekdayNum(value string) (WeekdayNum, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+	day, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+
+	wd := WeekdayNum{Weekday: day}
+	if prefix := value[:len(value)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A date-only UNTIL includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", value)
+}
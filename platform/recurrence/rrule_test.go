@@ -0,0 +1,224 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 18, 30, 0, 0, time.UTC)
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		from    time.Time
+		to      time.Time
+		exdates []time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "daily with interval",
+			rule:    "FREQ=DAILY;INTERVAL=2",
+			dtstart: date(2024, 1, 1),
+			from:    date(2024, 1, 1),
+			to:      date(2024, 1, 7),
+			want:    []time.Time{date(2024, 1, 1), date(2024, 1, 3), date(2024, 1, 5), date(2024, 1, 7)},
+		},
+		{
+			name:    "weekly on several days",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE",
+			dtstart: date(2024, 1, 1), // a Monday
+			from:    date(2024, 1, 1),
+			to:      date(2024, 1, 14),
+			want:    []time.Time{date(2024, 1, 1), date(2024, 1, 3), date(2024, 1, 8), date(2024, 1, 10)},
+		},
+		{
+			name:    "weekly defaults to dtstart's weekday",
+			rule:    "FREQ=WEEKLY",
+			dtstart: date(2024, 1, 4), // a Thursday
+			from:    date(2024, 1, 1),
+			to:      date(2024, 1, 20),
+			want:    []time.Time{date(2024, 1, 4), date(2024, 1, 11), date(2024, 1, 18)},
+		},
+		{
+			name:    "monthly on the last Friday",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			dtstart: date(2024, 1, 26),
+			from:    date(2024, 1, 1),
+			to:      date(2024, 12, 31),
+			want:    []time.Time{date(2024, 1, 26), date(2024, 2, 23), date(2024, 3, 29)},
+		},
+		{
+			name:    "monthly on the last day",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1",
+			dtstart: date(2024, 1, 31),
+			from:    date(2024, 1, 1),
+			to:      date(2024, 4, 30),
+			want:    []time.Time{date(2024, 1, 31), date(2024, 2, 29), date(2024, 3, 31), date(2024, 4, 30)},
+		},
+		{
+			name:    "monthly on the 31st skips short months",
+			rule:    "FREQ=MONTHLY",
+			dtstart: date(2024, 1, 31),
+			from:    date(2024, 1, 1),
+			to:      date(2024, 5, 31),
+			want:    []time.Time{date(2024, 1, 31), date(2024, 3, 31), date(2024, 5, 31)},
+		},
+		{
+			name:    "yearly birthday",
+			rule:    "FREQ=YEARLY",
+			dtstart: date(2020, 6, 14),
+			from:    date(2022, 1, 1),
+			to:      date(2024, 12, 31),
+			want:    []time.Time{date(2022, 6, 14), date(2023, 6, 14), date(2024, 6, 14)},
+		},
+		{
+			name:    "yearly on the 29th of February only in leap years",
+			rule:    "FREQ=YEARLY",
+			dtstart: date(2020, 2, 29),
+			from:    date(2020, 1, 1),
+			to:      date(2028, 12, 31),
+			want:    []time.Time{date(2020, 2, 29), date(2024, 2, 29), date(2028, 2, 29)},
+		},
+		{
+			name:    "yearly by month and month day",
+			rule:    "FREQ=YEARLY;BYMONTH=6,12;BYMONTHDAY=1",
+			dtstart: date(2024, 6, 1),
+			from:    date(2024, 1, 1),
+			to:      date(2025, 6, 30),
+			want:    []time.Time{date(2024, 6, 1), date(2024, 12, 1), date(2025, 6, 1)},
+		},
+		{
+			name:    "yearly by month day without month repeats every month",
+			rule:    "FREQ=YEARLY;BYMONTHDAY=15",
+			dtstart: date(2024, 1, 15),
+			from:    date(2024, 1, 1),
+			to:      date(2024, 4, 30),
+			want:    []time.Time{date(2024, 1, 15), date(2024, 2, 15), date(2024, 3, 15), date(2024, 4, 15)},
+		},
+		{
+			name:    "yearly thanksgiving",
+			rule:    "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+			dtstart: date(2023, 11, 23),
+			from:    date(2023, 1, 1),
+			to:      date(2025, 12, 31),
+			want:    []time.Time{date(2023, 11, 23), date(2024, 11, 28), date(2025, 11, 27)},
+		},
+		{
+			name:    "until includes the whole day",
+			rule:    "FREQ=DAILY;UNTIL=20240103",
+			dtstart: date(2024, 1, 1),
+			from:    date(2024, 1, 1),
+			to:      date(2024, 1, 10),
+			want:    []time.Time{date(2024, 1, 1), date(2024, 1, 2), date(2024, 1, 3)},
+		},
+		{
+			name:    "count counts occurrences before the window",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: date(2024, 1, 1),
+			from:    date(2024, 1, 2),
+			to:      date(2024, 1, 10),
+			want:    []time.Time{date(2024, 1, 2), date(2024, 1, 3)},
+		},
+		{
+			name:    "exdates are skipped",
+			rule:    "FREQ=DAILY",
+			dtstart: date(2024, 1, 1),
+			from:    date(2024, 1, 1),
+			to:      date(2024, 1, 3),
+			exdates: []time.Time{date(2024, 1, 2)},
+			want:    []time.Time{date(2024, 1, 1), date(2024, 1, 3)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			got := rule.Between(tt.dtstart, tt.from, tt.to, tt.exdates)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Fatalf("occurrence %d: got %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBetweenKeepsWallClockAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	rule, _ := Parse("FREQ=WEEKLY")
+	dtstart := time.Date(2024, 3, 24, 19, 0, 0, 0, berlin) // the week before the clocks change
+
+	got := rule.Between(dtstart, dtstart, dtstart.AddDate(0, 0, 7), nil)
+	if len(got) != 2 {
+		t.Fatalf("got %v, want 2 occurrences", got)
+	}
+	if got[1].Hour() != 19 {
+		t.Fatalf("second occurrence at %v, want 19:00 local time", got[1])
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	rule, _ := Parse("FREQ=MONTHLY;BYDAY=1MO")
+	dtstart := date(2024, 1, 1)
+
+	if !rule.Includes(dtstart, date(2024, 2, 5)) {
+		t.Error("first Monday of February should be included")
+	}
+	if rule.Includes(dtstart, date(2024, 2, 12)) {
+		t.Error("second Monday of February should not be included")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string // formatted back; empty when Parse must fail
+		wantErr bool
+	}{
+		{value: "RRULE:FREQ=YEARLY;BYMONTH=6;BYMONTHDAY=14", want: "FREQ=YEARLY;BYMONTHDAY=14;BYMONTH=6"},
+		{value: "freq=weekly;interval=2;byday=mo,fr", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{value: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=5", want: "FREQ=MONTHLY;COUNT=5;BYDAY=-1FR"},
+		{value: "FREQ=DAILY;UNTIL=20240105T120000Z", want: "FREQ=DAILY;UNTIL=20240105T120000Z"},
+		{value: "", wantErr: true},
+		{value: "INTERVAL=2", wantErr: true},
+		{value: "FREQ=HOURLY", wantErr: true},
+		{value: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{value: "FREQ=DAILY;COUNT=2;UNTIL=20240105", wantErr: true},
+		{value: "FREQ=WEEKLY;BYDAY=2MO", wantErr: true},
+		{value: "FREQ=YEARLY;BYDAY=MO", wantErr: true},
+		{value: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{value: "FREQ=DAILY;WKST=SU", wantErr: true},
+		{value: "FREQ=DAILY;BYSETPOS=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			rule, err := Parse(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %v, want an error", tt.value, rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.value, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Fatalf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,16 +3,16 @@ package router
 import (
 	"encoding/gob"
 	"net/http"
-	"os"
-	"time"
 
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 
 	"01-Login/platform/authenticator"
+	"01-Login/platform/authorization"
 	"01-Login/platform/controllers"
 	"01-Login/platform/middleware"
+	"01-Login/platform/models"
+	"01-Login/platform/sessionstore"
 	"01-Login/web/app/callback"
 	createevent "01-Login/web/app/create-event"
 	editevent "01-Login/web/app/edit-event"
@@ -21,32 +21,28 @@ import (
 	"01-Login/web/app/login"
 	"01-Login/web/app/logout"
 	"01-Login/web/app/signup"
+	"01-Login/web/app/unsubscribe"
 	"01-Login/web/app/user"
 )
 
-// New registers the routes and returns the router.
-func New(auth *authenticator.Authenticator) *gin.Engine {
+// New registers the routes and returns the router. Sessions are kept in
+// the given store.
+func New(auth *authenticator.Authenticator, store *sessionstore.Store) *gin.Engine {
 	router := gin.Default()
 
-	// To store custom types in our cookies,
+	// To store custom types in our sessions,
 	// we must first register them using gob.Register
 	gob.Register(map[string]interface{}{})
 
-	// Use environment variable for session secret, fallback to a default for development
-	sessionSecret := os.Getenv("SESSION_SECRET")
-	if sessionSecret == "" {
-		sessionSecret = "your-secret-key-change-in-production"
-	}
-
-	store := cookie.NewStore([]byte(sessionSecret))
+	// The store decides how long sessions last
 	store.Options(sessions.Options{
 		Path:     "/",
-		MaxAge:   int(24 * time.Hour.Seconds()),
 		Secure:   true, // Always use secure cookies for OAuth (ngrok/production)
 		HttpOnly: true,
 		SameSite: http.SameSiteNoneMode, // Required for cross-site OAuth flows
 	})
 	router.Use(sessions.Sessions("auth-session", store))
+	middleware.UseAuthenticator(auth)
 
 	// Serve static files
 	router.Static("/static", "web/static")
@@ -64,11 +60,43 @@ func New(auth *authenticator.Authenticator) *gin.Engine {
 	router.GET("/edit-event/:id", middleware.IsAuthenticated, editevent.Handler)
 	router.GET("/events/:id", events.DetailHandler)
 	router.GET("/logout", logout.Handler)
+	router.GET("/unsubscribe", unsubscribe.Handler)
 
 	// Initialize controllers
 	userController := controllers.NewUserController()
 	eventController := controllers.NewEventController()
 	rsvpController := controllers.NewRSVPController()
+	questionController := controllers.NewQuestionController()
+	calendarController := controllers.NewCalendarController()
+	invitationController := controllers.NewInvitationController()
+	memberController := controllers.NewMemberController()
+	notificationController := controllers.NewNotificationController()
+	webhookController := controllers.NewWebhookController()
+	streamController := controllers.NewStreamController()
+	commentController := controllers.NewCommentController()
+	announcementController := controllers.NewAnnouncementController()
+	datePollController := controllers.NewDatePollController()
+	sessionController := controllers.NewSessionController(store)
+	accessTokenController := controllers.NewAccessTokenController()
+	adminController := controllers.NewAdminController(store)
+
+	// Per-event permission checks
+	canEdit := middleware.RequireEventPermission(authorization.EventPermissionEdit)
+	canViewGuests := middleware.RequireEventPermission(authorization.EventPermissionViewGuests)
+	canDelete := middleware.RequireEventPermission(authorization.EventPermissionDelete)
+	canManageMembers := middleware.RequireEventPermission(authorization.EventPermissionManageMembers)
+	canModerateComments := middleware.RequireEventPermission(authorization.EventPermissionModerateComments)
+
+	// App-wide role checks
+	canManageUsers := middleware.RequireUserPermission(authorization.UserPermissionManageUsers)
+	canModerateEvents := middleware.RequireUserPermission(authorization.UserPermissionModerateEvents)
+	canImpersonate := middleware.RequireUserPermission(authorization.UserPermissionImpersonate)
+	canViewAuditLog := middleware.RequireUserPermission(authorization.UserPermissionViewAuditLog)
+
+	// Scopes a personal access token needs; routes without one only take a session
+	readEvents := middleware.RequireScope(models.TokenScopeReadEvents)
+	writeEvents := middleware.RequireScope(models.TokenScopeWriteEvents)
+	rsvp := middleware.RequireScope(models.TokenScopeRSVP)
 
 	// API routes
 	api := router.Group("/api")
@@ -81,37 +109,132 @@ func New(auth *authenticator.Authenticator) *gin.Engine {
 		// User routes
 		users := api.Group("/users")
 		{
-			users.POST("", userController.CreateUser)
-			users.GET("", userController.GetUsers)
-			users.GET("/:id", userController.GetUser)
-			users.PUT("/:id", userController.UpdateUser)
-			users.DELETE("/:id", userController.DeleteUser)
-			users.GET("/email/:email", userController.GetUserByEmail)
-			users.GET("/:id/events", eventController.GetUserEvents)
+			users.POST("", middleware.IsAuthenticatedAPI, canManageUsers, userController.CreateUser)
+			users.GET("", middleware.IsAuthenticatedAPI, canManageUsers, userController.GetUsers)
+			users.GET("/:id", middleware.IsAuthenticatedAPI, canManageUsers, userController.GetUser)
+			users.PUT("/:id", middleware.IsAuthenticatedAPI, canManageUsers, userController.UpdateUser)
+			users.DELETE("/:id", middleware.IsAuthenticatedAPI, canManageUsers, userController.DeleteUser)
+			users.GET("/email/:email", middleware.IsAuthenticatedAPI, canManageUsers, userController.GetUserByEmail)
+			users.GET("/:id/events", readEvents, middleware.LoadUser, eventController.GetUserEvents)
 		}
 
 		// Event routes
 		events := api.Group("/events")
 		{
-			events.POST("", eventController.CreateEvent)
-			events.GET("", eventController.GetEvents)
+			events.POST("", writeEvents, middleware.IsAuthenticatedAPI, eventController.CreateEvent)
+			events.GET("", readEvents, middleware.LoadUser, eventController.GetEvents)
 			events.GET("/public", eventController.GetPublicEvents)
-			events.GET("/upcoming", eventController.GetUpcomingEvents)
-			events.GET("/search", eventController.SearchEvents)
-			events.GET("/date-range", eventController.GetEventsByDateRange)
-			events.GET("/:id", eventController.GetEvent)
-			events.PUT("/:id", eventController.UpdateEvent)
-			events.DELETE("/:id", eventController.DeleteEvent)
+			events.GET("/upcoming", readEvents, middleware.LoadUser, eventController.GetUpcomingEvents)
+			events.GET("/search", readEvents, middleware.LoadUser, eventController.SearchEvents)
+			events.GET("/date-range", readEvents, middleware.LoadUser, eventController.GetEventsByDateRange)
+			events.POST("/import", writeEvents, middleware.IsAuthenticatedAPI, calendarController.ImportEvents)
+			events.GET("/:id", readEvents, middleware.RequireEventViewer, eventController.GetEvent)
+			events.GET("/:id/stream", readEvents, middleware.RequireEventViewer, streamController.StreamEvent)
+			events.PUT("/:id", writeEvents, middleware.IsAuthenticatedAPI, canEdit, eventController.UpdateEvent)
+			events.PATCH("/:id", writeEvents, middleware.IsAuthenticatedAPI, canEdit, eventController.UpdateEvent)
+			events.DELETE("/:id", writeEvents, middleware.IsAuthenticatedAPI, canDelete, eventController.DeleteEvent)
+
+			// Lifecycle routes
+			events.POST("/:id/publish", writeEvents, middleware.IsAuthenticatedAPI, canEdit, eventController.PublishEvent)
+			events.POST("/:id/cancel", writeEvents, middleware.IsAuthenticatedAPI, canEdit, eventController.CancelEvent)
+			events.POST("/:id/reopen", writeEvents, middleware.IsAuthenticatedAPI, canEdit, eventController.ReopenEvent)
+			events.GET("/:id/status-history", readEvents, middleware.IsAuthenticatedAPI, canEdit, eventController.GetEventStatusHistory)
+			events.GET("/:id/reminders", readEvents, middleware.IsAuthenticatedAPI, canEdit, eventController.GetEventReminders)
+			events.GET("/:id/jobs", readEvents, middleware.IsAuthenticatedAPI, canEdit, eventController.GetEventJobs)
+			events.POST("/:id/jobs/:job/retry", writeEvents, middleware.IsAuthenticatedAPI, canEdit, eventController.RetryEventJob)
+
+			// Recurring event routes
+			events.GET("/:id/occurrences", readEvents, middleware.RequireEventViewer, eventController.GetEventOccurrences)
+			events.PUT("/:id/occurrences/:occurrence", writeEvents, middleware.IsAuthenticatedAPI, canEdit, eventController.SetOccurrenceOverride)
+			events.DELETE("/:id/occurrences/:occurrence", writeEvents, middleware.IsAuthenticatedAPI, canEdit, eventController.DeleteOccurrenceOverride)
 
 			// RSVP routes for events
-			events.POST("/:id/rsvp", middleware.IsAuthenticatedAPI, rsvpController.SubmitRSVP)
-			events.GET("/:id/rsvp", middleware.IsAuthenticatedAPI, rsvpController.GetUserRSVP)
-			events.GET("/:id/rsvps", middleware.IsAuthenticatedAPI, rsvpController.GetEventRSVPs)
+			events.POST("/:id/rsvp", rsvp, middleware.IsAuthenticatedAPI, middleware.RequireEventViewer, rsvpController.SubmitRSVP)
+			events.GET("/:id/rsvp", rsvp, middleware.IsAuthenticatedAPI, middleware.RequireEventViewer, rsvpController.GetUserRSVP)
+			events.GET("/:id/rsvps", readEvents, middleware.IsAuthenticatedAPI, canViewGuests, rsvpController.GetEventRSVPs)
+
+			// RSVP question routes
+			events.GET("/:id/questions", readEvents, middleware.RequireEventViewer, questionController.GetEventQuestions)
+			events.PUT("/:id/questions", writeEvents, middleware.IsAuthenticatedAPI, canEdit, questionController.ReplaceEventQuestions)
+
+			// Discussion routes
+			events.GET("/:id/comments", readEvents, middleware.RequireEventViewer, commentController.GetComments)
+			events.POST("/:id/comments", writeEvents, middleware.IsAuthenticatedAPI, middleware.RequireEventViewer, commentController.CreateComment)
+			events.PATCH("/:id/comments/:comment", writeEvents, middleware.IsAuthenticatedAPI, middleware.RequireEventViewer, commentController.UpdateComment)
+			events.DELETE("/:id/comments/:comment", writeEvents, middleware.IsAuthenticatedAPI, middleware.RequireEventViewer, commentController.DeleteComment)
+			events.POST("/:id/comments/:comment/pin", writeEvents, middleware.IsAuthenticatedAPI, canModerateComments, commentController.PinComment)
+			events.DELETE("/:id/comments/:comment/pin", writeEvents, middleware.IsAuthenticatedAPI, canModerateComments, commentController.UnpinComment)
+
+			// Announcement routes
+			events.GET("/:id/announcements", readEvents, middleware.RequireEventViewer, announcementController.GetAnnouncements)
+			events.POST("/:id/announcements", writeEvents, middleware.IsAuthenticatedAPI, canEdit, announcementController.PostAnnouncement)
+			events.GET("/:id/announcements/:announcement/deliveries", readEvents, middleware.IsAuthenticatedAPI, canEdit, announcementController.GetAnnouncementDeliveries)
+
+			// Date poll routes
+			events.GET("/:id/poll", readEvents, middleware.RequireEventViewer, datePollController.GetDatePoll)
+			events.POST("/:id/poll", writeEvents, middleware.IsAuthenticatedAPI, canEdit, datePollController.CreateDatePoll)
+			events.DELETE("/:id/poll", writeEvents, middleware.IsAuthenticatedAPI, canEdit, datePollController.DeleteDatePoll)
+			events.PUT("/:id/poll/votes", rsvp, middleware.IsAuthenticatedAPI, middleware.RequireEventViewer, datePollController.VoteDatePoll)
+			events.POST("/:id/poll/finalize", writeEvents, middleware.IsAuthenticatedAPI, canEdit, datePollController.FinalizeDatePoll)
+
+			// iCalendar export
+			events.GET("/:id/ics", readEvents, middleware.RequireEventViewer, calendarController.ExportEvent)
+
+			// Invitation routes
+			events.POST("/:id/invitations", writeEvents, middleware.IsAuthenticatedAPI, canEdit, invitationController.InviteByEmail)
+			events.GET("/:id/invitations", readEvents, middleware.IsAuthenticatedAPI, canEdit, invitationController.GetEventInvitations)
+			events.DELETE("/:id/invitations/:invitation", writeEvents, middleware.IsAuthenticatedAPI, canEdit, invitationController.DeleteInvitation)
+			events.POST("/:id/invitations/accept", rsvp, middleware.IsAuthenticatedAPI, invitationController.AcceptInvitation)
+			events.GET("/:id/invite-link", readEvents, middleware.IsAuthenticatedAPI, canEdit, invitationController.GetInviteLink)
+			events.POST("/:id/invite-link/reset", writeEvents, middleware.IsAuthenticatedAPI, canEdit, invitationController.ResetInviteLink)
+
+			// Member routes
+			events.GET("/:id/members", readEvents, middleware.IsAuthenticatedAPI, canEdit, memberController.GetMembers)
+			events.POST("/:id/members", writeEvents, middleware.IsAuthenticatedAPI, canManageMembers, memberController.SetMember)
+			events.DELETE("/:id/members/:user", writeEvents, middleware.IsAuthenticatedAPI, canManageMembers, memberController.RemoveMember)
+			events.POST("/:id/transfer-ownership", writeEvents, middleware.IsAuthenticatedAPI, canManageMembers, memberController.TransferOwnership)
 		}
 
+		// Admin routes, for browser sessions only
+		admin := api.Group("/admin")
+		{
+			admin.PUT("/users/:id/role", middleware.IsAuthenticatedAPI, canManageUsers, adminController.SetUserRole)
+			admin.POST("/users/:id/deactivate", middleware.IsAuthenticatedAPI, canManageUsers, adminController.DeactivateUser)
+			admin.POST("/users/:id/reactivate", middleware.IsAuthenticatedAPI, canManageUsers, adminController.ReactivateUser)
+			admin.POST("/users/:id/impersonate", middleware.IsAuthenticatedAPI, canImpersonate, adminController.StartImpersonation)
+			admin.DELETE("/impersonation", middleware.IsAuthenticatedAPI, adminController.EndImpersonation)
+			admin.POST("/events/:id/unpublish", middleware.IsAuthenticatedAPI, canModerateEvents, adminController.UnpublishEvent)
+			admin.POST("/events/:id/lift-hold", middleware.IsAuthenticatedAPI, canModerateEvents, adminController.LiftEventHold)
+			admin.GET("/audit-log", middleware.IsAuthenticatedAPI, canViewAuditLog, adminController.GetAuditLog)
+		}
+
+		// Calendar feed, authenticated by the unguessable token in its URL
+		api.GET("/calendar/:token", calendarController.GetFeedCalendar)
+
+		// One-click unsubscribe, authenticated by the signed token in its URL
+		api.POST("/notifications/unsubscribe", notificationController.Unsubscribe)
+
 		// User RSVP routes
-		api.GET("/user/rsvps", middleware.IsAuthenticatedAPI, rsvpController.GetUserRSVPs)
-		api.GET("/user/events", middleware.IsAuthenticatedAPI, eventController.GetCurrentUserEvents)
+		api.GET("/user/rsvps", readEvents, middleware.IsAuthenticatedAPI, rsvpController.GetUserRSVPs)
+		api.GET("/user/events", readEvents, middleware.IsAuthenticatedAPI, eventController.GetCurrentUserEvents)
+		api.GET("/user/calendar-feed", middleware.IsAuthenticatedAPI, calendarController.GetFeed)
+		api.POST("/user/calendar-feed", middleware.IsAuthenticatedAPI, middleware.NotImpersonating, calendarController.CreateFeed)
+		api.DELETE("/user/calendar-feed", middleware.IsAuthenticatedAPI, calendarController.DeleteFeed)
+		api.GET("/user/tokens", middleware.IsAuthenticatedAPI, accessTokenController.GetTokens)
+		api.POST("/user/tokens", middleware.IsAuthenticatedAPI, middleware.NotImpersonating, accessTokenController.CreateToken)
+		api.DELETE("/user/tokens/:token", middleware.IsAuthenticatedAPI, accessTokenController.DeleteToken)
+		api.GET("/user/sessions", middleware.IsAuthenticatedAPI, middleware.NotImpersonating, sessionController.GetSessions)
+		api.DELETE("/user/sessions", middleware.IsAuthenticatedAPI, middleware.NotImpersonating, sessionController.RevokeOtherSessions)
+		api.DELETE("/user/sessions/:session", middleware.IsAuthenticatedAPI, middleware.NotImpersonating, sessionController.RevokeSession)
+		api.GET("/user/notifications", middleware.IsAuthenticatedAPI, notificationController.GetSettings)
+		api.PUT("/user/notifications", middleware.IsAuthenticatedAPI, notificationController.UpdateSettings)
+		api.GET("/user/webhooks", middleware.IsAuthenticatedAPI, webhookController.GetWebhooks)
+		api.POST("/user/webhooks", middleware.IsAuthenticatedAPI, middleware.NotImpersonating, webhookController.CreateWebhook)
+		api.GET("/user/webhooks/:webhook", middleware.IsAuthenticatedAPI, webhookController.GetWebhook)
+		api.PATCH("/user/webhooks/:webhook", middleware.IsAuthenticatedAPI, middleware.NotImpersonating, webhookController.UpdateWebhook)
+		api.DELETE("/user/webhooks/:webhook", middleware.IsAuthenticatedAPI, webhookController.DeleteWebhook)
+		api.GET("/user/webhooks/:webhook/deliveries", middleware.IsAuthenticatedAPI, webhookController.GetDeliveries)
+		api.POST("/user/webhooks/:webhook/deliveries/:delivery/redeliver", middleware.IsAuthenticatedAPI, webhookController.Redeliver)
 		api.GET("/user/google-photos-status", userController.GooglePhotosStatus) // No auth for debug with user_id param
 	}
 
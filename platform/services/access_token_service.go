@@ -0,0 +1,199 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// accessTokenPrefix starts every personal access token, so leaked ones are easy to spot
+	accessTokenPrefix = "evh_"
+	// accessTokenPrefixLength is how much of a token is kept to tell tokens apart
+	accessTokenPrefixLength = len(accessTokenPrefix) + 6
+	// Tokens last defaultAccessTokenDays unless asked otherwise, and at most maxAccessTokenDays
+	defaultAccessTokenDays = 90
+	maxAccessTokenDays     = 365
+	// maxAccessTokens caps how many tokens one user can have
+	maxAccessTokens = 50
+	// accessTokenTouchInterval limits how often using a token is written back
+	accessTokenTouchInterval = time.Minute
+)
+
+var (
+	// ErrAccessTokenNotFound is returned for tokens the user doesn't have
+	ErrAccessTokenNotFound = errors.New("access token not found")
+	// ErrInvalidAccessToken is returned for unknown and expired tokens
+	ErrInvalidAccessToken = errors.New("invalid or expired access token")
+)
+
+type AccessTokenService struct {
+	db *gorm.DB
+}
+
+// NewAccessTokenService creates a new access token service
+func NewAccessTokenService() *AccessTokenService {
+	return &AccessTokenService{
+		db: database.GetDB(),
+	}
+}
+
+// AccessTokenInput is a new personal access token
+type AccessTokenInput struct {
+	Name          string              `json:"name"`
+	Scopes        []models.TokenScope `json:"scopes"`
+	ExpiresInDays int                 `json:"expires_in_days"` // Defaults to 90
+}
+
+// CreateToken mints a personal access token for the user and returns it
+// along with the token itself, which can't be retrieved later
+func (s *AccessTokenService) CreateToken(userID uuid.UUID, input *AccessTokenInput) (*models.PersonalAccessToken, string, error) {
+	name := strings.TrimSpace(input.Name)
+	days := input.ExpiresInDays
+	if days == 0 {
+		days = defaultAccessTokenDays
+	}
+
+	errs := ValidationErrors{}
+	if name == "" {
+		errs["name"] = "must not be empty"
+	}
+	scopes, scopeErr := uniqueScopes(input.Scopes)
+	if scopeErr != "" {
+		errs["scopes"] = scopeErr
+	}
+	if days < 1 || days > maxAccessTokenDays {
+		errs["expires_in_days"] = fmt.Sprintf("must be between 1 and %d", maxAccessTokenDays)
+	}
+	if len(errs) > 0 {
+		return nil, "", errs
+	}
+
+	var count int64
+	if err := s.db.Model(&models.PersonalAccessToken{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, "", err
+	}
+	if count >= maxAccessTokens {
+		return nil, "", ValidationErrors{"name": fmt.Sprintf("at most %d access tokens are allowed; delete one first", maxAccessTokens)}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	token := accessTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	accessToken := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashAccessToken(token),
+		Prefix:    token[:accessTokenPrefixLength],
+		Scopes:    scopes,
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+	}
+	if err := s.db.Create(&accessToken).Error; err != nil {
+		return nil, "", err
+	}
+	return &accessToken, token, nil
+}
+
+// GetTokens lists the user's tokens, newest first
+func (s *AccessTokenService) GetTokens(userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// DeleteToken revokes one of the user's tokens
+func (s *AccessTokenService) DeleteToken(userID, tokenID uuid.UUID) error {
+	result := s.db.Where("id = ? AND user_id = ?", tokenID, userID).Delete(&models.PersonalAccessToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// IsPersonalAccessToken reports whether a bearer token looks like one of
+// ours rather than a token from the identity provider
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, accessTokenPrefix)
+}
+
+// Authenticate returns the user a token belongs to along with the token,
+// recording that it was used
+func (s *AccessTokenService) Authenticate(token string) (*models.User, *models.PersonalAccessToken, error) {
+	if !strings.HasPrefix(token, accessTokenPrefix) {
+		return nil, nil, ErrInvalidAccessToken
+	}
+
+	var accessToken models.PersonalAccessToken
+	err := s.db.First(&accessToken, "token_hash = ?", hashAccessToken(token)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidAccessToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	if !now.Before(accessToken.ExpiresAt) {
+		return nil, nil, ErrInvalidAccessToken
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", accessToken.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAccessToken
+		}
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, ErrUserDeactivated
+	}
+
+	if accessToken.LastUsedAt == nil || now.Sub(*accessToken.LastUsedAt) > accessTokenTouchInterval {
+		if err := s.db.Model(&accessToken).Update("last_used_at", now).Error; err != nil {
+			log.Printf("Error recording use of access token %v: %v", accessToken.ID, err)
+		}
+	}
+	return &user, &accessToken, nil
+}
+
+// uniqueScopes checks the scopes asked for and drops repeats, returning
+// the problem if there is one
+func uniqueScopes(scopes []models.TokenScope) ([]models.TokenScope, string) {
+	if len(scopes) == 0 {
+		return nil, "must name at least one scope"
+	}
+	unique := make([]models.TokenScope, 0, len(scopes))
+	seen := make(map[models.TokenScope]bool, len(scopes))
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return nil, fmt.Sprintf("unknown scope %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return unique, ""
+}
+
+func hashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEventNotFound is returned when moderating an event that doesn't exist
+	ErrEventNotFound = errors.New("event not found")
+	// ErrOwnAccount is returned when an admin tries to demote, deactivate,
+	// delete or impersonate themselves
+	ErrOwnAccount = errors.New("admins can't do this to their own account")
+	// ErrImpersonateAdmin is returned when impersonating another admin,
+	// which would hand over their privileges without a trace of who used them
+	ErrImpersonateAdmin = errors.New("admins can't be impersonated")
+	// ErrUserDeactivated is returned when impersonating a deactivated user
+	ErrUserDeactivated = errors.New("user is deactivated")
+	// ErrNoModerationHold is returned when lifting a hold the event doesn't have
+	ErrNoModerationHold = errors.New("event is not on moderation hold")
+)
+
+// AuditInfo is who is taking an admin action, from where and why
+type AuditInfo struct {
+	ActorID   uuid.UUID
+	IPAddress string
+	Reason    string
+}
+
+// AuditLogFilter narrows the audit log; empty fields match everything
+type AuditLogFilter struct {
+	ActorID  *uuid.UUID
+	TargetID *uuid.UUID
+	Action   models.AdminAuditAction
+}
+
+// AdminService holds the privileged actions of moderators and admins.
+// Every change it makes is recorded in the audit log in the same
+// transaction, so nothing happens without a record.
+type AdminService struct {
+	db *gorm.DB
+}
+
+// NewAdminService creates a new admin service
+func NewAdminService() *AdminService {
+	return &AdminService{
+		db: database.GetDB(),
+	}
+}
+
+// UserUpdate is a partial update of a user's profile. Only the fields an
+// admin may edit are listed and a nil field is left untouched; role and
+// activation have their own actions.
+type UserUpdate struct {
+	Name    *string `json:"name"`
+	Email   *string `json:"email"` // "" removes the address
+	Picture *string `json:"picture"`
+}
+
+// Validate checks every field that was sent and returns the problems per field
+func (u *UserUpdate) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if u.Name != nil && strings.TrimSpace(*u.Name) == "" {
+		errs["name"] = "must not be empty"
+	}
+	if u.Email != nil && strings.TrimSpace(*u.Email) != "" {
+		email := strings.TrimSpace(*u.Email)
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			errs["email"] = "is not a valid email address"
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Changes returns the column updates for the fields that were sent.
+// It assumes Validate has already passed.
+func (u *UserUpdate) Changes() map[string]interface{} {
+	changes := make(map[string]interface{})
+
+	if u.Name != nil {
+		changes["name"] = strings.TrimSpace(*u.Name)
+	}
+	if u.Email != nil {
+		changes["email"] = strings.TrimSpace(*u.Email)
+	}
+	if u.Picture != nil {
+		changes["picture"] = *u.Picture
+	}
+
+	return changes
+}
+
+// UpdateUser changes a user's profile fields
+func (s *AdminService) UpdateUser(audit AuditInfo, userID uuid.UUID, update *UserUpdate) (*models.User, error) {
+	if errs := update.Validate(); errs != nil {
+		return nil, errs
+	}
+	updates := update.Changes()
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := findUser(tx, userID, &user); err != nil {
+			return err
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		fields := make([]string, 0, len(updates))
+		for field := range updates {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		return recordAudit(tx, audit, models.AdminAuditUserUpdated, models.AdminAuditTargetUser, user.ID, map[string]interface{}{
+			"fields": fields,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DeleteUser deletes a user for good
+func (s *AdminService) DeleteUser(audit AuditInfo, userID uuid.UUID) error {
+	if userID == audit.ActorID {
+		return ErrOwnAccount
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := findUser(tx, userID, &user); err != nil {
+			return err
+		}
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, audit, models.AdminAuditUserDeleted, models.AdminAuditTargetUser, user.ID, map[string]interface{}{
+			"email": user.Email,
+			"name":  user.Name,
+		})
+	})
+}
+
+// SetUserRole changes a user's app-wide role
+func (s *AdminService) SetUserRole(audit AuditInfo, userID uuid.UUID, role models.UserRole) (*models.User, error) {
+	if !role.IsValid() {
+		return nil, ValidationErrors{"role": "must be one of user, moderator, admin"}
+	}
+	if userID == audit.ActorID {
+		return nil, ErrOwnAccount
+	}
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := findUser(tx, userID, &user); err != nil {
+			return err
+		}
+		if user.Role == role {
+			return nil
+		}
+
+		from := user.Role
+		if err := tx.Model(&user).Update("role", role).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, audit, models.AdminAuditUserRoleChanged, models.AdminAuditTargetUser, user.ID, map[string]interface{}{
+			"from": from,
+			"to":   role,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetUserActive deactivates or reactivates a user
+func (s *AdminService) SetUserActive(audit AuditInfo, userID uuid.UUID, active bool) (*models.User, error) {
+	if userID == audit.ActorID {
+		return nil, ErrOwnAccount
+	}
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := findUser(tx, userID, &user); err != nil {
+			return err
+		}
+		if user.IsActive == active {
+			return nil
+		}
+
+		if err := tx.Model(&user).Update("is_active", active).Error; err != nil {
+			return err
+		}
+		action := models.AdminAuditUserDeactivated
+		if active {
+			action = models.AdminAuditUserReactivated
+		}
+		return recordAudit(tx, audit, action, models.AdminAuditTargetUser, user.ID, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UnpublishEvent takes a published event down, back to a draft its
+// organizers can't publish again until the hold is lifted
+func (s *AdminService) UnpublishEvent(audit AuditInfo, eventID uuid.UUID) (*models.Event, error) {
+	if audit.Reason == "" {
+		return nil, ValidationErrors{"reason": "is required"}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := findEvent(tx, eventID); err != nil {
+			return err
+		}
+		if err := transitionEvent(tx, eventID, audit.ActorID, EventActionUnpublish, audit.Reason); err != nil {
+			return err
+		}
+		return recordAudit(tx, audit, models.AdminAuditEventUnpublished, models.AdminAuditTargetEvent, eventID, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewEventService().GetEventByID(eventID)
+}
+
+// LiftEventHold lets an unpublished event's organizers publish it again
+func (s *AdminService) LiftEventHold(audit AuditInfo, eventID uuid.UUID) (*models.Event, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := findEvent(tx, eventID); err != nil {
+			return err
+		}
+		event, err := lockEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+		if !event.ModerationHold {
+			return ErrNoModerationHold
+		}
+
+		if err := tx.Model(event).Update("moderation_hold", false).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, audit, models.AdminAuditEventHoldLifted, models.AdminAuditTargetEvent, event.ID, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewEventService().GetEventByID(eventID)
+}
+
+// StartImpersonation checks that an admin may act as a user and records
+// that they began to. The caller keeps track of the impersonation.
+func (s *AdminService) StartImpersonation(audit AuditInfo, userID uuid.UUID) (*models.User, error) {
+	if userID == audit.ActorID {
+		return nil, ErrOwnAccount
+	}
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := findUser(tx, userID, &user); err != nil {
+			return err
+		}
+		if user.Role == models.UserRoleAdmin {
+			return ErrImpersonateAdmin
+		}
+		if !user.IsActive {
+			return ErrUserDeactivated
+		}
+		return recordAudit(tx, audit, models.AdminAuditImpersonationStarted, models.AdminAuditTargetUser, user.ID, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// EndImpersonation records that an admin stopped acting as a user
+func (s *AdminService) EndImpersonation(audit AuditInfo, userID uuid.UUID) error {
+	return recordAudit(s.db, audit, models.AdminAuditImpersonationEnded, models.AdminAuditTargetUser, userID, nil)
+}
+
+// RecordImpersonatedRequest records an API request an admin made as a user
+func (s *AdminService) RecordImpersonatedRequest(audit AuditInfo, userID uuid.UUID, method, path string) error {
+	return recordAudit(s.db, audit, models.AdminAuditImpersonationRequest, models.AdminAuditTargetUser, userID, map[string]interface{}{
+		"method": method,
+		"path":   path,
+	})
+}
+
+// GetAuditLog returns audit log entries, newest first, with pagination
+func (s *AdminService) GetAuditLog(filter AuditLogFilter, page, pageSize int) ([]models.AdminAuditLog, int64, error) {
+	query := s.db.Model(&models.AdminAuditLog{})
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.TargetID != nil {
+		query = query.Where("target_id = ?", *filter.TargetID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.AdminAuditLog
+	offset := (page - 1) * pageSize
+	err := query.Preload("Actor").
+		Order("created_at DESC").
+		Offset(offset).Limit(pageSize).
+		Find(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// recordAudit adds an entry to the audit log within tx
+func recordAudit(tx *gorm.DB, audit AuditInfo, action models.AdminAuditAction, targetType string, targetID uuid.UUID, details map[string]interface{}) error {
+	entry := models.AdminAuditLog{
+		ActorID:    audit.ActorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     audit.Reason,
+		Details:    details,
+		IPAddress:  audit.IPAddress,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("recording %s in the audit log: %w", action, err)
+	}
+	return nil
+}
+
+func findUser(tx *gorm.DB, userID uuid.UUID, user *models.User) error {
+	err := tx.First(user, "id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+func findEvent(tx *gorm.DB, eventID uuid.UUID) error {
+	var count int64
+	if err := tx.Model(&models.Event{}).Where("id = ?", eventID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrEventNotFound
+	}
+	return nil
+}
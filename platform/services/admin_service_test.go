@@ -0,0 +1,63 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestUserUpdateChangesOnlyProfileFields(t *testing.T) {
+	// Field names and JSON keys of everything else on a user are ignored
+	body := `{"name": " Ada ", "email": "ada@example.com", "picture": "https://example.com/ada.png",
+		"role": "admin", "Role": "admin", "is_active": false, "IsActive": false,
+		"auth_id": "auth0|x", "AuthID": "auth0|x", "id": "00000000-0000-0000-0000-000000000000"}`
+
+	var update UserUpdate
+	if err := json.Unmarshal([]byte(body), &update); err != nil {
+		t.Fatalf("decoding update: %v", err)
+	}
+	if errs := update.Validate(); errs != nil {
+		t.Fatalf("Validate() = %v", errs)
+	}
+
+	changes := update.Changes()
+	columns := make([]string, 0, len(changes))
+	for column := range changes {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	if want := []string{"email", "name", "picture"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("changed columns = %v, want %v", columns, want)
+	}
+	if changes["name"] != "Ada" {
+		t.Errorf("name = %q, want it trimmed", changes["name"])
+	}
+}
+
+func TestUserUpdateValidate(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name   string
+		update UserUpdate
+		field  string // empty when valid
+	}{
+		{"nothing sent", UserUpdate{}, ""},
+		{"blank name", UserUpdate{Name: str("  ")}, "name"},
+		{"invalid email", UserUpdate{Email: str("not an email")}, "email"},
+		{"email with display name", UserUpdate{Email: str("Ada <ada@example.com>")}, "email"},
+		{"removed email", UserUpdate{Email: str("")}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.update.Validate()
+			if tt.field == "" && errs != nil {
+				t.Errorf("Validate() = %v, want no errors", errs)
+			}
+			if tt.field != "" && errs[tt.field] == "" {
+				t.Errorf("Validate() = %v, want an error on %s", errs, tt.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxAnnouncementLength caps an announcement's body, in characters
+const maxAnnouncementLength = 2000
+
+// ErrAnnouncementNotFound is returned for announcements that don't exist on the event
+var ErrAnnouncementNotFound = errors.New("announcement not found")
+
+type AnnouncementService struct {
+	db   *gorm.DB
+	rsvp *RSVPService
+}
+
+// NewAnnouncementService creates a new announcement service
+func NewAnnouncementService() *AnnouncementService {
+	return &AnnouncementService{
+		db:   database.GetDB(),
+		rsvp: NewRSVPService(),
+	}
+}
+
+// AnnouncementInput is a new announcement
+type AnnouncementInput struct {
+	Body     string                      `json:"body"`
+	Audience models.AnnouncementAudience `json:"audience"` // Defaults to all
+}
+
+// AnnouncementSummary is an announcement with how its delivery is going
+type AnnouncementSummary struct {
+	models.EventAnnouncement
+	Deliveries map[models.AnnouncementDeliveryStatus]int64 `json:"deliveries"`
+}
+
+// PostAnnouncement stores an announcement on an event and queues emailing it
+// to the guests whose RSVP is in its audience. Guests of several occurrences
+// of a recurring event get one email.
+func (s *AnnouncementService) PostAnnouncement(event *models.Event, authorID uuid.UUID, input *AnnouncementInput) (*AnnouncementSummary, error) {
+	body := strings.TrimSpace(input.Body)
+	audience := input.Audience
+	if audience == "" {
+		audience = models.AnnouncementAudienceAll
+	}
+
+	errs := ValidationErrors{}
+	switch {
+	case body == "":
+		errs["body"] = "must not be empty"
+	case utf8.RuneCountInString(body) > maxAnnouncementLength:
+		errs["body"] = fmt.Sprintf("must be at most %d characters", maxAnnouncementLength)
+	}
+	if !audience.IsValid() {
+		errs["audience"] = "must be all, yes or yes_maybe"
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	rsvps, err := s.rsvp.GetEventRSVPs(event.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	recipients := announcementRecipients(rsvps, audience)
+
+	announcement := models.EventAnnouncement{
+		EventID:  event.ID,
+		AuthorID: authorID,
+		Body:     body,
+		Audience: audience,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&announcement).Error; err != nil {
+			return err
+		}
+		if len(recipients) == 0 {
+			return nil
+		}
+
+		deliveries := make([]models.AnnouncementDelivery, 0, len(recipients))
+		for _, rsvp := range recipients {
+			deliveries = append(deliveries, models.AnnouncementDelivery{
+				AnnouncementID: announcement.ID,
+				UserID:         rsvp.UserID,
+				Response:       rsvp.Response,
+				Status:         models.AnnouncementDeliveryPending,
+			})
+		}
+		if err := tx.Create(&deliveries).Error; err != nil {
+			return err
+		}
+
+		return enqueueJob(tx, JobKindSendAnnouncement, models.JobPayload{"announcement_id": announcement.ID.String()}, JobOptions{
+			EventID:   &event.ID,
+			UniqueKey: JobKindSendAnnouncement + ":" + announcement.ID.String(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetAnnouncement(event.ID, announcement.ID)
+}
+
+// GetAnnouncements lists an event's announcements, newest first
+func (s *AnnouncementService) GetAnnouncements(eventID uuid.UUID) ([]models.EventAnnouncement, error) {
+	var announcements []models.EventAnnouncement
+	err := s.db.Preload("Author").Where("event_id = ?", eventID).
+		Order("created_at DESC").Find(&announcements).Error
+	return announcements, err
+}
+
+// GetAnnouncement returns one of an event's announcements with its delivery counts
+func (s *AnnouncementService) GetAnnouncement(eventID, announcementID uuid.UUID) (*AnnouncementSummary, error) {
+	var summary AnnouncementSummary
+	err := s.db.Preload("Author").First(&summary.EventAnnouncement, "id = ? AND event_id = ?", announcementID, eventID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAnnouncementNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Status models.AnnouncementDeliveryStatus
+		Count  int64
+	}
+	err = s.db.Model(&models.AnnouncementDelivery{}).
+		Select("status, COUNT(*) AS count").
+		Where("announcement_id = ?", announcementID).
+		Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Deliveries = map[models.AnnouncementDeliveryStatus]int64{
+		models.AnnouncementDeliveryPending: 0,
+		models.AnnouncementDeliverySent:    0,
+		models.AnnouncementDeliverySkipped: 0,
+		models.AnnouncementDeliveryFailed:  0,
+	}
+	for _, row := range rows {
+		summary.Deliveries[row.Status] = row.Count
+	}
+	return &summary, nil
+}
+
+// GetDeliveries lists who an announcement went to and how it went, by name
+func (s *AnnouncementService) GetDeliveries(eventID, announcementID uuid.UUID) ([]models.AnnouncementDelivery, error) {
+	if _, err := s.GetAnnouncement(eventID, announcementID); err != nil {
+		return nil, err
+	}
+
+	var deliveries []models.AnnouncementDelivery
+	err := s.db.Joins("User").Where("announcement_id = ?", announcementID).
+		Order(`"User"."name" ASC`).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// announcementRecipients picks the RSVPs in the audience, one per guest. A
+// guest of several occurrences counts with their most committed answer.
+func announcementRecipients(rsvps []models.RSVP, audience models.AnnouncementAudience) []models.RSVP {
+	responses := audience.Responses()
+	rank := make(map[models.RSVPResponse]int, len(responses))
+	for i, response := range responses {
+		rank[response] = len(responses) - i
+	}
+
+	best := make(map[uuid.UUID]int)
+	var recipients []models.RSVP
+	for _, rsvp := range rsvps {
+		if rsvp.VoidedAt != nil || rank[rsvp.Response] == 0 {
+			continue
+		}
+		i, seen := best[rsvp.UserID]
+		if !seen {
+			best[rsvp.UserID] = len(recipients)
+			recipients = append(recipients, rsvp)
+		} else if rank[rsvp.Response] > rank[recipients[i].Response] {
+			recipients[i] = rsvp
+		}
+	}
+	return recipients
+}
+
+// sendAnnouncement is the job that emails an announcement to its pending
+// recipients, recording each delivery. Failed deliveries are tried again
+// with the job.
+func sendAnnouncement(ctx context.Context, job *models.Job) error {
+	db := database.GetDB()
+
+	announcementID, err := uuid.Parse(fmt.Sprint(job.Payload["announcement_id"]))
+	if err != nil {
+		return permanent(fmt.Errorf("job has no announcement: %w", err))
+	}
+	var announcement models.EventAnnouncement
+	if err := db.Preload("Author").First(&announcement, "id = ?", announcementID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	var event models.Event
+	if err := db.First(&event, "id = ?", announcement.EventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	var deliveries []models.AnnouncementDelivery
+	err = db.Preload("User").Where("announcement_id = ? AND status IN ?", announcement.ID,
+		[]models.AnnouncementDeliveryStatus{models.AnnouncementDeliveryPending, models.AnnouncementDeliveryFailed}).
+		Find(&deliveries).Error
+	if err != nil {
+		return err
+	}
+
+	notifier := NewNotificationService()
+	failed := 0
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		delivery := &deliveries[i]
+		updates := map[string]interface{}{"error": ""}
+		sent, err := notifier.DeliverAnnouncement(&delivery.User, &event, &announcement)
+		switch {
+		case err != nil:
+			failed++
+			updates["status"] = models.AnnouncementDeliveryFailed
+			updates["error"] = err.Error()
+		case sent:
+			updates["status"] = models.AnnouncementDeliverySent
+			updates["sent_at"] = time.Now()
+		default:
+			updates["status"] = models.AnnouncementDeliverySkipped
+		}
+		if err := db.Model(delivery).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deliveries failed", failed, len(deliveries))
+	}
+	return nil
+}
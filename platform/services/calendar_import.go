@@ -0,0 +1,199 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"01-Login/platform/ical"
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Import actions
+const (
+	ImportActionCreate   = "create"   // A new event is (or in a dry run, would be) created
+	ImportActionExisting = "existing" // The entry was imported before, nothing changes
+	ImportActionSkip     = "skip"     // The entry can't be imported, see Reason and Fields
+)
+
+// ImportOptions controls how a calendar is imported
+type ImportOptions struct {
+	DryRun bool   // Report what would happen without creating anything
+	Status string // Status of the created events, draft or published
+}
+
+// ImportResult is the outcome for one VEVENT of an imported calendar
+type ImportResult struct {
+	UID    string           `json:"uid"`
+	Title  string           `json:"title"`
+	Action string           `json:"action"`
+	Reason string           `json:"reason,omitempty"`
+	Fields ValidationErrors `json:"fields,omitempty"`
+	Event  *models.Event    `json:"event,omitempty"`
+}
+
+// ImportCalendar creates an event owned by the user for each VEVENT in the
+// calendar. Entries are matched on UID, so importing the same file again
+// only creates the entries that weren't imported before.
+func (s *CalendarService) ImportCalendar(userID uuid.UUID, calendar *ical.Calendar, opts ImportOptions) ([]ImportResult, error) {
+	if opts.Status == "" {
+		opts.Status = models.EventStatusDraft
+	}
+
+	results := make([]ImportResult, 0, len(calendar.Events))
+	seen := make(map[string]bool)
+
+	for i := range calendar.Events {
+		entry := &calendar.Events[i]
+		uid := importUID(entry)
+		result := ImportResult{UID: uid, Title: entry.Summary}
+
+		switch {
+		case !entry.RecurrenceID.IsZero():
+			result.Action = ImportActionSkip
+			result.Reason = "changed occurrences of a repeating event are not imported"
+			results = append(results, result)
+			continue
+		case entry.Status == ical.StatusCancelled:
+			result.Action = ImportActionSkip
+			result.Reason = "the event is cancelled"
+			results = append(results, result)
+			continue
+		case seen[uid]:
+			result.Action = ImportActionSkip
+			result.Reason = "the UID appears more than once in the file"
+			results = append(results, result)
+			continue
+		}
+		seen[uid] = true
+
+		existing, err := s.findImported(userID, uid)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			result.Action = ImportActionExisting
+			result.Event = existing
+			results = append(results, result)
+			continue
+		}
+
+		event := eventFromICal(entry)
+		event.UserID = userID
+		event.ImportUID = uid
+		event.Status = opts.Status // createEvent publishes through the state machine
+
+		if errs := checkImported(entry); errs != nil {
+			result.Action = ImportActionSkip
+			result.Fields = errs
+			results = append(results, result)
+			continue
+		}
+		if err := prepareNewEvent(&event); err != nil {
+			var validationErrs ValidationErrors
+			if !errors.As(err, &validationErrs) {
+				return nil, err
+			}
+			result.Action = ImportActionSkip
+			result.Fields = validationErrs
+			results = append(results, result)
+			continue
+		}
+
+		result.Action = ImportActionCreate
+		result.Event = &event
+		if !opts.DryRun {
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				return s.eventService.createEvent(tx, &event)
+			})
+			if err != nil {
+				// A concurrent import of the same file got there first
+				if existing, findErr := s.findImported(userID, uid); findErr == nil && existing != nil {
+					result.Action = ImportActionExisting
+					result.Event = existing
+					results = append(results, result)
+					continue
+				}
+				return nil, err
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// findImported returns the user's event imported under uid, or nil. Files
+// exported from this app carry event IDs as UIDs, which match the event itself.
+func (s *CalendarService) findImported(userID uuid.UUID, uid string) (*models.Event, error) {
+	query := s.db.Where("user_id = ? AND import_uid = ?", userID, uid)
+	if id, err := uuid.Parse(uid); err == nil {
+		query = s.db.Where("user_id = ? AND (import_uid = ? OR id = ?)", userID, uid, id)
+	}
+
+	var event models.Event
+	err := query.Preload("User").First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// eventFromICal maps a VEVENT onto the fields of a new event
+func eventFromICal(entry *ical.Event) models.Event {
+	event := models.Event{
+		Title:             strings.TrimSpace(entry.Summary),
+		Description:       entry.Description,
+		Venue:             entry.Location,
+		EventDate:         entry.Start,
+		TimeZone:          entry.TZID,
+		RecurrenceRule:    entry.RRule,
+		RecurrenceExDates: entry.ExDates,
+		IsPublic:          entry.Class == "" || entry.Class == ical.ClassPublic, // PRIVATE, CONFIDENTIAL and unknown classes stay private
+	}
+	if !entry.End.IsZero() {
+		end := entry.End
+		event.EndDate = &end
+	}
+	if entry.Geo != nil {
+		event.VenueLat = entry.Geo.Lat
+		event.VenueLng = entry.Geo.Lng
+	}
+	for i, ex := range event.RecurrenceExDates {
+		event.RecurrenceExDates[i] = ex.UTC()
+	}
+	return event
+}
+
+// checkImported reports the fields a VEVENT needs that are missing
+func checkImported(entry *ical.Event) ValidationErrors {
+	errs := ValidationErrors{}
+	if strings.TrimSpace(entry.Summary) == "" {
+		errs["summary"] = "is required"
+	}
+	if entry.Start.IsZero() {
+		errs["dtstart"] = "is missing or not a valid date"
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// importUID is the entry's UID, or for the rare file without UIDs one derived
+// from its title and start so re-imports still match
+func importUID(entry *ical.Event) string {
+	if uid := strings.TrimSpace(entry.UID); uid != "" {
+		return uid
+	}
+	sum := sha256.Sum256([]byte(entry.Summary + "\n" + entry.Start.UTC().Format(time.RFC3339)))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
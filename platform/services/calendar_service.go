@@ -0,0 +1,284 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"01-Login/platform/database"
+	"01-Login/platform/ical"
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// calendarProdID identifies this app as the producer of exported calendars
+const calendarProdID = "-//Events//Events Calendar//EN"
+
+type CalendarService struct {
+	db           *gorm.DB
+	eventService *EventService
+	rsvpService  *RSVPService
+}
+
+// NewCalendarService creates a new calendar service
+func NewCalendarService() *CalendarService {
+	return &CalendarService{
+		db:           database.GetDB(),
+		eventService: NewEventService(),
+		rsvpService:  NewRSVPService(),
+	}
+}
+
+// ExportEvent builds a calendar holding a single event, including any changed
+// occurrences if it repeats. baseURL is prepended to the event page link.
+func (s *CalendarService) ExportEvent(event *models.Event, baseURL string) (*ical.Calendar, error) {
+	events, err := s.eventToICal(event, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &ical.Calendar{
+		ProdID: calendarProdID,
+		Method: "PUBLISH",
+		Events: events,
+	}, nil
+}
+
+// CreateFeed creates the user's calendar feed, replacing any existing one so
+// its old URL stops working, and returns the new feed token
+func (s *CalendarService) CreateFeed(userID uuid.UUID) (*models.CalendarFeed, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	feed := models.CalendarFeed{
+		UserID:    userID,
+		TokenHash: hashFeedToken(token),
+	}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"token_hash": feed.TokenHash, "created_at": time.Now(), "last_accessed_at": nil}),
+	}).Create(&feed).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.db.First(&feed, "user_id = ?", userID).Error; err != nil {
+		return nil, "", err
+	}
+	return &feed, token, nil
+}
+
+// GetFeed retrieves the user's calendar feed
+func (s *CalendarService) GetFeed(userID uuid.UUID) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	if err := s.db.First(&feed, "user_id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// DeleteFeed turns off the user's calendar feed
+func (s *CalendarService) DeleteFeed(userID uuid.UUID) error {
+	result := s.db.Where("user_id = ?", userID).Delete(&models.CalendarFeed{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("calendar feed not found")
+	}
+	return nil
+}
+
+// GetFeedCalendar builds the calendar served at a feed URL: the events the
+// user organizes plus the events they answered yes or maybe to
+func (s *CalendarService) GetFeedCalendar(token, baseURL string) (*ical.Calendar, error) {
+	// Feeds of deactivated users stop working along with their account
+	var feed models.CalendarFeed
+	if err := s.db.First(&feed, "token_hash = ? AND user_id IN (SELECT id FROM users WHERE is_active)", hashFeedToken(token)).Error; err != nil {
+		return nil, errors.New("calendar feed not found")
+	}
+
+	now := time.Now()
+	s.db.Model(&feed).UpdateColumn("last_accessed_at", now)
+
+	var organized []models.Event
+	if err := s.db.Preload("User").Scopes(hostedBy(feed.UserID)).Order("event_date ASC").Find(&organized).Error; err != nil {
+		return nil, err
+	}
+
+	var events []ical.Event
+	hosted := make(map[uuid.UUID]bool, len(organized))
+	for i := range organized {
+		hosted[organized[i].ID] = true
+		exported, err := s.eventToICal(&organized[i], baseURL)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, exported...)
+	}
+
+	rsvps, err := s.rsvpService.GetUserRSVPs(feed.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rsvp := range rsvps {
+		if rsvp.Response != models.RSVPResponseYes && rsvp.Response != models.RSVPResponseMaybe {
+			continue
+		}
+		event := rsvp.Event
+		if event.Status == models.EventStatusDraft || hosted[event.ID] {
+			continue
+		}
+
+		if rsvp.OccurrenceDate == nil {
+			exported, err := s.eventToICal(&event, baseURL)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, exported...)
+			continue
+		}
+
+		// Guests answer per occurrence, so only the occurrences they are going to are in their feed
+		occurrence, err := s.eventService.GetOccurrence(&event, *rsvp.OccurrenceDate)
+		if err != nil {
+			continue
+		}
+		exported := occurrenceToICal(occurrence, baseURL)
+		if rsvp.VoidedAt != nil {
+			exported.Status = ical.StatusCancelled
+		}
+		events = append(events, exported)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+
+	var user models.User
+	name := "Events"
+	if err := s.db.First(&user, "id = ?", feed.UserID).Error; err == nil && user.Name != "" {
+		name = "Events - " + user.Name
+	}
+
+	return &ical.Calendar{
+		ProdID: calendarProdID,
+		Name:   name,
+		Method: "PUBLISH",
+		Events: events,
+	}, nil
+}
+
+// eventToICal converts an event to VEVENTs: the event itself, followed by one
+// VEVENT per changed occurrence if it repeats
+func (s *CalendarService) eventToICal(event *models.Event, baseURL string) ([]ical.Event, error) {
+	master := baseICal(event, baseURL)
+	master.UID = event.ID.String()
+	if !event.IsRecurring() {
+		return []ical.Event{master}, nil
+	}
+
+	// Repeating events keep their wall-clock time across DST changes, so
+	// the series is written on the event's own zone
+	if event.TimeZone != "" && event.TimeZone != "UTC" {
+		master.TZID = event.TimeZone
+	}
+	master.RRule = event.RecurrenceRule
+	master.ExDates = event.RecurrenceExDates
+	events := []ical.Event{master}
+
+	overrides, err := s.eventService.getOccurrenceOverrides(event.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, override := range overrides {
+		occurrence := *event
+		occurrence.EventDate = override.OccurrenceDate
+		if event.EndDate != nil {
+			end := override.OccurrenceDate.Add(event.EndDate.Sub(event.EventDate))
+			occurrence.EndDate = &end
+		}
+		applyOverride(&occurrence, &override)
+
+		changed := baseICal(&occurrence, baseURL)
+		changed.UID = master.UID
+		changed.TZID = master.TZID
+		changed.RecurrenceID = override.OccurrenceDate
+		changed.LastModified = override.UpdatedAt
+		events = append(events, changed)
+	}
+	sort.SliceStable(events[1:], func(i, j int) bool {
+		return events[1+i].RecurrenceID.Before(events[1+j].RecurrenceID)
+	})
+	return events, nil
+}
+
+// occurrenceToICal converts a single expanded occurrence to a standalone VEVENT
+func occurrenceToICal(occurrence *models.Event, baseURL string) ical.Event {
+	exported := baseICal(occurrence, baseURL)
+	exported.UID = occurrence.ID.String() + "-" + occurrence.OccurrenceDate.UTC().Format("20060102T150405Z")
+	exported.URL += "?occurrence_date=" + occurrence.OccurrenceDate.UTC().Format(time.RFC3339)
+	return exported
+}
+
+// baseICal maps the fields every exported VEVENT shares
+func baseICal(event *models.Event, baseURL string) ical.Event {
+	exported := ical.Event{
+		Summary:      event.Title,
+		Description:  event.Description,
+		Location:     eventLocation(event),
+		URL:          strings.TrimRight(baseURL, "/") + "/events/" + event.ID.String(),
+		Start:        event.EventDate,
+		Created:      event.CreatedAt,
+		LastModified: event.UpdatedAt,
+	}
+	if event.EndDate != nil {
+		exported.End = *event.EndDate
+	}
+	if event.VenueLat != 0 || event.VenueLng != 0 {
+		exported.Geo = &ical.Geo{Lat: event.VenueLat, Lng: event.VenueLng}
+	}
+	if event.User.Email != "" {
+		exported.Organizer = &ical.Organizer{Name: event.User.Name, Email: event.User.Email}
+	}
+	if !event.IsPublic {
+		exported.Class = ical.ClassPrivate
+	}
+
+	switch event.Status {
+	case models.EventStatusCancelled:
+		exported.Status = ical.StatusCancelled
+	case models.EventStatusDraft:
+		exported.Status = ical.StatusTentative
+	default:
+		exported.Status = ical.StatusConfirmed
+	}
+	return exported
+}
+
+// eventLocation joins the venue's name and address, skipping whichever is missing
+func eventLocation(event *models.Event) string {
+	switch {
+	case event.VenueName == "" || strings.Contains(event.Venue, event.VenueName):
+		return event.Venue
+	case event.Venue == "":
+		return event.VenueName
+	default:
+		return event.VenueName + ", " + event.Venue
+	}
+}
+
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
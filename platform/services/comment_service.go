@@ -0,0 +1,365 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxCommentLength caps a comment's body, in characters
+	maxCommentLength = 4000
+	// maxCommentMentions caps the attendees one comment can mention
+	maxCommentMentions = 20
+)
+
+// commentRateLimits caps how many comments one user can post, across all
+// events, within each window
+var commentRateLimits = []struct {
+	window time.Duration
+	max    int
+}{
+	{time.Minute, 5},
+	{time.Hour, 60},
+}
+
+var (
+	// ErrCommentNotFound is returned for comments that don't exist on the event
+	ErrCommentNotFound = errors.New("comment not found")
+	// ErrCommentForbidden is returned when changing someone else's comment
+	ErrCommentForbidden = errors.New("only the author can change this comment")
+)
+
+// CommentRateLimitError is returned when a user posts comments too quickly
+type CommentRateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *CommentRateLimitError) Error() string {
+	return fmt.Sprintf("too many comments, try again in %d seconds", int(e.RetryAfter.Seconds())+1)
+}
+
+type CommentService struct {
+	db            *gorm.DB
+	notifications *NotificationService
+}
+
+// NewCommentService creates a new comment service
+func NewCommentService() *CommentService {
+	return &CommentService{
+		db:            database.GetDB(),
+		notifications: NewNotificationService(),
+	}
+}
+
+// CommentInput is a new comment or an edit of one
+type CommentInput struct {
+	Body     string      `json:"body"`
+	ParentID *uuid.UUID  `json:"parent_id"` // Only for new comments; replies to a reply join its thread
+	Mentions []uuid.UUID `json:"mentions"`  // Attendees mentioned in the body
+}
+
+// GetComments pages through an event's threads: the pinned one first, then
+// the oldest first, each with its replies oldest first
+func (s *CommentService) GetComments(eventID uuid.UUID, page, pageSize int) ([]models.EventComment, int64, error) {
+	var comments []models.EventComment
+	var total int64
+
+	query := s.db.Model(&models.EventComment{}).Where("event_id = ? AND parent_id IS NULL", eventID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("Author").
+		Preload("Replies", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Replies.Author").
+		Order("pinned_at DESC NULLS LAST, created_at ASC").
+		Offset(offset).Limit(pageSize).Find(&comments).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return comments, total, nil
+}
+
+// CreateComment posts a comment, or a reply when a parent is given, and
+// emails the attendees it mentions
+func (s *CommentService) CreateComment(event *models.Event, userID uuid.UUID, input *CommentInput) (*models.EventComment, error) {
+	body := strings.TrimSpace(input.Body)
+	if errs := validateCommentBody(body); errs != nil {
+		return nil, errs
+	}
+	comment := models.EventComment{
+		EventID: event.ID,
+		UserID:  userID,
+		Body:    body,
+	}
+
+	if input.ParentID != nil {
+		parent, err := s.getComment(s.db, event.ID, *input.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.RemovedAt != nil {
+			return nil, ValidationErrors{"parent_id": "cannot reply to a deleted comment"}
+		}
+		// Threads are one level deep
+		threadID := parent.ID
+		if parent.ParentID != nil {
+			threadID = *parent.ParentID
+		}
+		comment.ParentID = &threadID
+	}
+
+	mentions, err := s.validateMentions(event.ID, userID, input.Mentions)
+	if err != nil {
+		return nil, err
+	}
+	comment.MentionIDs = mentions
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkCommentRateLimit(tx, userID); err != nil {
+			return err
+		}
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		return s.notifications.NotifyMentions(tx, &comment, mentions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getComment(s.db.Preload("Author"), event.ID, comment.ID)
+}
+
+// UpdateComment changes the body and mentions of the user's own comment.
+// Only attendees who weren't mentioned before are emailed.
+func (s *CommentService) UpdateComment(event *models.Event, userID, commentID uuid.UUID, input *CommentInput) (*models.EventComment, error) {
+	body := strings.TrimSpace(input.Body)
+	if errs := validateCommentBody(body); errs != nil {
+		return nil, errs
+	}
+
+	comment, err := s.getComment(s.db, event.ID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != userID {
+		return nil, ErrCommentForbidden
+	}
+	if comment.RemovedAt != nil {
+		return nil, ErrCommentNotFound
+	}
+
+	mentions, err := s.validateMentions(event.ID, userID, input.Mentions)
+	if err != nil {
+		return nil, err
+	}
+	previous := make(map[uuid.UUID]bool, len(comment.MentionIDs))
+	for _, id := range comment.MentionIDs {
+		previous[id] = true
+	}
+	var added []uuid.UUID
+	for _, id := range mentions {
+		if !previous[id] {
+			added = append(added, id)
+		}
+	}
+
+	encoded, err := json.Marshal(mentions)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(comment).Updates(map[string]interface{}{
+			"body":        body,
+			"mention_ids": string(encoded),
+			"edited_at":   time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return s.notifications.NotifyMentions(tx, comment, added)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getComment(s.db.Preload("Author"), event.ID, commentID)
+}
+
+// DeleteComment deletes a comment of the user's own, or anyone's when they
+// moderate the event. Comments with replies are kept, with their body
+// cleared, so the thread still reads; they disappear with their last reply.
+func (s *CommentService) DeleteComment(eventID, userID, commentID uuid.UUID, canModerate bool) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		comment, err := s.getComment(tx.Clauses(clause.Locking{Strength: "UPDATE"}), eventID, commentID)
+		if err != nil {
+			return err
+		}
+		if comment.UserID != userID && !canModerate {
+			return ErrCommentForbidden
+		}
+		if comment.RemovedAt != nil {
+			return ErrCommentNotFound
+		}
+
+		var replies int64
+		if err := tx.Model(&models.EventComment{}).Where("parent_id = ?", comment.ID).Count(&replies).Error; err != nil {
+			return err
+		}
+		if replies > 0 {
+			return tx.Model(comment).Updates(map[string]interface{}{
+				"body":        "",
+				"mention_ids": "[]",
+				"pinned_at":   nil,
+				"removed_at":  time.Now(),
+			}).Error
+		}
+
+		if err := tx.Delete(comment).Error; err != nil {
+			return err
+		}
+		if comment.ParentID == nil {
+			return nil
+		}
+		// A deleted thread goes away once it has no replies left
+		return tx.Where("id = ? AND removed_at IS NOT NULL AND NOT EXISTS (SELECT 1 FROM event_comments WHERE parent_id = ?)",
+			*comment.ParentID, *comment.ParentID).Delete(&models.EventComment{}).Error
+	})
+}
+
+// PinComment pins a top-level comment to the top of the event's discussion,
+// unpinning the one pinned before
+func (s *CommentService) PinComment(eventID, commentID uuid.UUID) (*models.EventComment, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		comment, err := s.getComment(tx, eventID, commentID)
+		if err != nil {
+			return err
+		}
+		if comment.RemovedAt != nil {
+			return ErrCommentNotFound
+		}
+		if comment.ParentID != nil {
+			return ValidationErrors{"comment": "only top-level comments can be pinned"}
+		}
+
+		err = tx.Model(&models.EventComment{}).
+			Where("event_id = ? AND pinned_at IS NOT NULL", eventID).
+			Update("pinned_at", nil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(comment).Update("pinned_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getComment(s.db.Preload("Author"), eventID, commentID)
+}
+
+// UnpinComment unpins a comment
+func (s *CommentService) UnpinComment(eventID, commentID uuid.UUID) (*models.EventComment, error) {
+	comment, err := s.getComment(s.db, eventID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(comment).Update("pinned_at", nil).Error; err != nil {
+		return nil, err
+	}
+	return s.getComment(s.db.Preload("Author"), eventID, commentID)
+}
+
+func (s *CommentService) getComment(tx *gorm.DB, eventID, commentID uuid.UUID) (*models.EventComment, error) {
+	var comment models.EventComment
+	if err := tx.First(&comment, "id = ? AND event_id = ?", commentID, eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// checkCommentRateLimit fails if posting now would put the user over any of
+// commentRateLimits, saying how long until it wouldn't. It takes a lock on
+// the user until the transaction ends, so concurrent posts are counted one
+// after the other and can't all pass the check before any is inserted.
+func checkCommentRateLimit(tx *gorm.DB, userID uuid.UUID) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "comments:"+userID.String()).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, limit := range commentRateLimits {
+		// The oldest of the last max comments decides when another one fits
+		var oldest []time.Time
+		err := tx.Model(&models.EventComment{}).
+			Where("user_id = ? AND created_at > ?", userID, now.Add(-limit.window)).
+			Order("created_at DESC").Offset(limit.max-1).Limit(1).
+			Pluck("created_at", &oldest).Error
+		if err != nil {
+			return err
+		}
+		if len(oldest) > 0 {
+			return &CommentRateLimitError{RetryAfter: oldest[0].Add(limit.window).Sub(now)}
+		}
+	}
+	return nil
+}
+
+// validateMentions checks that mentioned users take part in the event, as
+// members or guests who haven't declined, and drops repeats and the author
+func (s *CommentService) validateMentions(eventID, authorID uuid.UUID, mentions []uuid.UUID) ([]uuid.UUID, error) {
+	unique := make([]uuid.UUID, 0, len(mentions))
+	seen := map[uuid.UUID]bool{authorID: true}
+	for _, id := range mentions {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return unique, nil
+	}
+	if len(unique) > maxCommentMentions {
+		return nil, ValidationErrors{"mentions": fmt.Sprintf("at most %d people can be mentioned", maxCommentMentions)}
+	}
+
+	var attendees []uuid.UUID
+	err := s.db.Model(&models.User{}).
+		Where("id IN ?", unique).
+		Where("(id IN (SELECT user_id FROM event_members WHERE event_id = ?) OR id IN (SELECT user_id FROM rsvps WHERE event_id = ? AND response <> ? AND voided_at IS NULL))",
+			eventID, eventID, models.RSVPResponseNo).
+		Pluck("id", &attendees).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(attendees) != len(unique) {
+		return nil, ValidationErrors{"mentions": "can only mention people taking part in this event"}
+	}
+	return unique, nil
+}
+
+func validateCommentBody(body string) ValidationErrors {
+	switch {
+	case body == "":
+		return ValidationErrors{"body": "must not be empty"}
+	case utf8.RuneCountInString(body) > maxCommentLength:
+		return ValidationErrors{"body": fmt.Sprintf("must be at most %d characters", maxCommentLength)}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestCommentRateLimitCountsUnderLock(t *testing.T) {
+	userID := uuid.New()
+	var fake *fakeDB
+	var lockedFirst bool
+	db, fake := newFakeDB(t, func(query string, args []driver.Value) fakeRows {
+		if strings.Contains(query, `"event_comments"`) {
+			fake.mu.Lock()
+			lockedFirst = len(fake.execs) > 0 && strings.Contains(fake.execs[0].SQL, "pg_advisory_xact_lock")
+			fake.mu.Unlock()
+		}
+		return fakeRows{}
+	})
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return checkCommentRateLimit(tx, userID)
+	})
+	if err != nil {
+		t.Fatalf("checkCommentRateLimit() = %v", err)
+	}
+	if !lockedFirst {
+		t.Error("comments were counted before the user was locked")
+	}
+	if !hasArg(fake.execs[0].Args, "comments:"+userID.String()) {
+		t.Errorf("lock taken on %v, want the user's ID", fake.execs[0].Args)
+	}
+}
+
+func TestCommentRateLimitExceeded(t *testing.T) {
+	// The fifth most recent comment of the last minute was posted 20 seconds ago
+	oldest := time.Now().Add(-20 * time.Second)
+	db, _ := newFakeDB(t, func(query string, args []driver.Value) fakeRows {
+		return fakeRows{Columns: []string{"created_at"}, Values: [][]driver.Value{{oldest}}}
+	})
+
+	err := checkCommentRateLimit(db, uuid.New())
+	var limited *CommentRateLimitError
+	if !errors.As(err, &limited) {
+		t.Fatalf("checkCommentRateLimit() = %v, want a CommentRateLimitError", err)
+	}
+	if limited.RetryAfter <= 0 || limited.RetryAfter > 40*time.Second {
+		t.Errorf("RetryAfter = %v, want about 40s", limited.RetryAfter)
+	}
+}
@@ -0,0 +1,457 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"01-Login/platform/authorization"
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+	"01-Login/platform/realtime"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A poll offers between two and maxDatePollOptions candidate dates
+const maxDatePollOptions = 20
+
+var (
+	// ErrDatePollNotFound is returned for events without a date poll
+	ErrDatePollNotFound = errors.New("date poll not found")
+	// ErrDatePollExists is returned when creating a second poll for an event
+	ErrDatePollExists = errors.New("event already has a date poll")
+	// ErrDatePollClosed is returned when voting on or finalizing a finalized poll
+	ErrDatePollClosed = errors.New("date poll has been finalized")
+	// ErrDatePollNotDraft is returned when polling on an event that is no longer a draft
+	ErrDatePollNotDraft = errors.New("date polls are only open while the event is a draft")
+)
+
+type DatePollService struct {
+	db            *gorm.DB
+	events        *EventService
+	notifications *NotificationService
+}
+
+// NewDatePollService creates a new date poll service
+func NewDatePollService() *DatePollService {
+	return &DatePollService{
+		db:            database.GetDB(),
+		events:        NewEventService(),
+		notifications: NewNotificationService(),
+	}
+}
+
+// DatePollOptionInput is a candidate date for a new poll
+type DatePollOptionInput struct {
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// DatePollResults is a poll with its votes tallied per option
+type DatePollResults struct {
+	models.DatePoll
+	Options       []DatePollOptionResult              `json:"options"`
+	BestOptionIDs []uuid.UUID                         `json:"best_option_ids"` // Most people available, then most yes answers
+	Voters        int                                 `json:"voters"`
+	MyVotes       map[uuid.UUID]models.DatePollAnswer `json:"my_votes,omitempty"`
+}
+
+// DatePollOptionResult is one candidate date with its tally and, for those
+// who may see the guest list, who voted what
+type DatePollOptionResult struct {
+	models.DatePollOption
+	Yes      int                   `json:"yes"`
+	IfNeedBe int                   `json:"if_need_be"`
+	No       int                   `json:"no"`
+	Votes    []models.DatePollVote `json:"votes,omitempty"`
+}
+
+// available counts the voters who can make it to the option
+func (r *DatePollOptionResult) available() int {
+	return r.Yes + r.IfNeedBe
+}
+
+// CreatePoll attaches a poll with the given candidate dates to a draft event
+func (s *DatePollService) CreatePoll(event *models.Event, userID uuid.UUID, options []DatePollOptionInput) (*DatePollResults, error) {
+	if errs := validateDatePollOptions(options); errs != nil {
+		return nil, errs
+	}
+
+	poll := models.DatePoll{
+		EventID:     event.ID,
+		CreatedByID: userID,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockEvent(tx, event.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != models.EventStatusDraft {
+			return ErrDatePollNotDraft
+		}
+
+		var existing int64
+		if err := tx.Model(&models.DatePoll{}).Where("event_id = ?", event.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrDatePollExists
+		}
+
+		if err := tx.Create(&poll).Error; err != nil {
+			return err
+		}
+		rows := make([]models.DatePollOption, 0, len(options))
+		for _, option := range options {
+			row := models.DatePollOption{PollID: poll.ID, StartsAt: option.StartsAt.UTC()}
+			if option.EndsAt != nil {
+				end := option.EndsAt.UTC()
+				row.EndsAt = &end
+			}
+			rows = append(rows, row)
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetPoll(event.ID, &userID)
+}
+
+// GetPoll returns an event's poll with the votes tallied. viewerID, when
+// set, fills in the viewer's own answers, and lists each option's voters
+// if the viewer's role lets them see the guest list.
+func (s *DatePollService) GetPoll(eventID uuid.UUID, viewerID *uuid.UUID) (*DatePollResults, error) {
+	var results DatePollResults
+	err := s.db.First(&results.DatePoll, "event_id = ?", eventID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDatePollNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	showVoters := false
+	if viewerID != nil {
+		role, err := getEventRole(s.db, eventID, *viewerID)
+		if err != nil {
+			return nil, err
+		}
+		showVoters = authorization.EventRoleAllows(role, authorization.EventPermissionViewGuests)
+	}
+
+	var options []models.DatePollOption
+	if err := s.db.Where("poll_id = ?", results.ID).Order("starts_at ASC").Find(&options).Error; err != nil {
+		return nil, err
+	}
+	var votes []models.DatePollVote
+	query := s.db.Where("poll_id = ?", results.ID).Order("created_at ASC")
+	if showVoters {
+		query = query.Preload("User")
+	}
+	if err := query.Find(&votes).Error; err != nil {
+		return nil, err
+	}
+
+	index := make(map[uuid.UUID]int, len(options))
+	results.Options = make([]DatePollOptionResult, len(options))
+	for i, option := range options {
+		index[option.ID] = i
+		results.Options[i] = DatePollOptionResult{DatePollOption: option}
+		if showVoters {
+			results.Options[i].Votes = []models.DatePollVote{}
+		}
+	}
+
+	voters := make(map[uuid.UUID]bool)
+	for _, vote := range votes {
+		i, ok := index[vote.OptionID]
+		if !ok {
+			continue
+		}
+		option := &results.Options[i]
+		switch vote.Answer {
+		case models.DatePollAnswerYes:
+			option.Yes++
+		case models.DatePollAnswerIfNeedBe:
+			option.IfNeedBe++
+		case models.DatePollAnswerNo:
+			option.No++
+		}
+		if showVoters {
+			option.Votes = append(option.Votes, vote)
+		}
+		voters[vote.UserID] = true
+
+		if viewerID != nil && vote.UserID == *viewerID {
+			if results.MyVotes == nil {
+				results.MyVotes = make(map[uuid.UUID]models.DatePollAnswer)
+			}
+			results.MyVotes[vote.OptionID] = vote.Answer
+		}
+	}
+	results.Voters = len(voters)
+	results.BestOptionIDs = bestDatePollOptions(results.Options)
+	return &results, nil
+}
+
+// Vote records the user's answers, keyed by option ID. Options left out
+// keep the user's earlier answer, if any.
+func (s *DatePollService) Vote(eventID, userID uuid.UUID, answers map[uuid.UUID]models.DatePollAnswer) (*DatePollResults, error) {
+	if len(answers) == 0 {
+		return nil, ValidationErrors{"votes": "must answer at least one option"}
+	}
+	for _, answer := range answers {
+		if !answer.IsValid() {
+			return nil, ValidationErrors{"votes": "answers must be yes, if_need_be or no"}
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		poll, err := lockDatePoll(tx, eventID)
+		if err != nil {
+			return err
+		}
+		if err := checkDatePollOpen(tx, poll); err != nil {
+			return err
+		}
+
+		optionIDs := make([]uuid.UUID, 0, len(answers))
+		for id := range answers {
+			optionIDs = append(optionIDs, id)
+		}
+		var known int64
+		err = tx.Model(&models.DatePollOption{}).Where("poll_id = ? AND id IN ?", poll.ID, optionIDs).Count(&known).Error
+		if err != nil {
+			return err
+		}
+		if int(known) != len(optionIDs) {
+			return ValidationErrors{"votes": "can only answer the poll's options"}
+		}
+
+		votes := make([]models.DatePollVote, 0, len(answers))
+		for optionID, answer := range answers {
+			votes = append(votes, models.DatePollVote{
+				PollID:   poll.ID,
+				OptionID: optionID,
+				UserID:   userID,
+				Answer:   answer,
+			})
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "option_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"answer", "updated_at"}),
+		}).Create(&votes).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetPoll(eventID, &userID)
+}
+
+// FinalizePoll picks an option as the event's date and publishes the event
+// in one go, then tells everyone who voted. An option without an end keeps
+// the event's duration.
+func (s *DatePollService) FinalizePoll(eventID, actorID, optionID uuid.UUID) (*models.Event, *DatePollResults, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		poll, err := lockDatePoll(tx, eventID)
+		if err != nil {
+			return err
+		}
+		if err := checkDatePollOpen(tx, poll); err != nil {
+			return err
+		}
+
+		var option models.DatePollOption
+		err = tx.First(&option, "id = ? AND poll_id = ?", optionID, poll.ID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ValidationErrors{"option_id": "must be one of the poll's options"}
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.scheduleEvent(tx, eventID, &option); err != nil {
+			return err
+		}
+		if err := transitionEvent(tx, eventID, actorID, EventActionPublish, "Date chosen by poll"); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err = tx.Model(poll).Updates(map[string]interface{}{
+			"finalized_option_id": option.ID,
+			"finalized_at":        now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		var voterIDs []uuid.UUID
+		err = tx.Model(&models.DatePollVote{}).Distinct("user_id").
+			Where("poll_id = ?", poll.ID).Pluck("user_id", &voterIDs).Error
+		if err != nil {
+			return err
+		}
+		return s.notifications.NotifyDatePollFinalized(tx, eventID, voterIDs)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	event, err := s.events.GetEventByID(eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	results, err := s.GetPoll(eventID, &actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return event, results, nil
+}
+
+// DeletePoll removes an event's poll with its options and votes
+func (s *DatePollService) DeletePoll(eventID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		poll, err := lockDatePoll(tx, eventID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("poll_id = ?", poll.ID).Delete(&models.DatePollVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("poll_id = ?", poll.ID).Delete(&models.DatePollOption{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(poll).Error
+	})
+}
+
+// scheduleEvent moves an event to a poll option's date, with the same
+// follow-ups as editing the date by hand
+func (s *DatePollService) scheduleEvent(tx *gorm.DB, eventID uuid.UUID, option *models.DatePollOption) error {
+	event, err := lockEvent(tx, eventID)
+	if err != nil {
+		return err
+	}
+
+	scheduled := *event
+	scheduled.EventDate = option.StartsAt
+	switch {
+	case option.EndsAt != nil:
+		scheduled.EndDate = option.EndsAt
+	case event.EndDate != nil:
+		end := option.StartsAt.Add(event.EndDate.Sub(event.EventDate))
+		scheduled.EndDate = &end
+	}
+	if errs := validateSchedule(&scheduled); errs != nil {
+		return errs
+	}
+
+	changes := map[string]interface{}{}
+	if !scheduled.EventDate.Equal(event.EventDate) {
+		changes["event_date"] = scheduled.EventDate.UTC()
+	}
+	if !sameTime(scheduled.EndDate, event.EndDate) {
+		changes["end_date"] = scheduled.EndDate.UTC()
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if err := tx.Model(event).Updates(changes).Error; err != nil {
+		return err
+	}
+	if err := queueWebhooks(tx, eventID, models.WebhookEventUpdated, webhookEventUpdate(tx, eventID, changes)); err != nil {
+		return err
+	}
+	if err := realtime.Notify(tx, eventID, realtime.KindEvent); err != nil {
+		return err
+	}
+	return s.events.rescheduleReminders(tx, &scheduled)
+}
+
+// lockDatePoll loads an event's poll, locking it against concurrent votes
+func lockDatePoll(tx *gorm.DB, eventID uuid.UUID) (*models.DatePoll, error) {
+	var poll models.DatePoll
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&poll, "event_id = ?", eventID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDatePollNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// checkDatePollOpen fails once the poll is finalized or its event has left draft
+func checkDatePollOpen(tx *gorm.DB, poll *models.DatePoll) error {
+	if poll.FinalizedAt != nil {
+		return ErrDatePollClosed
+	}
+	var status string
+	if err := tx.Model(&models.Event{}).Where("id = ?", poll.EventID).Pluck("status", &status).Error; err != nil {
+		return err
+	}
+	if status != models.EventStatusDraft {
+		return ErrDatePollNotDraft
+	}
+	return nil
+}
+
+// bestDatePollOptions returns the options the most voters can make, breaking
+// ties by who would rather not have to. Nothing is best before anyone voted.
+func bestDatePollOptions(options []DatePollOptionResult) []uuid.UUID {
+	ranked := make([]*DatePollOptionResult, 0, len(options))
+	for i := range options {
+		if options[i].available() > 0 {
+			ranked = append(ranked, &options[i])
+		}
+	}
+	if len(ranked) == 0 {
+		return []uuid.UUID{}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].available() != ranked[j].available() {
+			return ranked[i].available() > ranked[j].available()
+		}
+		return ranked[i].Yes > ranked[j].Yes
+	})
+
+	best := []uuid.UUID{ranked[0].ID}
+	for _, option := range ranked[1:] {
+		if option.available() != ranked[0].available() || option.Yes != ranked[0].Yes {
+			break
+		}
+		best = append(best, option.ID)
+	}
+	return best
+}
+
+func validateDatePollOptions(options []DatePollOptionInput) ValidationErrors {
+	if len(options) < 2 || len(options) > maxDatePollOptions {
+		return ValidationErrors{"options": fmt.Sprintf("must offer between 2 and %d dates", maxDatePollOptions)}
+	}
+
+	seen := make(map[time.Time]bool, len(options))
+	for i, option := range options {
+		field := fmt.Sprintf("options[%d]", i)
+		switch {
+		case option.StartsAt.IsZero():
+			return ValidationErrors{field: "starts_at is required"}
+		case option.EndsAt != nil && !option.EndsAt.After(option.StartsAt):
+			return ValidationErrors{field: "ends_at must be after starts_at"}
+		case seen[option.StartsAt.UTC()]:
+			return ValidationErrors{field: "starts_at is offered twice"}
+		}
+		seen[option.StartsAt.UTC()] = true
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"01-Login/platform/models"
+	"01-Login/platform/realtime"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Event lifecycle actions
+const (
+	EventActionPublish = "publish"
+	EventActionCancel  = "cancel"
+	EventActionReopen  = "reopen"
+	// EventActionUnpublish is a moderator taking an event down; see AdminService.UnpublishEvent
+	EventActionUnpublish = "unpublish"
+)
+
+// ErrIllegalTransition is returned when an action is not allowed from the event's current status
+var ErrIllegalTransition = errors.New("illegal status transition")
+
+// eventTransition describes which statuses an action may start from, where it
+// leads and what else has to happen in the same transaction.
+type eventTransition struct {
+	from       []string
+	to         string
+	sideEffect func(tx *gorm.DB, event *models.Event) error
+}
+
+var eventTransitions = map[string]eventTransition{
+	EventActionPublish: {
+		from: []string{models.EventStatusDraft},
+		to:   models.EventStatusPublished,
+	},
+	EventActionCancel: {
+		from:       []string{models.EventStatusDraft, models.EventStatusPublished},
+		to:         models.EventStatusCancelled,
+		sideEffect: voidEventRSVPs,
+	},
+	EventActionReopen: {
+		from:       []string{models.EventStatusCancelled},
+		to:         models.EventStatusPublished,
+		sideEffect: restoreEventRSVPs,
+	},
+	EventActionUnpublish: {
+		from:       []string{models.EventStatusPublished},
+		to:         models.EventStatusDraft,
+		sideEffect: holdEvent,
+	},
+}
+
+// TransitionEvent applies a lifecycle action to an event, records it in the
+// status history and runs the action's side effects atomically.
+func (s *EventService) TransitionEvent(eventID, actorID uuid.UUID, action, reason string) (*models.Event, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := transitionEvent(tx, eventID, actorID, action, reason); err != nil {
+			return err
+		}
+		if action == EventActionCancel {
+			return s.notifications.NotifyEventCancelled(tx, eventID, reason)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetEventByID(eventID)
+}
+
+// transitionEvent applies a lifecycle action within tx, so callers can
+// combine it with other changes to the event
+func transitionEvent(tx *gorm.DB, eventID, actorID uuid.UUID, action, reason string) error {
+	transition, ok := eventTransitions[action]
+	if !ok {
+		return fmt.Errorf("unknown event action %q", action)
+	}
+
+	event, err := lockEvent(tx, eventID)
+	if err != nil {
+		return err
+	}
+
+	if !canTransition(transition, event.Status) {
+		return fmt.Errorf("%w: cannot %s an event that is %s", ErrIllegalTransition, action, event.Status)
+	}
+	if transition.to == models.EventStatusPublished && event.ModerationHold {
+		return fmt.Errorf("%w: the event was unpublished by a moderator", ErrIllegalTransition)
+	}
+
+	history := models.EventStatusTransition{
+		EventID:    event.ID,
+		FromStatus: event.Status,
+		ToStatus:   transition.to,
+		Reason:     reason,
+		ActorID:    actorID,
+	}
+
+	if err := tx.Model(event).Update("status", transition.to).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+
+	if transition.sideEffect != nil {
+		if err := transition.sideEffect(tx, event); err != nil {
+			return err
+		}
+	}
+
+	if err := realtime.Notify(tx, event.ID, realtime.KindStatus); err != nil {
+		return err
+	}
+	return queueWebhooks(tx, event.ID, transitionWebhook(action), webhookTransition(tx, event.ID, history))
+}
+
+// GetStatusHistory returns an event's status transitions, oldest first
+func (s *EventService) GetStatusHistory(eventID uuid.UUID) ([]models.EventStatusTransition, error) {
+	var history []models.EventStatusTransition
+	err := s.db.Where("event_id = ?", eventID).
+		Preload("Actor").Order("created_at ASC").Find(&history).Error
+	return history, err
+}
+
+// GetLatestTransition returns the most recent transition into the given status, or nil if there is none
+func (s *EventService) GetLatestTransition(eventID uuid.UUID, toStatus string) (*models.EventStatusTransition, error) {
+	var transition models.EventStatusTransition
+	err := s.db.Where("event_id = ? AND to_status = ?", eventID, toStatus).
+		Order("created_at DESC").First(&transition).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &transition, nil
+}
+
+// transitionWebhook is the webhook event type an action fires; publishing
+// and reopening change the event's status, which counts as an update
+func transitionWebhook(action string) models.WebhookEventType {
+	if action == EventActionCancel {
+		return models.WebhookEventCancelled
+	}
+	return models.WebhookEventUpdated
+}
+
+// webhookTransition describes an event for webhooks along with the status change
+func webhookTransition(tx *gorm.DB, eventID uuid.UUID, history models.EventStatusTransition) func() (map[string]interface{}, error) {
+	return func() (map[string]interface{}, error) {
+		data, err := webhookEvent(tx, eventID)()
+		if err != nil {
+			return nil, err
+		}
+		data["changed_fields"] = []string{"status"}
+		data["from_status"] = history.FromStatus
+		data["reason"] = history.Reason
+		return data, nil
+	}
+}
+
+func canTransition(transition eventTransition, from string) bool {
+	for _, status := range transition.from {
+		if status == from {
+			return true
+		}
+	}
+	return false
+}
+
+// holdEvent keeps an unpublished event from being published again
+func holdEvent(tx *gorm.DB, event *models.Event) error {
+	return tx.Model(event).Update("moderation_hold", true).Error
+}
+
+// voidEventRSVPs marks every RSVP of a cancelled event as void
+func voidEventRSVPs(tx *gorm.DB, event *models.Event) error {
+	return tx.Model(&models.RSVP{}).
+		Where("event_id = ? AND voided_at IS NULL", event.ID).
+		Update("voided_at", time.Now()).Error
+}
+
+// restoreEventRSVPs brings back the RSVPs voided when the event was
+// cancelled. Capacity may have been lowered meanwhile, so confirmed parties
+// are seated again in the order they answered and those that no longer fit
+// join the waitlist; seats left over then go to the waitlist as usual. The
+// caller holds the event lock.
+func restoreEventRSVPs(tx *gorm.DB, event *models.Event) error {
+	if err := tx.Model(&models.RSVP{}).
+		Where("event_id = ? AND voided_at IS NOT NULL AND response <> ?", event.ID, models.RSVPResponseYes).
+		Update("voided_at", nil).Error; err != nil {
+		return err
+	}
+
+	var confirmed []models.RSVP
+	err := tx.Where("event_id = ? AND voided_at IS NOT NULL", event.ID).
+		Order("created_at ASC, id ASC").Find(&confirmed).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range confirmed {
+		rsvp := &confirmed[i]
+		ok, err := hasSeats(tx, event, rsvp.OccurrenceDate, rsvp.GuestCount)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := tx.Model(rsvp).Update("voided_at", nil).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Keep the order they answered in on the waitlist
+		waitlistedAt := now.Add(time.Duration(i) * time.Microsecond)
+		if err := tx.Model(rsvp).Updates(map[string]interface{}{
+			"voided_at":     nil,
+			"response":      models.RSVPResponseWaitlisted,
+			"waitlisted_at": waitlistedAt,
+		}).Error; err != nil {
+			return err
+		}
+		err = queueWebhooks(tx, event.ID, models.WebhookRSVPChanged, webhookRSVP(tx, rsvp.ID, models.RSVPResponseYes))
+		if err != nil {
+			return err
+		}
+	}
+
+	return fillAllWaitlists(tx, event)
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+)
+
+func TestEventTransitions(t *testing.T) {
+	statuses := []string{models.EventStatusDraft, models.EventStatusPublished, models.EventStatusCancelled}
+
+	// Where each action leads from each status; missing entries are illegal
+	tests := []struct {
+		action string
+		to     map[string]string
+	}{
+		{
+			action: EventActionPublish,
+			to:     map[string]string{models.EventStatusDraft: models.EventStatusPublished},
+		},
+		{
+			action: EventActionCancel,
+			to: map[string]string{
+				models.EventStatusDraft:     models.EventStatusCancelled,
+				models.EventStatusPublished: models.EventStatusCancelled,
+			},
+		},
+		{
+			action: EventActionReopen,
+			to:     map[string]string{models.EventStatusCancelled: models.EventStatusPublished},
+		},
+		{
+			action: EventActionUnpublish,
+			to:     map[string]string{models.EventStatusPublished: models.EventStatusDraft},
+		},
+	}
+
+	if len(tests) != len(eventTransitions) {
+		t.Fatalf("testing %d actions, but there are %d transitions", len(tests), len(eventTransitions))
+	}
+
+	for _, tt := range tests {
+		transition, ok := eventTransitions[tt.action]
+		if !ok {
+			t.Errorf("no transition for %s", tt.action)
+			continue
+		}
+		for _, from := range statuses {
+			want, legal := tt.to[from]
+			if got := canTransition(transition, from); got != legal {
+				t.Errorf("canTransition(%s, %s) = %v, want %v", tt.action, from, got, legal)
+			}
+			if legal && transition.to != want {
+				t.Errorf("%s from %s leads to %s, want %s", tt.action, from, transition.to, want)
+			}
+		}
+	}
+}
+
+func TestCancelVoidsRSVPs(t *testing.T) {
+	db, fake := newFakeDB(t, nil)
+	event := &models.Event{ID: uuid.New()}
+
+	if err := eventTransitions[EventActionCancel].sideEffect(db, event); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	updates := fake.execsOn("rsvps")
+	if len(updates) != 1 {
+		t.Fatalf("got %d statements on rsvps, want 1: %v", len(updates), updates)
+	}
+	update := updates[0]
+	if !strings.Contains(update.SQL, `SET "voided_at"=`) || !strings.Contains(update.SQL, "voided_at IS NULL") {
+		t.Errorf("statement doesn't void the active RSVPs: %s", update.SQL)
+	}
+	if !hasArg(update.Args, event.ID.String()) {
+		t.Errorf("statement isn't limited to the event: %v", update.Args)
+	}
+}
+
+func TestUnpublishHoldsEvent(t *testing.T) {
+	db, fake := newFakeDB(t, nil)
+	event := &models.Event{ID: uuid.New()}
+
+	if err := eventTransitions[EventActionUnpublish].sideEffect(db, event); err != nil {
+		t.Fatalf("unpublish: %v", err)
+	}
+
+	updates := fake.execsOn("events")
+	if len(updates) != 1 {
+		t.Fatalf("got %d statements on events, want 1: %v", len(updates), updates)
+	}
+	if !strings.Contains(updates[0].SQL, `SET "moderation_hold"=`) || !hasArg(updates[0].Args, true) {
+		t.Errorf("statement doesn't set the hold: %s %v", updates[0].SQL, updates[0].Args)
+	}
+	if !hasArg(updates[0].Args, event.ID.String()) {
+		t.Errorf("statement isn't limited to the event: %v", updates[0].Args)
+	}
+}
+
+func TestReopenRechecksCapacity(t *testing.T) {
+	event := &models.Event{ID: uuid.New(), MaxAttendees: 4}
+	first := voidedRSVP(event.ID, 3)
+	second := voidedRSVP(event.ID, 2)
+	third := voidedRSVP(event.ID, 1)
+
+	// Seats taken by the RSVPs restored so far
+	var seated int64
+	db, fake := newFakeDB(t, func(query string, args []driver.Value) fakeRows {
+		switch {
+		case strings.Contains(query, "SUM(guest_count)"):
+			return fakeRows{Columns: []string{"coalesce"}, Values: [][]driver.Value{{seated}}}
+		case strings.Contains(query, `FROM "rsvps"`) && strings.Contains(query, "voided_at IS NOT NULL"):
+			return rsvpRows(first, second, third)
+		}
+		return fakeRows{}
+	})
+	// Count a party as seated once it is un-voided without being waitlisted
+	fake.onExec = func(statement fakeStatement) {
+		if strings.Contains(statement.SQL, `UPDATE "rsvps" SET "voided_at"=`) && !hasArg(statement.Args, string(models.RSVPResponseWaitlisted)) {
+			for _, rsvp := range []models.RSVP{first, second, third} {
+				if hasArg(statement.Args, rsvp.ID.String()) {
+					seated += int64(rsvp.GuestCount)
+				}
+			}
+		}
+	}
+
+	if err := eventTransitions[EventActionReopen].sideEffect(db, event); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		rsvp       models.RSVP
+		waitlisted bool
+	}{
+		{"fits", first, false},
+		{"no longer fits", second, true},
+		{"fits in what is left", third, false},
+	}
+	for _, tt := range tests {
+		var restored, waitlisted bool
+		for _, statement := range fake.execsOn("rsvps") {
+			if !hasArg(statement.Args, tt.rsvp.ID.String()) {
+				continue
+			}
+			restored = true
+			waitlisted = waitlisted || hasArg(statement.Args, string(models.RSVPResponseWaitlisted))
+		}
+		if !restored {
+			t.Errorf("%s: RSVP wasn't restored", tt.name)
+		}
+		if waitlisted != tt.waitlisted {
+			t.Errorf("%s: waitlisted = %v, want %v", tt.name, waitlisted, tt.waitlisted)
+		}
+	}
+}
+
+func voidedRSVP(eventID uuid.UUID, guests int) models.RSVP {
+	now := time.Now()
+	return models.RSVP{
+		ID:         uuid.New(),
+		UserID:     uuid.New(),
+		EventID:    eventID,
+		Response:   models.RSVPResponseYes,
+		GuestCount: guests,
+		VoidedAt:   &now,
+		CreatedAt:  now,
+	}
+}
+
+func rsvpRows(rsvps ...models.RSVP) fakeRows {
+	rows := fakeRows{Columns: []string{"id", "user_id", "event_id", "response", "guest_count", "voided_at", "created_at"}}
+	for _, rsvp := range rsvps {
+		rows.Values = append(rows.Values, []driver.Value{
+			rsvp.ID.String(), rsvp.UserID.String(), rsvp.EventID.String(), string(rsvp.Response),
+			int64(rsvp.GuestCount), *rsvp.VoidedAt, rsvp.CreatedAt,
+		})
+	}
+	return rows
+}
+
+func hasArg(args []driver.Value, want driver.Value) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCreatePublishedEventRecordsTransition(t *testing.T) {
+	event := &models.Event{ID: uuid.New(), UserID: uuid.New(), Title: "Picnic", EventDate: time.Now().Add(24 * time.Hour), Status: models.EventStatusPublished}
+
+	db, fake := newFakeDB(t, func(query string, args []driver.Value) fakeRows {
+		if strings.Contains(query, `FROM "events"`) && strings.Contains(query, "FOR UPDATE") {
+			return fakeRows{
+				Columns: []string{"id", "user_id", "status"},
+				Values:  [][]driver.Value{{event.ID.String(), event.UserID.String(), models.EventStatusDraft}},
+			}
+		}
+		return fakeRows{}
+	})
+	service := &EventService{db: db}
+
+	if err := service.createEvent(db, event); err != nil {
+		t.Fatalf("createEvent: %v", err)
+	}
+	if event.Status != models.EventStatusPublished {
+		t.Errorf("Status = %s, want %s", event.Status, models.EventStatusPublished)
+	}
+
+	inserts := fake.execsOn("events")
+	if len(inserts) == 0 || !strings.HasPrefix(inserts[0].SQL, "INSERT") || !hasArg(inserts[0].Args, models.EventStatusDraft) {
+		t.Fatalf("event wasn't inserted as a draft: %v", inserts)
+	}
+	history := fake.execsOn("event_status_transitions")
+	if len(history) != 1 {
+		t.Fatalf("got %d status history statements, want 1", len(history))
+	}
+	for _, want := range []driver.Value{models.EventStatusDraft, models.EventStatusPublished, event.UserID.String()} {
+		if !hasArg(history[0].Args, want) {
+			t.Errorf("history entry lacks %v: %v", want, history[0].Args)
+		}
+	}
+}
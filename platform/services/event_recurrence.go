@@ -0,0 +1,239 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"01-Login/platform/models"
+	"01-Login/platform/recurrence"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// upcomingOccurrenceWindow is how far ahead recurring events are expanded for the upcoming list
+const upcomingOccurrenceWindow = 90 * 24 * time.Hour
+
+// OccurrenceOverride is the change made to a single occurrence
+type OccurrenceOverride struct {
+	EventDate   *time.Time
+	Title       string
+	Description string
+	Venue       string
+}
+
+// ExpandOccurrences returns the occurrences of recurring events that start
+// within [from, to], each as a copy of its event with OccurrenceDate set and
+// any override applied. One-off events are returned unchanged if they fall in
+// the window.
+func (s *EventService) ExpandOccurrences(events []models.Event, from, to time.Time) ([]models.Event, error) {
+	var expanded []models.Event
+
+	for _, event := range events {
+		if !event.IsRecurring() {
+			if !event.EventDate.Before(from) && !event.EventDate.After(to) {
+				expanded = append(expanded, event)
+			}
+			continue
+		}
+
+		rule, err := recurrence.Parse(event.RecurrenceRule)
+		if err != nil {
+			// Rules are validated on write, so this is old or hand-edited data
+			continue
+		}
+
+		// Expand on the event's local wall clock so occurrences keep their time across DST changes
+		dtstart := event.EventDate.In(event.Location())
+		dates := rule.Between(dtstart, from, to, event.RecurrenceExDates)
+		if len(dates) == 0 {
+			continue
+		}
+
+		overrides, err := s.getOccurrenceOverrides(event.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, date := range dates {
+			occurrence := event
+			occurrenceDate := date
+			occurrence.OccurrenceDate = &occurrenceDate
+			occurrence.EventDate = date
+			if event.EndDate != nil {
+				end := date.Add(event.EndDate.Sub(event.EventDate))
+				occurrence.EndDate = &end
+			}
+			if override, ok := overrides[date.Unix()]; ok {
+				applyOverride(&occurrence, &override)
+			}
+			expanded = append(expanded, occurrence)
+		}
+	}
+
+	sort.SliceStable(expanded, func(i, j int) bool {
+		return expanded[i].EventDate.Before(expanded[j].EventDate)
+	})
+	return expanded, nil
+}
+
+// GetOccurrence returns a single occurrence of a recurring event
+func (s *EventService) GetOccurrence(event *models.Event, occurrenceDate time.Time) (*models.Event, error) {
+	if !isOccurrence(event, occurrenceDate) {
+		return nil, errors.New("occurrence not found")
+	}
+
+	occurrences, err := s.ExpandOccurrences([]models.Event{*event}, occurrenceDate, occurrenceDate)
+	if err != nil {
+		return nil, err
+	}
+	if len(occurrences) == 0 {
+		return nil, errors.New("occurrence not found")
+	}
+	return &occurrences[0], nil
+}
+
+// SetOccurrenceOverride creates or replaces the override of one occurrence
+func (s *EventService) SetOccurrenceOverride(event *models.Event, occurrenceDate time.Time, change OccurrenceOverride) (*models.EventOccurrenceOverride, error) {
+	if !isOccurrence(event, occurrenceDate) {
+		return nil, errors.New("occurrence not found")
+	}
+
+	override := models.EventOccurrenceOverride{
+		EventID:        event.ID,
+		OccurrenceDate: occurrenceDate.UTC(),
+		EventDate:      change.EventDate,
+		Title:          change.Title,
+		Description:    change.Description,
+		Venue:          change.Venue,
+	}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}, {Name: "occurrence_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"event_date", "title", "description", "venue", "updated_at"}),
+	}).Create(&override).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Moving an occurrence moves its reminders
+	if err := s.RescheduleReminders(event.ID); err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// DeleteOccurrenceOverride restores a single occurrence to the series' values
+func (s *EventService) DeleteOccurrenceOverride(eventID uuid.UUID, occurrenceDate time.Time) error {
+	result := s.db.Where("event_id = ? AND occurrence_date = ?", eventID, occurrenceDate.UTC()).
+		Delete(&models.EventOccurrenceOverride{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("override not found")
+	}
+	return s.RescheduleReminders(eventID)
+}
+
+func (s *EventService) getOccurrenceOverrides(eventID uuid.UUID) (map[int64]models.EventOccurrenceOverride, error) {
+	var overrides []models.EventOccurrenceOverride
+	if err := s.db.Where("event_id = ?", eventID).Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[int64]models.EventOccurrenceOverride, len(overrides))
+	for _, o := range overrides {
+		byDate[o.OccurrenceDate.Unix()] = o
+	}
+	return byDate, nil
+}
+
+// pageOccurrences merges one-off events with expanded occurrences, both sorted
+// by date, and returns one page. oneOff only needs its first offset+pageSize
+// rows for the page to be correct.
+func pageOccurrences(oneOff, occurrences []models.Event, offset, pageSize int) []models.Event {
+	merged := make([]models.Event, 0, len(oneOff)+len(occurrences))
+	i, j := 0, 0
+	for i < len(oneOff) || j < len(occurrences) {
+		if j >= len(occurrences) || (i < len(oneOff) && !oneOff[i].EventDate.After(occurrences[j].EventDate)) {
+			merged = append(merged, oneOff[i])
+			i++
+		} else {
+			merged = append(merged, occurrences[j])
+			j++
+		}
+	}
+
+	if offset >= len(merged) {
+		return []models.Event{}
+	}
+	end := offset + pageSize
+	if end > len(merged) {
+		end = len(merged)
+	}
+	return merged[offset:end]
+}
+
+// isOccurrence reports whether t is a non-excluded occurrence of a recurring event
+func isOccurrence(event *models.Event, t time.Time) bool {
+	if !event.IsRecurring() {
+		return false
+	}
+	rule, err := recurrence.Parse(event.RecurrenceRule)
+	if err != nil {
+		return false
+	}
+	for _, ex := range event.RecurrenceExDates {
+		if ex.Equal(t) {
+			return false
+		}
+	}
+	return rule.Includes(event.EventDate.In(event.Location()), t)
+}
+
+// checkOccurrence validates the occurrence an RSVP is for: recurring events
+// need one, one-off events must not have one
+func checkOccurrence(event *models.Event, occurrenceDate *time.Time) ValidationErrors {
+	if !event.IsRecurring() {
+		if occurrenceDate != nil {
+			return ValidationErrors{"occurrence_date": "this event does not repeat"}
+		}
+		return nil
+	}
+	if occurrenceDate == nil {
+		return ValidationErrors{"occurrence_date": "is required for a recurring event"}
+	}
+	if !isOccurrence(event, *occurrenceDate) {
+		return ValidationErrors{"occurrence_date": "is not an occurrence of this event"}
+	}
+	return nil
+}
+
+// forOccurrence narrows an RSVP query to one occurrence; nil means a one-off event
+func forOccurrence(db *gorm.DB, occurrenceDate *time.Time) *gorm.DB {
+	if occurrenceDate == nil {
+		return db.Where("occurrence_date IS NULL")
+	}
+	return db.Where("occurrence_date = ?", occurrenceDate.UTC())
+}
+
+func applyOverride(event *models.Event, override *models.EventOccurrenceOverride) {
+	if override.EventDate != nil {
+		if event.EndDate != nil {
+			end := override.EventDate.Add(event.EndDate.Sub(event.EventDate))
+			event.EndDate = &end
+		}
+		event.EventDate = *override.EventDate
+	}
+	if override.Title != "" {
+		event.Title = override.Title
+	}
+	if override.Description != "" {
+		event.Description = override.Description
+	}
+	if override.Venue != "" {
+		event.Venue = override.Venue
+	}
+}
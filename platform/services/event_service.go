@@ -2,31 +2,108 @@ package services
 
 import (
 	"errors"
+	"sort"
+	"strings"
 	"time"
 
 	"01-Login/platform/database"
 	"01-Login/platform/models"
+	"01-Login/platform/realtime"
+	"01-Login/platform/recurrence"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type EventService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	notifications *NotificationService
 }
 
 // NewEventService creates a new event service
 func NewEventService() *EventService {
 	return &EventService{
-		db: database.GetDB(),
+		db:            database.GetDB(),
+		notifications: NewNotificationService(),
 	}
 }
 
 // CreateEvent creates a new event
 func (s *EventService) CreateEvent(event *models.Event) error {
-	if err := s.db.Create(event).Error; err != nil {
+	if err := prepareNewEvent(event); err != nil {
 		return err
 	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.createEvent(tx, event)
+	})
+}
+
+// createEvent inserts an event prepared by prepareNewEvent within tx, along
+// with its photo album, created webhook and reminders. Every event starts as
+// a draft; one asked for as published is then published by its organizer, so
+// its status history records it.
+func (s *EventService) createEvent(tx *gorm.DB, event *models.Event) error {
+	// GORM inserts the column default in place of a false IsPublic, so
+	// private events are made private once inserted
+	isPublic := event.IsPublic
+	publish := event.Status == models.EventStatusPublished
+	event.Status = models.EventStatusDraft
+	if err := tx.Create(event).Error; err != nil {
+		return err
+	}
+	if !isPublic {
+		if err := tx.Model(event).Update("is_public", false).Error; err != nil {
+			return err
+		}
+	}
+	if err := enqueuePhotoAlbum(tx, event); err != nil {
+		return err
+	}
+	if err := queueWebhooks(tx, event.ID, models.WebhookEventCreated, webhookEvent(tx, event.ID)); err != nil {
+		return err
+	}
+	if publish {
+		if err := transitionEvent(tx, event.ID, event.UserID, EventActionPublish, ""); err != nil {
+			return err
+		}
+		event.Status = models.EventStatusPublished
+	}
+	return s.rescheduleReminders(tx, event)
+}
+
+// prepareNewEvent validates a new event, with the same checks as
+// EventUpdate.Validate, and normalizes its title, schedule and rule
+func prepareNewEvent(event *models.Event) error {
+	if event.TimeZone == "" {
+		event.TimeZone = "UTC"
+	}
+	errs := validateEventDetails(&event.Title, &event.MaxAttendees, &event.MaxPlusOnes, &event.VenueLat, &event.VenueLng)
+	if event.EventDate.IsZero() {
+		errs = errs.merge(ValidationErrors{"event_date": "is required"})
+	}
+	if errs = errs.merge(validateSchedule(event)); errs != nil {
+		return errs
+	}
+	event.Title = strings.TrimSpace(event.Title)
+	event.EventDate = event.EventDate.UTC()
+	if event.EndDate != nil {
+		end := event.EndDate.UTC()
+		event.EndDate = &end
+	}
+
+	if event.RecurrenceRule != "" {
+		rule, err := recurrence.Parse(event.RecurrenceRule)
+		if err != nil {
+			return ValidationErrors{"recurrence_rule": err.Error()}
+		}
+		event.RecurrenceRule = rule.String()
+	}
+
+	if errs := validateReminderOffsets(event.ReminderOffsets); errs != nil {
+		return errs
+	}
+	event.ReminderOffsets = normalizeReminderOffsets(event.ReminderOffsets)
 	return nil
 }
 
@@ -42,12 +119,12 @@ func (s *EventService) GetEventByID(id uuid.UUID) (*models.Event, error) {
 	return &event, nil
 }
 
-// GetAllEvents retrieves all events with pagination and optional filtering
-func (s *EventService) GetAllEvents(page, pageSize int, eventType, status string, userID *uuid.UUID) ([]models.Event, int64, error) {
+// GetAllEvents retrieves the events the viewer may see with pagination and optional filtering
+func (s *EventService) GetAllEvents(page, pageSize int, eventType, status string, userID, viewerID *uuid.UUID) ([]models.Event, int64, error) {
 	var events []models.Event
 	var total int64
 
-	query := s.db.Model(&models.Event{})
+	query := s.db.Model(&models.Event{}).Scopes(visibleTo(viewerID), hostedByActiveUser(viewerID))
 
 	// Apply filters
 	if eventType != "" {
@@ -74,19 +151,21 @@ func (s *EventService) GetAllEvents(page, pageSize int, eventType, status string
 	return events, total, nil
 }
 
-// GetEventsByUser retrieves all events for a specific user
-func (s *EventService) GetEventsByUser(userID uuid.UUID, page, pageSize int) ([]models.Event, int64, error) {
+// GetEventsByUser retrieves the events a specific user owns or co-hosts that the viewer may see
+func (s *EventService) GetEventsByUser(userID uuid.UUID, page, pageSize int, viewerID *uuid.UUID) ([]models.Event, int64, error) {
 	var events []models.Event
 	var total int64
 
+	query := s.db.Model(&models.Event{}).Scopes(visibleTo(viewerID), hostedByActiveUser(viewerID), hostedBy(userID))
+
 	// Count total records for user
-	if err := s.db.Model(&models.Event{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated results
 	offset := (page - 1) * pageSize
-	if err := s.db.Preload("User").Where("user_id = ?", userID).Order("event_date ASC").Offset(offset).Limit(pageSize).Find(&events).Error; err != nil {
+	if err := query.Preload("User").Order("event_date ASC").Offset(offset).Limit(pageSize).Find(&events).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -98,7 +177,7 @@ func (s *EventService) GetPublicEvents(page, pageSize int, eventType string) ([]
 	var events []models.Event
 	var total int64
 
-	query := s.db.Model(&models.Event{}).Where("is_public = ? AND status = ?", true, "published")
+	query := s.db.Model(&models.Event{}).Scopes(hostedByActiveUser(nil)).Where("is_public = ? AND status = ?", true, "published")
 
 	// Apply event type filter if provided
 	if eventType != "" {
@@ -119,41 +198,19 @@ func (s *EventService) GetPublicEvents(page, pageSize int, eventType string) ([]
 	return events, total, nil
 }
 
-// GetUpcomingEvents retrieves events that are scheduled for the future
-func (s *EventService) GetUpcomingEvents(page, pageSize int, eventType string, userID *uuid.UUID) ([]models.Event, int64, error) {
-	var events []models.Event
-	var total int64
-
-	query := s.db.Model(&models.Event{}).Where("event_date > ? AND status = ?", time.Now(), "published")
-
-	// Apply filters
-	if eventType != "" {
-		query = query.Where("event_type = ?", eventType)
-	}
-	if userID != nil {
-		query = query.Where("user_id = ?", *userID)
-	}
-
-	// Count total records
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// Get paginated results with user information
-	offset := (page - 1) * pageSize
-	if err := query.Preload("User").Order("event_date ASC").Offset(offset).Limit(pageSize).Find(&events).Error; err != nil {
-		return nil, 0, err
-	}
-
-	return events, total, nil
+// GetUpcomingEvents retrieves events that are scheduled for the future. Recurring
+// events contribute their occurrences within the next upcomingOccurrenceWindow.
+func (s *EventService) GetUpcomingEvents(page, pageSize int, eventType string, userID, viewerID *uuid.UUID) ([]models.Event, int64, error) {
+	now := time.Now()
+	return s.getEventsInWindow(now, now.Add(upcomingOccurrenceWindow), false, page, pageSize, eventType, userID, viewerID)
 }
 
-// SearchEvents searches events by title or description
-func (s *EventService) SearchEvents(searchTerm string, page, pageSize int) ([]models.Event, int64, error) {
+// SearchEvents searches the events the viewer may see by title or description
+func (s *EventService) SearchEvents(searchTerm string, page, pageSize int, viewerID *uuid.UUID) ([]models.Event, int64, error) {
 	var events []models.Event
 	var total int64
 
-	query := s.db.Model(&models.Event{}).Where(
+	query := s.db.Model(&models.Event{}).Scopes(visibleTo(viewerID), hostedByActiveUser(viewerID)).Where(
 		"(title ILIKE ? OR description ILIKE ?) AND status = ?",
 		"%"+searchTerm+"%",
 		"%"+searchTerm+"%",
@@ -174,20 +231,79 @@ func (s *EventService) SearchEvents(searchTerm string, page, pageSize int) ([]mo
 	return events, total, nil
 }
 
-// UpdateEvent updates an existing event
-func (s *EventService) UpdateEvent(id uuid.UUID, updates map[string]interface{}) (*models.Event, error) {
-	var event models.Event
+// UpdateEvent applies a validated partial update to an existing event
+func (s *EventService) UpdateEvent(id uuid.UUID, update *EventUpdate) (*models.Event, error) {
+	if errs := update.Validate(); errs != nil {
+		return nil, errs
+	}
 
-	// Check if event exists
-	if err := s.db.First(&event, "id = ?", id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("event not found")
+	var before models.Event
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Check if event exists, locking it against concurrent RSVPs
+		event, err := lockEvent(tx, id)
+		if err != nil {
+			return err
+		}
+		before = *event
+
+		// Check the resulting schedule as a whole, since start and end can come from either side
+		scheduled := update.schedule(*event)
+		if errs := validateSchedule(&scheduled); errs != nil {
+			return errs
 		}
-		return nil, err
-	}
 
-	// Update event
-	if err := s.db.Model(&event).Updates(updates).Error; err != nil {
+		// Update only the fields that were sent
+		changes := update.Changes()
+		if !sameTime(scheduled.EndDate, event.EndDate) {
+			if scheduled.EndDate != nil {
+				changes["end_date"] = scheduled.EndDate.UTC()
+			} else {
+				changes["end_date"] = nil
+			}
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		if err := tx.Model(event).Updates(changes).Error; err != nil {
+			return err
+		}
+		if err := queueWebhooks(tx, id, models.WebhookEventUpdated, webhookEventUpdate(tx, id, changes)); err != nil {
+			return err
+		}
+		if err := realtime.Notify(tx, id, realtime.KindEvent); err != nil {
+			return err
+		}
+
+		// Turning Google Photos on creates the album, unless there is one from before
+		if enabled, ok := changes["google_photos_enabled"].(bool); ok && enabled {
+			event.GooglePhotosEnabled = true
+			if err := enqueuePhotoAlbum(tx, event); err != nil {
+				return err
+			}
+		}
+
+		var updated models.Event
+		if err := tx.First(&updated, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := s.notifications.NotifyEventUpdated(tx, &before, &updated); err != nil {
+			return err
+		}
+
+		// Pending reminders follow the new schedule
+		if reschedulesReminders(changes) {
+			if err := s.rescheduleReminders(tx, &updated); err != nil {
+				return err
+			}
+		}
+
+		// Raising the capacity frees seats for the waitlist
+		if update.MaxAttendees != nil {
+			return fillAllWaitlists(tx, event)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -195,39 +311,155 @@ func (s *EventService) UpdateEvent(id uuid.UUID, updates map[string]interface{})
 	return s.GetEventByID(id)
 }
 
-// DeleteEvent deletes an event
-func (s *EventService) DeleteEvent(id uuid.UUID) error {
-	result := s.db.Delete(&models.Event{}, "id = ?", id)
+// SetGooglePhotosAlbum stores the Google Photos album created for an event
+func (s *EventService) SetGooglePhotosAlbum(id uuid.UUID, albumID, albumURL string) (*models.Event, error) {
+	result := s.db.Model(&models.Event{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"google_photos_album_id":  albumID,
+		"google_photos_album_url": albumURL,
+	})
 	if result.Error != nil {
-		return result.Error
+		return nil, result.Error
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("event not found")
+		return nil, errors.New("event not found")
 	}
-	return nil
+
+	return s.GetEventByID(id)
 }
 
-// GetEventsByDateRange retrieves events within a specific date range
-func (s *EventService) GetEventsByDateRange(startDate, endDate time.Time, page, pageSize int, userID *uuid.UUID) ([]models.Event, int64, error) {
-	var events []models.Event
-	var total int64
+// DeleteEvent deletes an event
+func (s *EventService) DeleteEvent(id uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Event{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("event not found")
+		}
+		// Ends the streams of anyone watching it
+		return realtime.Notify(tx, id, realtime.KindDeleted)
+	})
+}
 
-	query := s.db.Model(&models.Event{}).Where("event_date BETWEEN ? AND ? AND status = ?", startDate, endDate, "published")
+// GetEventsByDateRange retrieves events within a specific date range, expanding
+// recurring events into their occurrences within the range
+func (s *EventService) GetEventsByDateRange(startDate, endDate time.Time, page, pageSize int, userID, viewerID *uuid.UUID) ([]models.Event, int64, error) {
+	return s.getEventsInWindow(startDate, endDate, true, page, pageSize, "", userID, viewerID)
+}
 
-	if userID != nil {
-		query = query.Where("user_id = ?", *userID)
+// getEventsInWindow pages through published events starting at or after from,
+// merged with the occurrences of recurring events up to to. One-off events are
+// only capped at to when bounded is set. Only events the viewer may see are included.
+func (s *EventService) getEventsInWindow(from, to time.Time, bounded bool, page, pageSize int, eventType string, userID, viewerID *uuid.UUID) ([]models.Event, int64, error) {
+	var oneOff []models.Event
+	var oneOffTotal int64
+
+	filter := func(query *gorm.DB) *gorm.DB {
+		query = query.Scopes(visibleTo(viewerID), hostedByActiveUser(viewerID)).Where("status = ?", models.EventStatusPublished)
+		if eventType != "" {
+			query = query.Where("event_type = ?", eventType)
+		}
+		if userID != nil {
+			query = query.Where("user_id = ?", *userID)
+		}
+		return query
+	}
+
+	query := filter(s.db.Model(&models.Event{})).Where("recurrence_rule = '' AND event_date >= ?", from)
+	if bounded {
+		query = query.Where("event_date <= ?", to)
 	}
 
 	// Count total records
-	if err := query.Count(&total).Error; err != nil {
+	if err := query.Count(&oneOffTotal).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// Get paginated results with user information
+	// Only the rows up to the end of the requested page can appear on it
 	offset := (page - 1) * pageSize
-	if err := query.Preload("User").Order("event_date ASC").Offset(offset).Limit(pageSize).Find(&events).Error; err != nil {
+	if err := query.Preload("User").Order("event_date ASC").Limit(offset + pageSize).Find(&oneOff).Error; err != nil {
 		return nil, 0, err
 	}
 
-	return events, total, nil
+	var series []models.Event
+	if err := filter(s.db).Preload("User").Where("recurrence_rule <> '' AND event_date <= ?", to).Find(&series).Error; err != nil {
+		return nil, 0, err
+	}
+	occurrences, err := s.ExpandOccurrences(series, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := oneOffTotal + int64(len(occurrences))
+	return pageOccurrences(oneOff, occurrences, offset, pageSize), total, nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// enqueuePhotoAlbum queues the job creating an event's Google Photos album,
+// if it wants one and doesn't have a shared one yet
+func enqueuePhotoAlbum(tx *gorm.DB, event *models.Event) error {
+	if !event.GooglePhotosEnabled || event.GooglePhotosAlbumURL != "" {
+		return nil
+	}
+	return enqueueJob(tx, JobKindCreatePhotoAlbum, nil, JobOptions{
+		EventID:   &event.ID,
+		UniqueKey: JobKindCreatePhotoAlbum + ":" + event.ID.String(),
+	})
+}
+
+// webhookEventUpdate describes an updated event for webhooks, along with the fields that changed
+func webhookEventUpdate(tx *gorm.DB, id uuid.UUID, changes map[string]interface{}) func() (map[string]interface{}, error) {
+	return func() (map[string]interface{}, error) {
+		data, err := webhookEvent(tx, id)()
+		if err != nil {
+			return nil, err
+		}
+		changed := make([]string, 0, len(changes))
+		for column := range changes {
+			changed = append(changed, column)
+		}
+		sort.Strings(changed)
+		data["changed_fields"] = changed
+		return data, nil
+	}
+}
+
+// hostedBy limits a query to events the user owns or co-hosts
+func hostedBy(userID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("events.id IN (SELECT event_id FROM event_members WHERE user_id = ? AND role IN ?)",
+			userID, []models.EventRole{models.EventRoleOwner, models.EventRoleCoHost})
+	}
+}
+
+// hostedByActiveUser hides the events of organizers an admin deactivated,
+// except from the events' members. viewerID is nil for signed-out visitors.
+func hostedByActiveUser(viewerID *uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerID == nil {
+			return db.Where("events.user_id IN (SELECT id FROM users WHERE is_active)")
+		}
+		return db.Where(
+			`(events.user_id IN (SELECT id FROM users WHERE is_active)
+				OR events.id IN (SELECT event_id FROM event_members WHERE user_id = ?))`,
+			*viewerID,
+		)
+	}
+}
+
+// reschedulesReminders reports whether an update moves the times guests are reminded at
+func reschedulesReminders(changes map[string]interface{}) bool {
+	for _, column := range []string{"event_date", "time_zone", "recurrence_rule", "recurrence_exdates", "reminder_offsets"} {
+		if _, ok := changes[column]; ok {
+			return true
+		}
+	}
+	return false
 }
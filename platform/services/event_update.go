@@ -0,0 +1,295 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"01-Login/platform/models"
+	"01-Login/platform/recurrence"
+)
+
+// EventUpdate is a partial update of an event. Only the editable fields are
+// listed and a nil field means the caller did not send it, so it is left
+// untouched. Status changes go through TransitionEvent instead.
+type EventUpdate struct {
+	Title               *string  `json:"title"`
+	Description         *string  `json:"description"`
+	Venue               *string  `json:"venue"`
+	VenueName           *string  `json:"venue_name"`
+	VenuePlaceID        *string  `json:"venue_place_id"`
+	VenueLat            *float64 `json:"venue_lat"`
+	VenueLng            *float64 `json:"venue_lng"`
+	EventDate           *string  `json:"event_date"`       // RFC 3339
+	EndDate             *string  `json:"end_date"`         // RFC 3339, "" removes the end
+	DurationMinutes     *int     `json:"duration_minutes"` // Alternative to end_date
+	TimeZone            *string  `json:"time_zone"`        // IANA name, e.g. Europe/Berlin
+	Image               *string  `json:"image"`
+	EventType           *string  `json:"event_type"`
+	IsPublic            *bool    `json:"is_public"`
+	MaxAttendees        *int     `json:"max_attendees"`
+	MaxPlusOnes         *int     `json:"max_plus_ones"`
+	GooglePhotosEnabled *bool    `json:"google_photos_enabled"`
+	RecurrenceRule      *string  `json:"recurrence_rule"`    // RRULE, "" makes the event one-off
+	RecurrenceExDates   []string `json:"recurrence_exdates"` // RFC 3339, replaces the list when sent
+	ReminderOffsets     []int    `json:"reminder_offsets"`   // Minutes before the start, replaces the list when sent
+}
+
+// Reminders are limited to a handful, between five minutes and 60 days before the start
+const (
+	maxReminders      = 5
+	minReminderOffset = 5
+	maxReminderOffset = 60 * 24 * 60
+)
+
+// ValidationErrors maps a JSON field name to the reason its value was rejected
+type ValidationErrors map[string]string
+
+func (v ValidationErrors) Error() string {
+	fields := make([]string, 0, len(v))
+	for field, msg := range v {
+		fields = append(fields, fmt.Sprintf("%s: %s", field, msg))
+	}
+	sort.Strings(fields)
+	return "validation failed: " + strings.Join(fields, "; ")
+}
+
+// merge combines two sets of problems, returning nil if there are none
+func (v ValidationErrors) merge(other ValidationErrors) ValidationErrors {
+	if len(other) == 0 {
+		return v
+	}
+	if v == nil {
+		v = ValidationErrors{}
+	}
+	for field, msg := range other {
+		v[field] = msg
+	}
+	return v
+}
+
+// Validate checks every field that was sent and returns the problems per field
+func (u *EventUpdate) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	errs = errs.merge(validateEventDetails(u.Title, u.MaxAttendees, u.MaxPlusOnes, u.VenueLat, u.VenueLng))
+	if u.EventDate != nil {
+		if _, err := time.Parse(time.RFC3339, *u.EventDate); err != nil {
+			errs["event_date"] = "must be an RFC 3339 timestamp"
+		}
+	}
+	if u.EndDate != nil && *u.EndDate != "" {
+		if _, err := time.Parse(time.RFC3339, *u.EndDate); err != nil {
+			errs["end_date"] = "must be an RFC 3339 timestamp"
+		}
+	}
+	if u.DurationMinutes != nil {
+		if *u.DurationMinutes <= 0 {
+			errs["duration_minutes"] = "must be greater than 0"
+		}
+		if u.EndDate != nil {
+			errs["duration_minutes"] = "send either end_date or duration_minutes, not both"
+		}
+	}
+	if u.TimeZone != nil {
+		if _, err := time.LoadLocation(*u.TimeZone); err != nil || *u.TimeZone == "" {
+			errs["time_zone"] = "must be an IANA time zone such as Europe/Berlin"
+		}
+	}
+	if u.RecurrenceRule != nil && *u.RecurrenceRule != "" {
+		if _, err := recurrence.Parse(*u.RecurrenceRule); err != nil {
+			errs["recurrence_rule"] = err.Error()
+		}
+	}
+	for _, exdate := range u.RecurrenceExDates {
+		if _, err := time.Parse(time.RFC3339, exdate); err != nil {
+			errs["recurrence_exdates"] = "must be RFC 3339 timestamps"
+			break
+		}
+	}
+	errs = errs.merge(validateReminderOffsets(u.ReminderOffsets))
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// schedule applies the sent start, end, duration and time zone to a copy of
+// the event. Moving the start of an event that has an end keeps its length.
+func (u *EventUpdate) schedule(event models.Event) models.Event {
+	if u.EventDate != nil {
+		start, _ := time.Parse(time.RFC3339, *u.EventDate)
+		if event.EndDate != nil && u.EndDate == nil && u.DurationMinutes == nil {
+			end := start.Add(event.EndDate.Sub(event.EventDate))
+			event.EndDate = &end
+		}
+		event.EventDate = start
+	}
+	if u.EndDate != nil {
+		event.EndDate = nil
+		if *u.EndDate != "" {
+			end, _ := time.Parse(time.RFC3339, *u.EndDate)
+			event.EndDate = &end
+		}
+	}
+	if u.DurationMinutes != nil {
+		end := event.EventDate.Add(time.Duration(*u.DurationMinutes) * time.Minute)
+		event.EndDate = &end
+	}
+	if u.TimeZone != nil {
+		event.TimeZone = *u.TimeZone
+	}
+	return event
+}
+
+// validateEventDetails checks the title, capacity, plus-ones and venue
+// coordinates of a new or updated event. A nil field isn't checked.
+func validateEventDetails(title *string, maxAttendees, maxPlusOnes *int, lat, lng *float64) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if title != nil && strings.TrimSpace(*title) == "" {
+		errs["title"] = "must not be empty"
+	}
+	if maxAttendees != nil && *maxAttendees < 0 {
+		errs["max_attendees"] = "must be 0 (unlimited) or greater"
+	}
+	if maxPlusOnes != nil && *maxPlusOnes < 0 {
+		errs["max_plus_ones"] = "must be 0 or greater"
+	}
+	if lat != nil && (*lat < -90 || *lat > 90) {
+		errs["venue_lat"] = "must be between -90 and 90"
+	}
+	if lng != nil && (*lng < -180 || *lng > 180) {
+		errs["venue_lng"] = "must be between -180 and 180"
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateSchedule checks an event's time zone and that it ends after it starts
+func validateSchedule(event *models.Event) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if event.TimeZone != "" {
+		if _, err := time.LoadLocation(event.TimeZone); err != nil {
+			errs["time_zone"] = "must be an IANA time zone such as Europe/Berlin"
+		}
+	}
+	if event.EndDate != nil && !event.EndDate.After(event.EventDate) {
+		errs["end_date"] = "must be after event_date"
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateReminderOffsets checks the minutes before the start guests are reminded at
+func validateReminderOffsets(offsets []int) ValidationErrors {
+	if len(offsets) > maxReminders {
+		return ValidationErrors{"reminder_offsets": fmt.Sprintf("at most %d reminders are allowed", maxReminders)}
+	}
+	for _, offset := range offsets {
+		if offset < minReminderOffset || offset > maxReminderOffset {
+			return ValidationErrors{"reminder_offsets": fmt.Sprintf("must be between %d minutes and %d days before the start", minReminderOffset, maxReminderOffset/(24*60))}
+		}
+	}
+	return nil
+}
+
+// normalizeReminderOffsets drops duplicate offsets and sorts them largest first
+func normalizeReminderOffsets(offsets []int) []int {
+	normalized := make([]int, 0, len(offsets))
+	seen := make(map[int]bool, len(offsets))
+	for _, offset := range offsets {
+		if !seen[offset] {
+			seen[offset] = true
+			normalized = append(normalized, offset)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(normalized)))
+	return normalized
+}
+
+// Changes returns the column updates for the fields that were sent.
+// It assumes Validate has already passed; end_date is derived by UpdateEvent.
+func (u *EventUpdate) Changes() map[string]interface{} {
+	changes := make(map[string]interface{})
+
+	if u.Title != nil {
+		changes["title"] = strings.TrimSpace(*u.Title)
+	}
+	if u.Description != nil {
+		changes["description"] = *u.Description
+	}
+	if u.Venue != nil {
+		changes["venue"] = *u.Venue
+	}
+	if u.VenueName != nil {
+		changes["venue_name"] = *u.VenueName
+	}
+	if u.VenuePlaceID != nil {
+		changes["venue_place_id"] = *u.VenuePlaceID
+	}
+	if u.VenueLat != nil {
+		changes["venue_lat"] = *u.VenueLat
+	}
+	if u.VenueLng != nil {
+		changes["venue_lng"] = *u.VenueLng
+	}
+	if u.EventDate != nil {
+		eventDate, _ := time.Parse(time.RFC3339, *u.EventDate)
+		changes["event_date"] = eventDate.UTC()
+	}
+	if u.TimeZone != nil {
+		changes["time_zone"] = *u.TimeZone
+	}
+	if u.Image != nil {
+		changes["image"] = *u.Image
+	}
+	if u.EventType != nil {
+		changes["event_type"] = *u.EventType
+	}
+	if u.IsPublic != nil {
+		changes["is_public"] = *u.IsPublic
+	}
+	if u.MaxAttendees != nil {
+		changes["max_attendees"] = *u.MaxAttendees
+	}
+	if u.MaxPlusOnes != nil {
+		changes["max_plus_ones"] = *u.MaxPlusOnes
+	}
+	if u.GooglePhotosEnabled != nil {
+		changes["google_photos_enabled"] = *u.GooglePhotosEnabled
+	}
+	if u.RecurrenceRule != nil {
+		rule := ""
+		if parsed, err := recurrence.Parse(*u.RecurrenceRule); err == nil {
+			rule = parsed.String()
+		}
+		changes["recurrence_rule"] = rule
+	}
+	if u.RecurrenceExDates != nil {
+		exdates := make([]time.Time, 0, len(u.RecurrenceExDates))
+		for _, exdate := range u.RecurrenceExDates {
+			t, _ := time.Parse(time.RFC3339, exdate)
+			exdates = append(exdates, t.UTC())
+		}
+		// Map updates bypass the column's JSON serializer
+		encoded, _ := json.Marshal(exdates)
+		changes["recurrence_exdates"] = string(encoded)
+	}
+	if u.ReminderOffsets != nil {
+		encoded, _ := json.Marshal(normalizeReminderOffsets(u.ReminderOffsets))
+		changes["reminder_offsets"] = string(encoded)
+	}
+
+	return changes
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeDB is a database that answers queries from a test's function and
+// records every statement, so code working on a *gorm.DB can be tested
+// without Postgres
+type fakeDB struct {
+	mu     sync.Mutex
+	query  func(query string, args []driver.Value) fakeRows
+	onExec func(statement fakeStatement) // Optional, to change what later queries return
+	execs  []fakeStatement
+}
+
+// fakeStatement is a recorded insert, update or delete
+type fakeStatement struct {
+	SQL  string
+	Args []driver.Value
+}
+
+// fakeRows is the result of a query
+type fakeRows struct {
+	Columns []string
+	Values  [][]driver.Value
+}
+
+// newFakeDB opens a GORM database on a fake. query answers selects and may
+// be nil, in which case every select comes back empty.
+func newFakeDB(t *testing.T, query func(query string, args []driver.Value) fakeRows) (*gorm.DB, *fakeDB) {
+	t.Helper()
+
+	fake := &fakeDB{query: query}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(fake)}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("opening fake database: %v", err)
+	}
+	return db, fake
+}
+
+// execsOn returns the recorded statements that mention the table
+func (f *fakeDB) execsOn(table string) []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var matching []fakeStatement
+	for _, exec := range f.execs {
+		if strings.Contains(exec.SQL, `"`+table+`"`) {
+			matching = append(matching, exec)
+		}
+	}
+	return matching
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fake database doesn't prepare statements")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(fakeStatement{SQL: query, Args: values(args)})
+	return driver.RowsAffected(1), nil
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if strings.HasPrefix(query, "INSERT") || strings.HasPrefix(query, "UPDATE") || strings.HasPrefix(query, "DELETE") {
+		c.db.record(fakeStatement{SQL: query, Args: values(args)})
+		return &fakeCursor{}, nil
+	}
+	if c.db.query == nil {
+		return &fakeCursor{}, nil
+	}
+	return &fakeCursor{rows: c.db.query(query, values(args))}, nil
+}
+
+func (f *fakeDB) record(statement fakeStatement) {
+	f.mu.Lock()
+	f.execs = append(f.execs, statement)
+	f.mu.Unlock()
+	if f.onExec != nil {
+		f.onExec(statement)
+	}
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vals[i] = arg.Value
+	}
+	return vals
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeCursor struct {
+	rows fakeRows
+	next int
+}
+
+func (r *fakeCursor) Columns() []string { return r.rows.Columns }
+func (r *fakeCursor) Close() error      { return nil }
+
+func (r *fakeCursor) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows.Values) {
+		return io.EOF
+	}
+	copy(dest, r.rows.Values[r.next])
+	r.next++
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -66,21 +67,9 @@ func NewGooglePhotosService() *GooglePhotosService {
 
 // CreateSharedAlbum creates a shared album for an event and returns the album ID and shareable URL
 func (gps *GooglePhotosService) CreateSharedAlbum(ctx context.Context, userID uuid.UUID, eventTitle string) (albumID, shareableURL string, err error) {
-	// Get user with Google Photos tokens
-	user, err := gps.getUserWithTokens(userID)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to get user tokens: %w", err)
-	}
-
-	// Check if user has Google Photos connected
-	if user.GooglePhotosAccessToken == "" {
-		return "", "", fmt.Errorf("user has not connected Google Photos")
-	}
-
-	// Create OAuth client with user's tokens
-	client, err := gps.createOAuthClient(ctx, user)
+	client, err := gps.albumClient(ctx, userID)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create OAuth client: %w", err)
+		return "", "", err
 	}
 
 	// Create the album
@@ -98,6 +87,27 @@ func (gps *GooglePhotosService) CreateSharedAlbum(ctx context.Context, userID uu
 	return albumID, shareableURL, nil
 }
 
+// albumClient returns an HTTP client calling the Photos API on the user's behalf
+func (gps *GooglePhotosService) albumClient(ctx context.Context, userID uuid.UUID) (*http.Client, error) {
+	// Get user with Google Photos tokens
+	user, err := gps.getUserWithTokens(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tokens: %w", err)
+	}
+
+	// Check if user has Google Photos connected
+	if user.GooglePhotosAccessToken == "" {
+		return nil, fmt.Errorf("user has not connected Google Photos")
+	}
+
+	// Create OAuth client with user's tokens
+	client, err := gps.createOAuthClient(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OAuth client: %w", err)
+	}
+	return client, nil
+}
+
 // getUserWithTokens retrieves user with Google Photos tokens
 func (gps *GooglePhotosService) getUserWithTokens(userID uuid.UUID) (*models.User, error) {
 	var user models.User
@@ -240,3 +250,65 @@ func (gps *GooglePhotosService) CheckUserHasGooglePhotos(userID uuid.UUID) (bool
 
 	return user.GooglePhotosAccessToken != "", nil
 }
+
+// createEventPhotoAlbum is the job that creates the shared album of an event
+// with Google Photos enabled. Jobs for events that are gone, no longer want
+// an album or already have a shared one succeed without doing anything.
+//
+// The album ID is stored as soon as the album exists, so a retry after the
+// sharing step failed shares that album instead of creating a second one.
+func createEventPhotoAlbum(ctx context.Context, job *models.Job) error {
+	if job.EventID == nil {
+		return permanent(fmt.Errorf("job has no event"))
+	}
+	db := database.GetDB()
+	var event models.Event
+	if err := db.First(&event, "id = ?", *job.EventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !event.GooglePhotosEnabled || event.GooglePhotosAlbumURL != "" {
+		return nil
+	}
+
+	gps := NewGooglePhotosService()
+	hasGooglePhotos, err := gps.CheckUserHasGooglePhotos(event.UserID)
+	if err != nil {
+		return err
+	}
+	if !hasGooglePhotos {
+		return permanent(fmt.Errorf("organizer %v hasn't connected Google Photos", event.UserID))
+	}
+
+	client, err := gps.albumClient(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+
+	albumID := event.GooglePhotosAlbumID
+	if albumID == "" {
+		albumID, err = gps.createAlbum(ctx, client, fmt.Sprintf("%s - Photos", event.Title))
+		if err != nil {
+			return fmt.Errorf("failed to create album: %w", err)
+		}
+		result := db.Model(&models.Event{}).
+			Where("id = ? AND google_photos_album_id = ''", event.ID).
+			Update("google_photos_album_id", albumID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to store album %s: %w", albumID, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			// Another run stored its album first; it shares that one
+			return nil
+		}
+	}
+
+	shareableURL, err := gps.shareAlbum(ctx, client, albumID)
+	if err != nil {
+		return fmt.Errorf("failed to share album: %w", err)
+	}
+	_, err = NewEventService().SetGooglePhotosAlbum(event.ID, albumID, shareableURL)
+	return err
+}
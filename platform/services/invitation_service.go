@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"01-Login/platform/authorization"
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+	"01-Login/platform/notifications"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidInvitation is returned for invite tokens that are forged, revoked
+// or already used by someone else
+var ErrInvalidInvitation = errors.New("invitation is invalid or has been revoked")
+
+type InvitationService struct {
+	db *gorm.DB
+}
+
+// NewInvitationService creates a new invitation service
+func NewInvitationService() *InvitationService {
+	return &InvitationService{
+		db: database.GetDB(),
+	}
+}
+
+// InviteByEmail invites each address to an event and queues an email with
+// the personal invite link to each; invitations stay pending until it is
+// delivered. Addresses that are already invited keep their existing
+// invitation, and are emailed again only while it is still pending.
+func (s *InvitationService) InviteByEmail(event *models.Event, inviterID uuid.UUID, emails []string) ([]models.EventInvitation, error) {
+	errs := ValidationErrors{}
+	normalized := make([]string, 0, len(emails))
+	for i, email := range emails {
+		address, err := mail.ParseAddress(strings.TrimSpace(email))
+		if err != nil {
+			errs[fmt.Sprintf("emails[%d]", i)] = "is not a valid email address"
+			continue
+		}
+		normalized = append(normalized, strings.ToLower(address.Address))
+	}
+	if len(emails) == 0 {
+		errs["emails"] = "must contain at least one address"
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	invitations := make([]models.EventInvitation, 0, len(normalized))
+	for _, email := range normalized {
+		invitations = append(invitations, models.EventInvitation{
+			EventID:     event.ID,
+			Email:       email,
+			Via:         models.InvitationViaEmail,
+			Status:      models.InvitationStatusPending,
+			InvitedByID: inviterID,
+		})
+	}
+
+	var stored []models.EventInvitation
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "event_id"}, {Name: "email"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "email <> ''"}}},
+			DoNothing:   true,
+		}).Create(&invitations).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Preload("User").Where("event_id = ? AND email IN ?", event.ID, normalized).
+			Order("created_at ASC").Find(&stored).Error
+		if err != nil {
+			return err
+		}
+		for i := range stored {
+			if stored[i].Status != models.InvitationStatusPending {
+				continue
+			}
+			err := enqueueJob(tx, JobKindSendInvitation, models.JobPayload{"invitation_id": stored[i].ID.String()}, JobOptions{
+				EventID:   &event.ID,
+				UniqueKey: JobKindSendInvitation + ":" + stored[i].ID.String(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// GetEventInvitations lists an event's invitations, oldest first
+func (s *InvitationService) GetEventInvitations(eventID uuid.UUID) ([]models.EventInvitation, error) {
+	var invitations []models.EventInvitation
+	err := s.db.Preload("User").Where("event_id = ?", eventID).Order("created_at ASC").Find(&invitations).Error
+	return invitations, err
+}
+
+// DeleteInvitation revokes an invitation. The invitee loses access to a
+// private event; an RSVP they already sent is kept.
+func (s *InvitationService) DeleteInvitation(eventID, invitationID uuid.UUID) error {
+	result := s.db.Where("id = ? AND event_id = ?", invitationID, eventID).Delete(&models.EventInvitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invitation not found")
+	}
+	return nil
+}
+
+// InvitationToken returns the signed token of an email invitation's personal link
+func (s *InvitationService) InvitationToken(invitation *models.EventInvitation) string {
+	return invitationToken(invitation)
+}
+
+func invitationToken(invitation *models.EventInvitation) string {
+	return signToken(inviteSigningKey(), "i:"+invitation.ID.String())
+}
+
+// sendInvitation emails an invitee their personal invite link and marks the
+// invitation sent. Invitations revoked or opened in the meantime are left alone.
+func sendInvitation(ctx context.Context, job *models.Job) error {
+	db := database.GetDB()
+
+	invitationID, err := uuid.Parse(fmt.Sprint(job.Payload["invitation_id"]))
+	if err != nil {
+		return permanent(fmt.Errorf("job has no invitation: %w", err))
+	}
+	var invitation models.EventInvitation
+	if err := db.First(&invitation, "id = ?", invitationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if invitation.Status != models.InvitationStatusPending || invitation.Email == "" {
+		return nil
+	}
+
+	var event models.Event
+	if err := db.First(&event, "id = ?", invitation.EventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	var inviter models.PublicUser
+	if err := db.First(&inviter, "id = ?", invitation.InvitedByID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	data := &notifications.Data{
+		Inviter:  inviter.Name,
+		Event:    &event,
+		When:     formatEventTime(&event, event.EventDate),
+		EventURL: notifications.AppURL() + "/events/" + event.ID.String() + "?invite=" + url.QueryEscape(invitationToken(&invitation)),
+	}
+	if err := notifications.Default().Send(invitation.Email, notifications.TemplateInvitation, data, nil); err != nil {
+		return err
+	}
+
+	return db.Model(&invitation).Where("status = ?", models.InvitationStatusPending).Updates(map[string]interface{}{
+		"status":  models.InvitationStatusSent,
+		"sent_at": time.Now(),
+	}).Error
+}
+
+// InviteLinkToken returns the signed token of an event's shareable invite link
+func (s *InvitationService) InviteLinkToken(event *models.Event) string {
+	return signToken(inviteSigningKey(), "l:"+event.ID.String()+":"+strconv.Itoa(event.InviteLinkVersion))
+}
+
+// ResetInviteLink revokes every shareable link issued for an event so far.
+// Invitations already opened through them are kept.
+func (s *InvitationService) ResetInviteLink(eventID uuid.UUID) (*models.Event, error) {
+	err := s.db.Model(&models.Event{}).Where("id = ?", eventID).
+		UpdateColumn("invite_link_version", gorm.Expr("invite_link_version + 1")).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var event models.Event
+	if err := s.db.Preload("User").First(&event, "id = ?", eventID).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// AcceptInvitation redeems an invite token for the user, giving them access
+// to the event and marking their invitation opened
+func (s *InvitationService) AcceptInvitation(user *models.User, event *models.Event, token string) (*models.EventInvitation, error) {
+	payload, ok := verifyToken(inviteSigningKey(), token)
+	if !ok {
+		return nil, ErrInvalidInvitation
+	}
+	parts := strings.Split(payload, ":")
+
+	// Whatever the token, someone already invited keeps their invitation
+	existing, err := s.findInvitation(user, event.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case parts[0] == "i" && len(parts) == 2:
+		var invitation models.EventInvitation
+		err := s.db.First(&invitation, "id = ? AND event_id = ?", parts[1], event.ID).Error
+		if err != nil {
+			return nil, ErrInvalidInvitation
+		}
+		if invitation.UserID != nil && *invitation.UserID != user.ID {
+			return nil, ErrInvalidInvitation
+		}
+		if existing == nil {
+			existing = &invitation
+		}
+
+	case parts[0] == "l" && len(parts) == 3:
+		if parts[1] != event.ID.String() || parts[2] != strconv.Itoa(event.InviteLinkVersion) {
+			return nil, ErrInvalidInvitation
+		}
+		if existing == nil {
+			invitation := models.EventInvitation{
+				EventID:     event.ID,
+				UserID:      &user.ID,
+				Via:         models.InvitationViaLink,
+				Status:      models.InvitationStatusSent,
+				InvitedByID: event.UserID,
+			}
+			if err := s.db.Create(&invitation).Error; err != nil {
+				return nil, err
+			}
+			existing = &invitation
+		}
+
+	default:
+		return nil, ErrInvalidInvitation
+	}
+
+	if err := s.openInvitation(existing, user.ID); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// CanView reports whether the user, nil when signed out, may see the event
+// and RSVP to it. Events of deactivated organizers are only shown to their
+// members. It only reads; invitations are opened by AcceptInvitation.
+func (s *InvitationService) CanView(user *models.User, event *models.Event) (bool, error) {
+	if event == nil {
+		return false, nil
+	}
+
+	var role models.EventRole
+	if user != nil {
+		var err error
+		if role, err = getEventRole(s.db, event.ID, user.ID); err != nil {
+			return false, err
+		}
+	}
+	if role == "" {
+		var active int64
+		err := s.db.Model(&models.Event{}).Scopes(hostedByActiveUser(nil)).Where("events.id = ?", event.ID).Count(&active).Error
+		if err != nil {
+			return false, err
+		}
+		if active == 0 {
+			return false, nil
+		}
+	}
+	if authorization.CanViewEvent(event, role, false) {
+		return true, nil
+	}
+	if user == nil {
+		return false, nil
+	}
+
+	invitation, err := s.findInvitation(user, event.ID)
+	if err != nil {
+		return false, err
+	}
+	return authorization.CanViewEvent(event, role, invitation != nil), nil
+}
+
+// findInvitation returns the user's invitation to an event, preferring one
+// already bound to them over one matching their email
+func (s *InvitationService) findInvitation(user *models.User, eventID uuid.UUID) (*models.EventInvitation, error) {
+	var invitation models.EventInvitation
+	query := s.db.Where("event_id = ?", eventID)
+	if user.Email != "" {
+		query = query.Where("user_id = ? OR (user_id IS NULL AND email = ?)", user.ID, strings.ToLower(user.Email))
+	} else {
+		query = query.Where("user_id = ?", user.ID)
+	}
+	err := query.Order("user_id IS NULL").First(&invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// openInvitation binds an invitation to the user who opened it and moves it
+// from pending or sent to opened
+func (s *InvitationService) openInvitation(invitation *models.EventInvitation, userID uuid.UUID) error {
+	unopened := invitation.Status == models.InvitationStatusPending || invitation.Status == models.InvitationStatusSent
+	if invitation.UserID != nil && !unopened {
+		return nil
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"user_id": userID}
+	if unopened {
+		updates["status"] = models.InvitationStatusOpened
+		updates["opened_at"] = now
+	}
+	if err := s.db.Model(invitation).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	invitation.UserID = &userID
+	if unopened {
+		invitation.Status = models.InvitationStatusOpened
+		invitation.OpenedAt = &now
+	}
+	return nil
+}
+
+// markInvitationResponded records that an invitee has RSVPed. An email
+// invitation the user never opened a link for is bound to them here, unless
+// they already hold another invitation to the event.
+func markInvitationResponded(tx *gorm.DB, eventID, userID uuid.UUID) error {
+	return tx.Model(&models.EventInvitation{}).
+		Where("event_id = ? AND status <> ?", eventID, models.InvitationStatusResponded).
+		Where(
+			`(user_id = ? OR (user_id IS NULL AND email <> '' AND email = (SELECT LOWER(email) FROM users WHERE id = ?)
+				AND NOT EXISTS (SELECT 1 FROM event_invitations bound WHERE bound.event_id = ? AND bound.user_id = ?)))`,
+			userID, userID, eventID, userID,
+		).
+		Updates(map[string]interface{}{
+			"user_id":      userID,
+			"status":       models.InvitationStatusResponded,
+			"responded_at": time.Now(),
+		}).Error
+}
+
+// visibleTo limits an event query to the events a viewer may see: public
+// events, plus for signed-in viewers the private events they are a member of
+// or invited to. viewerID is nil for signed-out visitors.
+func visibleTo(viewerID *uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerID == nil {
+			return db.Where("events.is_public = ?", true)
+		}
+		return db.Where(
+			`(events.is_public = ? OR events.id IN (
+				SELECT event_id FROM event_members WHERE user_id = ?
+			) OR events.id IN (
+				SELECT event_id FROM event_invitations
+				WHERE user_id = ? OR (user_id IS NULL AND email <> '' AND email = (SELECT LOWER(email) FROM users WHERE id = ?))
+			))`,
+			true, *viewerID, *viewerID, *viewerID,
+		)
+	}
+}
+
+// inviteSigningKey signs invite tokens. INVITE_SECRET can be set to rotate
+// invite links independently of sessions.
+func inviteSigningKey() []byte {
+	if secret := os.Getenv("INVITE_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return appSigningKey()
+}
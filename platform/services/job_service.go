@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Job kinds
+const (
+	JobKindCreatePhotoAlbum = "google_photos.create_album"
+	JobKindDeliverWebhook   = "webhook.deliver"
+	JobKindSendAnnouncement = "announcement.send"
+	JobKindSendEmail        = "notification.send"
+	JobKindSendReminder     = "reminder.send"
+	JobKindSendInvitation   = "invitation.send"
+)
+
+const (
+	// defaultJobMaxAttempts is how often a job is tried before it is dead-lettered
+	defaultJobMaxAttempts = 8
+	// jobBackoffBase is the wait after the first failure; it doubles with every further one
+	jobBackoffBase = 30 * time.Second
+	// jobBackoffMax caps the wait between attempts
+	jobBackoffMax = 6 * time.Hour
+	// jobAttemptTimeout bounds a single attempt
+	jobAttemptTimeout = 5 * time.Minute
+	// jobLockTimeout is how long a job may stay running before it is assumed
+	// its worker died; well above jobAttemptTimeout so live attempts aren't stolen
+	jobLockTimeout = 15 * time.Minute
+)
+
+// ErrJobNotRetryable is returned when retrying a job that hasn't been dead-lettered
+var ErrJobNotRetryable = errors.New("job can't be retried")
+
+// JobHandler runs one attempt of a job. Returned errors are retried with
+// backoff unless wrapped with permanent.
+type JobHandler func(ctx context.Context, job *models.Job) error
+
+// jobHandlers maps each job kind to the code that runs it
+var jobHandlers = map[string]JobHandler{
+	JobKindCreatePhotoAlbum: createEventPhotoAlbum,
+	JobKindDeliverWebhook:   deliverWebhook,
+	JobKindSendAnnouncement: sendAnnouncement,
+	JobKindSendEmail:        sendEmail,
+	JobKindSendReminder:     sendReminder,
+	JobKindSendInvitation:   sendInvitation,
+}
+
+// JobOptions tunes how a job is queued
+type JobOptions struct {
+	EventID     *uuid.UUID
+	UniqueKey   string    // Skips queueing while an unfinished job has the same key
+	MaxAttempts int       // Defaults to defaultJobMaxAttempts
+	RunAt       time.Time // Defaults to now
+}
+
+// permanentJobError marks a failure that retrying can't fix
+type permanentJobError struct {
+	err error
+}
+
+func (e permanentJobError) Error() string { return e.err.Error() }
+func (e permanentJobError) Unwrap() error { return e.err }
+
+// permanent makes a job dead-letter on this error instead of retrying
+func permanent(err error) error {
+	return permanentJobError{err: err}
+}
+
+type JobService struct {
+	db *gorm.DB
+}
+
+// NewJobService creates a new job service
+func NewJobService() *JobService {
+	return &JobService{
+		db: database.GetDB(),
+	}
+}
+
+// Enqueue queues a job outside of any other transaction
+func (s *JobService) Enqueue(kind string, payload models.JobPayload, opts JobOptions) error {
+	return enqueueJob(s.db, kind, payload, opts)
+}
+
+// enqueueJob queues a job in tx, so it only runs if the surrounding change
+// commits. A job whose unique key is already queued or running is dropped.
+func enqueueJob(tx *gorm.DB, kind string, payload models.JobPayload, opts JobOptions) error {
+	if _, ok := jobHandlers[kind]; !ok {
+		return fmt.Errorf("unknown job kind %q", kind)
+	}
+
+	job := models.Job{
+		Kind:        kind,
+		Payload:     payload,
+		EventID:     opts.EventID,
+		UniqueKey:   opts.UniqueKey,
+		Status:      models.JobStatusQueued,
+		RunAt:       opts.RunAt,
+		MaxAttempts: opts.MaxAttempts,
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = defaultJobMaxAttempts
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&job).Error
+}
+
+// Run works through due jobs with the given number of workers until the
+// context is done, polling every interval when the queue is empty. Every app
+// instance may run it: jobs are claimed with SKIP LOCKED, so each attempt is
+// made by exactly one worker, and jobs left running by an instance that died
+// are put back in the queue after jobLockTimeout.
+func (s *JobService) Run(ctx context.Context, workers int, interval time.Duration) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx, interval)
+		}()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RequeueStale(); err != nil {
+			log.Printf("Error requeueing stale jobs: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// work runs jobs back to back while there are due ones
+func (s *JobService) work(ctx context.Context, interval time.Duration) {
+	for ctx.Err() == nil {
+		ran, err := s.RunNext(ctx)
+		if err != nil {
+			log.Printf("Error running job: %v", err)
+		}
+		if ran && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+// RunNext claims the longest-due job and runs one attempt of it, reporting
+// whether there was one
+func (s *JobService) RunNext(ctx context.Context) (bool, error) {
+	job, err := s.claim()
+	if err != nil || job == nil {
+		return false, err
+	}
+	return true, s.finish(job, s.attempt(ctx, job))
+}
+
+// claim locks the next due job and marks it running in one transaction, so
+// concurrent workers skip past it instead of waiting
+func (s *JobService) claim() (*models.Job, error) {
+	var jobs []models.Job
+	now := time.Now()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ?", models.JobStatusQueued, now).
+			Order("run_at ASC").Limit(1).Find(&jobs).Error
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+
+		return tx.Model(&jobs[0]).Updates(map[string]interface{}{
+			"status":    models.JobStatusRunning,
+			"attempts":  gorm.Expr("attempts + 1"),
+			"locked_at": now,
+		}).Error
+	})
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+
+	job := &jobs[0]
+	job.Status = models.JobStatusRunning
+	job.Attempts++
+	job.LockedAt = &now
+	return job, nil
+}
+
+// attempt runs a claimed job's handler, turning panics into failures
+func (s *JobService) attempt(ctx context.Context, job *models.Job) (err error) {
+	handler, ok := jobHandlers[job.Kind]
+	if !ok {
+		return permanent(fmt.Errorf("no handler for job kind %q", job.Kind))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, jobAttemptTimeout)
+	defer cancel()
+	return handler(ctx, job)
+}
+
+// finish records the outcome of an attempt: success, a retry after backoff,
+// or dead-lettering once the attempts are used up or the error is permanent.
+// An attempt that outlived its lock and was requeued is left alone.
+func (s *JobService) finish(job *models.Job, jobErr error) error {
+	now := time.Now()
+	updates := map[string]interface{}{"locked_at": nil}
+
+	var permanentErr permanentJobError
+	switch {
+	case jobErr == nil:
+		updates["status"] = models.JobStatusSucceeded
+		updates["finished_at"] = now
+	case errors.As(jobErr, &permanentErr) || job.Attempts >= job.MaxAttempts:
+		log.Printf("Job %v (%s) failed for good after %d attempts: %v", job.ID, job.Kind, job.Attempts, jobErr)
+		updates["status"] = models.JobStatusDead
+		updates["finished_at"] = now
+		updates["last_error"] = jobErr.Error()
+	default:
+		updates["status"] = models.JobStatusQueued
+		updates["run_at"] = now.Add(jobBackoff(job.Attempts))
+		updates["last_error"] = jobErr.Error()
+	}
+
+	return s.db.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND attempts = ?", job.ID, models.JobStatusRunning, job.Attempts).
+		Updates(updates).Error
+}
+
+// RequeueStale puts jobs whose worker stopped responding back in the queue,
+// counting the lost attempt, and dead-letters those that are out of attempts
+func (s *JobService) RequeueStale() error {
+	cutoff := time.Now().Add(-jobLockTimeout)
+	stale := s.db.Model(&models.Job{}).Where("status = ? AND locked_at < ?", models.JobStatusRunning, cutoff)
+
+	err := stale.Session(&gorm.Session{}).Where("attempts >= max_attempts").Updates(map[string]interface{}{
+		"status":      models.JobStatusDead,
+		"locked_at":   nil,
+		"finished_at": time.Now(),
+		"last_error":  "worker stopped responding",
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	return stale.Session(&gorm.Session{}).Updates(map[string]interface{}{
+		"status":     models.JobStatusQueued,
+		"locked_at":  nil,
+		"run_at":     time.Now(),
+		"last_error": "worker stopped responding",
+	}).Error
+}
+
+// GetJobs lists an event's jobs, newest first
+func (s *JobService) GetJobs(eventID uuid.UUID) ([]models.Job, error) {
+	var jobs []models.Job
+	err := s.db.Where("event_id = ?", eventID).Order("created_at DESC").Find(&jobs).Error
+	return jobs, err
+}
+
+// RetryJob puts a dead job of an event back in the queue with fresh attempts
+func (s *JobService) RetryJob(eventID, jobID uuid.UUID) (*models.Job, error) {
+	var job models.Job
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&job, "id = ? AND event_id = ?", jobID, eventID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("job not found")
+		}
+		if err != nil {
+			return err
+		}
+		if job.Status != models.JobStatusDead {
+			return fmt.Errorf("%w: only dead jobs can be retried", ErrJobNotRetryable)
+		}
+
+		if job.UniqueKey != "" {
+			// A newer job with the same key makes this one redundant
+			var active int64
+			err := tx.Model(&models.Job{}).
+				Where("unique_key = ? AND finished_at IS NULL", job.UniqueKey).Count(&active).Error
+			if err != nil {
+				return err
+			}
+			if active > 0 {
+				return fmt.Errorf("%w: a job with the same key is already queued", ErrJobNotRetryable)
+			}
+		}
+
+		err = tx.Model(&job).Updates(map[string]interface{}{
+			"status":      models.JobStatusQueued,
+			"attempts":    0,
+			"run_at":      time.Now(),
+			"finished_at": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.First(&job, "id = ?", job.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// jobBackoff is the wait before the next attempt after the given number of
+// failed ones, doubling each time with up to 10% jitter so jobs that failed
+// together don't retry in lockstep
+func jobBackoff(attempts int) time.Duration {
+	delay := jobBackoffMax
+	if attempts <= 20 {
+		delay = min(jobBackoffBase<<(attempts-1), jobBackoffMax)
+	}
+	return delay + rand.N(delay/10+1)
+}
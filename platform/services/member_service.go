@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrOwnerMembership is returned when adding, changing or removing the owner
+// directly instead of transferring ownership
+var ErrOwnerMembership = errors.New("the owner can only change through an ownership transfer")
+
+type MemberService struct {
+	db *gorm.DB
+}
+
+// NewMemberService creates a new member service
+func NewMemberService() *MemberService {
+	return &MemberService{
+		db: database.GetDB(),
+	}
+}
+
+// GetRole returns the user's role on an event, or "" if they aren't a member
+func (s *MemberService) GetRole(eventID, userID uuid.UUID) (models.EventRole, error) {
+	return getEventRole(s.db, eventID, userID)
+}
+
+// GetMembers lists an event's members, owner first
+func (s *MemberService) GetMembers(eventID uuid.UUID) ([]models.EventMember, error) {
+	var members []models.EventMember
+	err := s.db.Preload("User").Where("event_id = ?", eventID).
+		Order("CASE role WHEN 'owner' THEN 0 WHEN 'co_host' THEN 1 ELSE 2 END, created_at ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// SetMember adds a user to an event with a co-host or check-in staff role,
+// or changes the role of an existing member
+func (s *MemberService) SetMember(eventID, userID uuid.UUID, role models.EventRole, addedByID uuid.UUID) (*models.EventMember, error) {
+	if role == models.EventRoleOwner {
+		return nil, ErrOwnerMembership
+	}
+	if !role.IsValid() {
+		return nil, ValidationErrors{"role": "must be co_host or checkin_staff"}
+	}
+
+	var member models.EventMember
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockEvent(tx, eventID); err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ValidationErrors{"user": "not found"}
+			}
+			return err
+		}
+
+		err := tx.Where("event_id = ? AND user_id = ?", eventID, userID).First(&member).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			member = models.EventMember{EventID: eventID, UserID: userID, Role: role, AddedByID: &addedByID}
+			return tx.Create(&member).Error
+		case err != nil:
+			return err
+		case member.Role == models.EventRoleOwner:
+			return ErrOwnerMembership
+		default:
+			member.Role = role
+			return tx.Save(&member).Error
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Preload("User").First(&member, "id = ?", member.ID).Error
+	return &member, err
+}
+
+// RemoveMember takes a member's role on an event away
+func (s *MemberService) RemoveMember(eventID, userID uuid.UUID) error {
+	role, err := s.GetRole(eventID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return errors.New("member not found")
+	}
+	if role == models.EventRoleOwner {
+		return ErrOwnerMembership
+	}
+
+	return s.db.Where("event_id = ? AND user_id = ? AND role <> ?", eventID, userID, models.EventRoleOwner).
+		Delete(&models.EventMember{}).Error
+}
+
+// TransferOwnership makes another user the owner of an event. The previous
+// owner stays on as a co-host.
+func (s *MemberService) TransferOwnership(eventID, newOwnerID, actorID uuid.UUID) (*models.Event, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		event, err := lockEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+		if event.UserID == newOwnerID {
+			return nil
+		}
+
+		var user models.User
+		if err := tx.First(&user, "id = ?", newOwnerID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ValidationErrors{"user": "not found"}
+			}
+			return err
+		}
+
+		err = tx.Model(&models.EventMember{}).
+			Where("event_id = ? AND role = ?", eventID, models.EventRoleOwner).
+			Update("role", models.EventRoleCoHost).Error
+		if err != nil {
+			return err
+		}
+
+		owner := models.EventMember{EventID: eventID, UserID: newOwnerID, Role: models.EventRoleOwner, AddedByID: &actorID}
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "event_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+		}).Create(&owner).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Event{}).Where("id = ?", eventID).Update("user_id", newOwnerID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var event models.Event
+	err = s.db.Preload("User").First(&event, "id = ?", eventID).Error
+	return &event, err
+}
+
+// BackfillOwners gives events created before per-event roles their organizer
+// as owner member. It is safe to run on every start.
+func (s *MemberService) BackfillOwners() error {
+	return s.db.Exec(`
+		INSERT INTO event_members (id, event_id, user_id, role, created_at, updated_at)
+		SELECT gen_random_uuid(), events.id, events.user_id, ?, NOW(), NOW()
+		FROM events
+		WHERE NOT EXISTS (SELECT 1 FROM event_members WHERE event_members.event_id = events.id AND event_members.role = ?)
+		ON CONFLICT (event_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		models.EventRoleOwner, models.EventRoleOwner).Error
+}
+
+func getEventRole(db *gorm.DB, eventID, userID uuid.UUID) (models.EventRole, error) {
+	var member models.EventMember
+	err := db.Select("role").Where("event_id = ? AND user_id = ?", eventID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
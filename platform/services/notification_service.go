@@ -0,0 +1,495 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+	"01-Login/platform/notifications"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidUnsubscribe is returned for unsubscribe tokens that are forged or malformed
+var ErrInvalidUnsubscribe = errors.New("unsubscribe link is invalid")
+
+// unsubscribeAll stands for every category in unsubscribe tokens
+const unsubscribeAll = "all"
+
+type NotificationService struct {
+	db     *gorm.DB
+	mailer *notifications.Mailer
+}
+
+// NewNotificationService creates a new notification service sending through the default mailer
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		db:     database.GetDB(),
+		mailer: notifications.Default(),
+	}
+}
+
+// NotificationSettings is a user's email preferences, with every category listed
+type NotificationSettings struct {
+	EmailEnabled bool                                 `json:"email_enabled"`
+	Categories   map[models.NotificationCategory]bool `json:"categories"`
+}
+
+// NotificationSettingsUpdate changes the preferences that were sent
+type NotificationSettingsUpdate struct {
+	EmailEnabled *bool                                `json:"email_enabled"`
+	Categories   map[models.NotificationCategory]bool `json:"categories"`
+}
+
+// GetSettings returns a user's email preferences
+func (s *NotificationService) GetSettings(userID uuid.UUID) (*NotificationSettings, error) {
+	preference, err := s.getPreference(userID)
+	if err != nil {
+		return nil, err
+	}
+	return settingsFrom(preference), nil
+}
+
+// UpdateSettings changes a user's email preferences
+func (s *NotificationService) UpdateSettings(userID uuid.UUID, update *NotificationSettingsUpdate) (*NotificationSettings, error) {
+	for category := range update.Categories {
+		if !category.IsValid() {
+			return nil, ValidationErrors{"categories": "unknown category " + string(category)}
+		}
+	}
+
+	preference, err := s.getPreference(userID)
+	if err != nil {
+		return nil, err
+	}
+	if update.EmailEnabled != nil {
+		preference.EmailDisabled = !*update.EmailEnabled
+	}
+	for category, enabled := range update.Categories {
+		preference.DisabledCategories = setCategory(preference.DisabledCategories, category, enabled)
+	}
+
+	if err := s.savePreference(preference); err != nil {
+		return nil, err
+	}
+	return settingsFrom(preference), nil
+}
+
+// UnsubscribeToken signs a link that turns off one category of email for a
+// user, or all email when category is empty
+func (s *NotificationService) UnsubscribeToken(userID uuid.UUID, category models.NotificationCategory) string {
+	scope := string(category)
+	if scope == "" {
+		scope = unsubscribeAll
+	}
+	return signToken(appSigningKey(), "u:"+userID.String()+":"+scope)
+}
+
+// Unsubscribe turns off the email an unsubscribe token is for. It returns the
+// category, empty when the user unsubscribed from all email.
+func (s *NotificationService) Unsubscribe(token string) (models.NotificationCategory, error) {
+	userID, category, err := parseUnsubscribeToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	preference, err := s.getPreference(userID)
+	if err != nil {
+		return "", err
+	}
+	if category == "" {
+		preference.EmailDisabled = true
+	} else {
+		preference.DisabledCategories = setCategory(preference.DisabledCategories, category, false)
+	}
+
+	return category, s.savePreference(preference)
+}
+
+// UnsubscribeCategory checks an unsubscribe token without using it, returning
+// the category it turns off, empty for all email
+func (s *NotificationService) UnsubscribeCategory(token string) (models.NotificationCategory, error) {
+	_, category, err := parseUnsubscribeToken(token)
+	return category, err
+}
+
+// parseUnsubscribeToken verifies an unsubscribe token and returns the user and
+// category it is for
+func parseUnsubscribeToken(token string) (uuid.UUID, models.NotificationCategory, error) {
+	payload, ok := verifyToken(appSigningKey(), token)
+	if !ok {
+		return uuid.Nil, "", ErrInvalidUnsubscribe
+	}
+	parts := strings.Split(payload, ":")
+	if len(parts) != 3 || parts[0] != "u" {
+		return uuid.Nil, "", ErrInvalidUnsubscribe
+	}
+	userID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return uuid.Nil, "", ErrInvalidUnsubscribe
+	}
+
+	if parts[2] == unsubscribeAll {
+		return userID, "", nil
+	}
+	category := models.NotificationCategory(parts[2])
+	if !category.IsValid() {
+		return uuid.Nil, "", ErrInvalidUnsubscribe
+	}
+	return userID, category, nil
+}
+
+// NotifyRSVP queues a confirmation of a guest's RSVP to them within tx
+func (s *NotificationService) NotifyRSVP(tx *gorm.DB, rsvp *models.RSVP) error {
+	return queueEmails(tx, []uuid.UUID{rsvp.UserID}, rsvp.EventID, models.NotificationRSVPConfirmations,
+		notifications.TemplateRSVPConfirmation, models.EmailContent{RSVPID: &rsvp.ID})
+}
+
+// NotifyEventUpdated queues telling guests who are coming, might come or are
+// waiting for a seat what changed about a published event. Changes guests
+// don't see, such as capacity, send nothing.
+func (s *NotificationService) NotifyEventUpdated(tx *gorm.DB, before, after *models.Event) error {
+	if after.Status != models.EventStatusPublished {
+		return nil
+	}
+	changes := describeEventChanges(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	guestIDs, err := eventGuestIDs(tx, after.ID, false)
+	if err != nil {
+		return err
+	}
+	content := models.EmailContent{Changes: make([]models.EmailChange, 0, len(changes))}
+	for _, change := range changes {
+		content.Changes = append(content.Changes, models.EmailChange{Field: change.Field, From: change.From, To: change.To})
+	}
+	return queueEmails(tx, guestIDs, after.ID, models.NotificationEventUpdates, notifications.TemplateEventUpdated, content)
+}
+
+// NotifyEventCancelled queues telling the guests of a cancelled event,
+// passing on the organizer's reason
+func (s *NotificationService) NotifyEventCancelled(tx *gorm.DB, eventID uuid.UUID, reason string) error {
+	guestIDs, err := eventGuestIDs(tx, eventID, true)
+	if err != nil {
+		return err
+	}
+	return queueEmails(tx, guestIDs, eventID, models.NotificationEventCancellations,
+		notifications.TemplateEventCancelled, models.EmailContent{Reason: reason})
+}
+
+// NotifyMentions queues telling the users mentioned in a comment about it
+func (s *NotificationService) NotifyMentions(tx *gorm.DB, comment *models.EventComment, userIDs []uuid.UUID) error {
+	return queueEmails(tx, userIDs, comment.EventID, models.NotificationCommentMentions,
+		notifications.TemplateCommentMention, models.EmailContent{CommentID: &comment.ID})
+}
+
+// NotifyDatePollFinalized queues telling the users who voted in an event's
+// date poll which date was picked
+func (s *NotificationService) NotifyDatePollFinalized(tx *gorm.DB, eventID uuid.UUID, userIDs []uuid.UUID) error {
+	return queueEmails(tx, userIDs, eventID, models.NotificationEventUpdates,
+		notifications.TemplateDatePollFinalized, models.EmailContent{})
+}
+
+// DeliverAnnouncement emails an announcement to one guest, reporting
+// whether it was sent or skipped because of the guest's preferences
+func (s *NotificationService) DeliverAnnouncement(user *models.User, event *models.Event, announcement *models.EventAnnouncement) (bool, error) {
+	return s.deliver(user, models.NotificationAnnouncements, notifications.TemplateAnnouncement, &notifications.Data{
+		Event:        event,
+		When:         formatEventTime(event, event.EventDate),
+		Announcement: announcement,
+	})
+}
+
+// queueEmails records an email to each user and queues a job sending it,
+// within tx so nothing is sent unless the change it is about commits
+func queueEmails(tx *gorm.DB, userIDs []uuid.UUID, eventID uuid.UUID, category models.NotificationCategory, template notifications.Template, content models.EmailContent) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	emails := make([]models.EmailNotification, 0, len(userIDs))
+	for _, userID := range userIDs {
+		emails = append(emails, models.EmailNotification{
+			UserID:   userID,
+			EventID:  eventID,
+			Category: category,
+			Template: string(template),
+			Content:  content,
+			Status:   models.EmailNotificationPending,
+		})
+	}
+	if err := tx.Create(&emails).Error; err != nil {
+		return err
+	}
+
+	for _, email := range emails {
+		err := enqueueJob(tx, JobKindSendEmail, models.JobPayload{"email_id": email.ID.String()}, JobOptions{
+			EventID:   &eventID,
+			UniqueKey: JobKindSendEmail + ":" + email.ID.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendEmail is the job that sends one queued notification email, recording
+// how it went. Failures are retried with the job.
+func sendEmail(ctx context.Context, job *models.Job) error {
+	db := database.GetDB()
+
+	emailID, err := uuid.Parse(fmt.Sprint(job.Payload["email_id"]))
+	if err != nil {
+		return permanent(fmt.Errorf("job has no email: %w", err))
+	}
+	var email models.EmailNotification
+	if err := db.Preload("User").First(&email, "id = ?", emailID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if email.Status == models.EmailNotificationSent || email.Status == models.EmailNotificationSkipped {
+		return nil
+	}
+	return deliverEmail(db, &email)
+}
+
+// deliverEmail sends a recorded email and records how it went, returning
+// the delivery error to retry on
+func deliverEmail(db *gorm.DB, email *models.EmailNotification) error {
+	data, err := emailData(db, email)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"error": ""}
+	sent := false
+	if data != nil {
+		sent, err = NewNotificationService().deliver(&email.User, email.Category, notifications.Template(email.Template), data)
+	}
+	switch {
+	case err != nil:
+		updates["status"] = models.EmailNotificationFailed
+		updates["error"] = err.Error()
+	case sent:
+		updates["status"] = models.EmailNotificationSent
+		updates["sent_at"] = time.Now()
+	default:
+		updates["status"] = models.EmailNotificationSkipped
+	}
+	if updateErr := db.Model(email).Updates(updates).Error; updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+// emailData loads what a queued email shows. It returns nil when the event,
+// RSVP or comment it is about has been deleted since.
+func emailData(db *gorm.DB, email *models.EmailNotification) (*notifications.Data, error) {
+	var event models.Event
+	if err := db.First(&event, "id = ?", email.EventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	content := email.Content
+	data := &notifications.Data{
+		Event:    &event,
+		When:     content.When,
+		Reason:   content.Reason,
+		StartsIn: content.StartsIn,
+	}
+	for _, change := range content.Changes {
+		data.Changes = append(data.Changes, notifications.Change{Field: change.Field, From: change.From, To: change.To})
+	}
+
+	if content.RSVPID != nil {
+		var rsvp models.RSVP
+		if err := db.First(&rsvp, "id = ?", *content.RSVPID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		data.RSVP = &rsvp
+		if data.When == "" && rsvp.OccurrenceDate != nil {
+			data.When = formatEventTime(&event, *rsvp.OccurrenceDate)
+		}
+	}
+	if content.CommentID != nil {
+		var comment models.EventComment
+		err := db.Preload("Author").First(&comment, "id = ?", *content.CommentID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && comment.RemovedAt != nil) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data.Comment = &comment
+	}
+
+	if data.When == "" {
+		data.When = formatEventTime(&event, event.EventDate)
+	}
+	return data, nil
+}
+
+// deliver emails a user if their preferences allow the category, with links
+// to the event and to unsubscribe from the category. It reports whether the
+// email was sent; users without an address are skipped like opted-out ones.
+func (s *NotificationService) deliver(user *models.User, category models.NotificationCategory, template notifications.Template, data *notifications.Data) (bool, error) {
+	if user.Email == "" || !user.IsActive {
+		return false, nil
+	}
+
+	preference, err := s.getPreference(user.ID)
+	if err != nil {
+		return false, fmt.Errorf("loading notification preferences: %w", err)
+	}
+	if !preference.Allows(category) {
+		return false, nil
+	}
+
+	token := url.QueryEscape(s.UnsubscribeToken(user.ID, category))
+	data.RecipientName = user.Name
+	data.UnsubscribeURL = notifications.AppURL() + "/unsubscribe?token=" + token
+	if data.Event != nil {
+		data.EventURL = notifications.AppURL() + "/events/" + data.Event.ID.String()
+	}
+
+	headers := map[string]string{
+		// One-click unsubscribe from the mail client (RFC 8058)
+		"List-Unsubscribe":      "<" + notifications.AppURL() + "/api/notifications/unsubscribe?token=" + token + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	if err := s.mailer.Send(user.Email, template, data, headers); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// eventGuestIDs returns the users who answered yes or maybe to an event or
+// are on its waitlist, once each. Voided RSVPs only count when includeVoided
+// is set, for telling guests about the cancellation that voided them.
+func eventGuestIDs(tx *gorm.DB, eventID uuid.UUID, includeVoided bool) ([]uuid.UUID, error) {
+	query := tx.Model(&models.RSVP{}).Distinct("user_id").
+		Where("event_id = ? AND response IN ?", eventID, []models.RSVPResponse{
+			models.RSVPResponseYes, models.RSVPResponseMaybe, models.RSVPResponseWaitlisted,
+		})
+	if !includeVoided {
+		query = query.Where("voided_at IS NULL")
+	}
+
+	var userIDs []uuid.UUID
+	err := query.Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// getPreference loads a user's preferences, defaulting to all email on
+func (s *NotificationService) getPreference(userID uuid.UUID) (*models.NotificationPreference, error) {
+	preference := models.NotificationPreference{UserID: userID}
+	err := s.db.First(&preference, "user_id = ?", userID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &preference, nil
+}
+
+func (s *NotificationService) savePreference(preference *models.NotificationPreference) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"email_disabled", "disabled_categories", "updated_at"}),
+	}).Create(preference).Error
+}
+
+func settingsFrom(preference *models.NotificationPreference) *NotificationSettings {
+	settings := &NotificationSettings{
+		EmailEnabled: !preference.EmailDisabled,
+		Categories:   make(map[models.NotificationCategory]bool, len(models.NotificationCategories)),
+	}
+	for _, category := range models.NotificationCategories {
+		settings.Categories[category] = true
+	}
+	for _, category := range preference.DisabledCategories {
+		settings.Categories[category] = false
+	}
+	return settings
+}
+
+// setCategory adds a category to or removes it from the disabled list
+func setCategory(disabled []models.NotificationCategory, category models.NotificationCategory, enabled bool) []models.NotificationCategory {
+	kept := make([]models.NotificationCategory, 0, len(disabled)+1)
+	for _, existing := range disabled {
+		if existing != category {
+			kept = append(kept, existing)
+		}
+	}
+	if !enabled {
+		kept = append(kept, category)
+	}
+	return kept
+}
+
+// describeEventChanges lists the changes guests care about, formatted in the event's time zone
+func describeEventChanges(before, after *models.Event) []notifications.Change {
+	var changes []notifications.Change
+	add := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, notifications.Change{Field: field, From: from, To: to})
+		}
+	}
+
+	add("Title", before.Title, after.Title)
+	add("When", formatEventTime(before, before.EventDate), formatEventTime(after, after.EventDate))
+	add("Ends", formatEventEnd(before), formatEventEnd(after))
+	add("Where", before.Venue, after.Venue)
+	return changes
+}
+
+// formatEventTime formats an instant for people in the event's time zone
+func formatEventTime(event *models.Event, t time.Time) string {
+	return t.In(event.Location()).Format("Monday, January 2, 2006 at 3:04 PM MST")
+}
+
+func formatEventEnd(event *models.Event) string {
+	if event.EndDate == nil {
+		return ""
+	}
+	return formatEventTime(event, *event.EndDate)
+}
+
+// formatOffset describes how long before the start a reminder goes out, e.g. "in 1 day"
+func formatOffset(minutes int) string {
+	units := []struct {
+		minutes int
+		name    string
+	}{
+		{7 * 24 * 60, "week"},
+		{24 * 60, "day"},
+		{60, "hour"},
+		{1, "minute"},
+	}
+	for _, unit := range units {
+		if minutes%unit.minutes == 0 {
+			count := minutes / unit.minutes
+			if count == 1 {
+				return "in 1 " + unit.name
+			}
+			return fmt.Sprintf("in %d %ss", count, unit.name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type QuestionService struct {
+	db *gorm.DB
+}
+
+// NewQuestionService creates a new question service
+func NewQuestionService() *QuestionService {
+	return &QuestionService{
+		db: database.GetDB(),
+	}
+}
+
+// QuestionSummary aggregates the answers to one choice or yes/no question
+type QuestionSummary struct {
+	QuestionID uuid.UUID           `json:"question_id"`
+	Prompt     string              `json:"prompt"`
+	Kind       models.QuestionKind `json:"kind"`
+	Counts     map[string]int64    `json:"counts"` // Number of guests per option
+	Answered   int64               `json:"answered"`
+}
+
+// GetEventQuestions retrieves an event's questions in display order
+func (s *QuestionService) GetEventQuestions(eventID uuid.UUID) ([]models.EventQuestion, error) {
+	return getEventQuestions(s.db, eventID)
+}
+
+// ReplaceEventQuestions validates and stores the full question schema of an
+// event. Questions sent with the ID of an existing question keep that ID, so
+// answers already given to it stay attached; questions left out are removed.
+func (s *QuestionService) ReplaceEventQuestions(eventID uuid.UUID, questions []models.EventQuestion) ([]models.EventQuestion, error) {
+	if errs := validateQuestions(questions); errs != nil {
+		return nil, errs
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		existing, err := getEventQuestions(tx, eventID)
+		if err != nil {
+			return err
+		}
+		kept := make(map[uuid.UUID]bool)
+		for _, q := range existing {
+			kept[q.ID] = false
+		}
+
+		for i := range questions {
+			q := &questions[i]
+			q.EventID = eventID
+			q.Position = i
+			q.Prompt = strings.TrimSpace(q.Prompt)
+			// Timestamps are the server's, whatever the client sent
+			q.CreatedAt, q.UpdatedAt = time.Time{}, time.Time{}
+			if _, ok := kept[q.ID]; ok {
+				kept[q.ID] = true
+				err := tx.Model(&models.EventQuestion{}).
+					Where("event_id = ? AND id = ?", eventID, q.ID).
+					Select("position", "kind", "prompt", "options", "required").
+					Updates(q).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			q.ID = uuid.Nil
+			if err := tx.Create(q).Error; err != nil {
+				return err
+			}
+		}
+
+		for id, stillThere := range kept {
+			if !stillThere {
+				if err := tx.Delete(&models.EventQuestion{}, "id = ?", id).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetEventQuestions(eventID)
+}
+
+// SummarizeAnswers counts the answers to the choice and yes/no questions across RSVPs
+func (s *QuestionService) SummarizeAnswers(questions []models.EventQuestion, rsvps []models.RSVP) []QuestionSummary {
+	summaries := make([]QuestionSummary, 0)
+
+	for _, q := range questions {
+		if !q.IsChoice() && q.Kind != models.QuestionKindBoolean {
+			continue
+		}
+
+		summary := QuestionSummary{
+			QuestionID: q.ID,
+			Prompt:     q.Prompt,
+			Kind:       q.Kind,
+			Counts:     make(map[string]int64),
+		}
+		if q.Kind == models.QuestionKindBoolean {
+			summary.Counts["true"] = 0
+			summary.Counts["false"] = 0
+		}
+		for _, option := range q.Options {
+			summary.Counts[option] = 0
+		}
+
+		for _, rsvp := range rsvps {
+			if rsvp.VoidedAt != nil {
+				continue
+			}
+			answer, ok := rsvp.Answers[q.ID.String()]
+			if !ok || answer == nil {
+				continue
+			}
+			summary.Answered++
+
+			switch value := answer.(type) {
+			case string:
+				summary.Counts[value]++
+			case bool:
+				summary.Counts[fmt.Sprint(value)]++
+			case []interface{}:
+				for _, choice := range value {
+					if option, ok := choice.(string); ok {
+						summary.Counts[option]++
+					}
+				}
+			}
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+func getEventQuestions(db *gorm.DB, eventID uuid.UUID) ([]models.EventQuestion, error) {
+	var questions []models.EventQuestion
+	err := db.Where("event_id = ?", eventID).Order("position ASC").Find(&questions).Error
+	return questions, err
+}
+
+// validateQuestions checks a question schema before it is stored
+func validateQuestions(questions []models.EventQuestion) ValidationErrors {
+	errs := ValidationErrors{}
+	ids := make(map[uuid.UUID]int)
+
+	for i, q := range questions {
+		field := fmt.Sprintf("questions[%d]", i)
+
+		if q.ID != uuid.Nil {
+			if first, ok := ids[q.ID]; ok {
+				errs[field+".id"] = fmt.Sprintf("repeats the ID of questions[%d]", first)
+			} else {
+				ids[q.ID] = i
+			}
+		}
+		if strings.TrimSpace(q.Prompt) == "" {
+			errs[field+".prompt"] = "must not be empty"
+		}
+
+		switch q.Kind {
+		case models.QuestionKindSingleChoice, models.QuestionKindMultiChoice:
+			if len(q.Options) < 2 {
+				errs[field+".options"] = "choice questions need at least two options"
+				continue
+			}
+			seen := make(map[string]bool)
+			for _, option := range q.Options {
+				if strings.TrimSpace(option) == "" {
+					errs[field+".options"] = "options must not be empty"
+					break
+				}
+				if seen[option] {
+					errs[field+".options"] = "options must be unique"
+					break
+				}
+				seen[option] = true
+			}
+		case models.QuestionKindText, models.QuestionKindNumber, models.QuestionKindBoolean:
+			if len(q.Options) > 0 {
+				errs[field+".options"] = "only choice questions have options"
+			}
+		default:
+			errs[field+".kind"] = "must be one of text, single_choice, multi_choice, number, boolean"
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateAnswers checks a guest's answers against the event's questions.
+// Required questions only have to be answered by guests who are coming.
+func validateAnswers(questions []models.EventQuestion, answers models.RSVPAnswers, attending bool) ValidationErrors {
+	errs := ValidationErrors{}
+
+	known := make(map[string]bool)
+	for _, q := range questions {
+		key := q.ID.String()
+		known[key] = true
+		field := "answers." + key
+
+		answer, ok := answers[key]
+		if !ok || answer == nil || answer == "" {
+			if q.Required && attending {
+				errs[field] = "is required"
+			}
+			continue
+		}
+
+		if msg := checkAnswer(&q, answer); msg != "" {
+			errs[field] = msg
+		}
+	}
+
+	for key := range answers {
+		if !known[key] {
+			errs["answers."+key] = "is not a question on this event"
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// checkAnswer returns why an answer doesn't fit its question, or "" if it does
+func checkAnswer(q *models.EventQuestion, answer interface{}) string {
+	switch q.Kind {
+	case models.QuestionKindText:
+		if _, ok := answer.(string); !ok {
+			return "must be text"
+		}
+	case models.QuestionKindNumber:
+		number, ok := answer.(float64)
+		if !ok || math.IsNaN(number) || math.IsInf(number, 0) {
+			return "must be a number"
+		}
+	case models.QuestionKindBoolean:
+		if _, ok := answer.(bool); !ok {
+			return "must be true or false"
+		}
+	case models.QuestionKindSingleChoice:
+		choice, ok := answer.(string)
+		if !ok || !hasOption(q, choice) {
+			return "must be one of the options"
+		}
+	case models.QuestionKindMultiChoice:
+		choices, ok := answer.([]interface{})
+		if !ok {
+			return "must be a list of options"
+		}
+		seen := make(map[string]bool)
+		for _, c := range choices {
+			choice, ok := c.(string)
+			if !ok || !hasOption(q, choice) {
+				return "must only contain the options"
+			}
+			if seen[choice] {
+				return "must not repeat an option"
+			}
+			seen[choice] = true
+		}
+	}
+	return ""
+}
+
+func hasOption(q *models.EventQuestion, choice string) bool {
+	for _, option := range q.Options {
+		if option == choice {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+	"01-Login/platform/notifications"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// reminderHorizon is how far past the largest offset recurring events are
+	// searched for their next occurrence
+	reminderHorizon = 400 * 24 * time.Hour
+	// reminderBatchSize caps the reminders one scheduler tick claims
+	reminderBatchSize = 50
+	// reminderRefillInterval is how often recurring events without a pending
+	// reminder get their next one scheduled
+	reminderRefillInterval = time.Hour
+)
+
+type ReminderService struct {
+	db           *gorm.DB
+	eventService *EventService
+}
+
+// NewReminderService creates a new reminder service
+func NewReminderService() *ReminderService {
+	return &ReminderService{
+		db:           database.GetDB(),
+		eventService: NewEventService(),
+	}
+}
+
+// Run queues the jobs sending due reminders every interval until the context
+// is done. Every app instance may run it: reminders are claimed with SKIP
+// LOCKED and marked queued in the same transaction as their job, so each is
+// queued exactly once, and pending reminders live in the database so restarts
+// pick up where they left off.
+func (s *ReminderService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastRefill time.Time
+	for {
+		if time.Since(lastRefill) >= reminderRefillInterval {
+			if err := s.RefillRecurring(); err != nil {
+				log.Printf("Error scheduling recurring event reminders: %v", err)
+			}
+			lastRefill = time.Now()
+		}
+		if _, err := s.QueueDueReminders(); err != nil {
+			log.Printf("Error queueing event reminders: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// QueueDueReminders claims the reminders that are due and queues a job
+// sending each, returning how many were queued
+func (s *ReminderService) QueueDueReminders() (int, error) {
+	var due []models.EventReminder
+	now := time.Now()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL AND queued_at IS NULL AND send_at <= ?", now).
+			Order("send_at ASC").Limit(reminderBatchSize).Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(due))
+		for i := range due {
+			ids[i] = due[i].ID
+			err := enqueueJob(tx, JobKindSendReminder, models.JobPayload{"reminder_id": due[i].ID.String()}, JobOptions{
+				EventID:   &due[i].EventID,
+				UniqueKey: JobKindSendReminder + ":" + due[i].ID.String(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.EventReminder{}).Where("id IN ?", ids).Update("queued_at", now).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(due), nil
+}
+
+// RefillRecurring schedules the next reminders of published recurring events
+// that have none pending, such as yearly events whose next occurrence was
+// beyond the horizon when they were last scheduled
+func (s *ReminderService) RefillRecurring() error {
+	var ids []uuid.UUID
+	err := s.db.Model(&models.Event{}).
+		Where("status = ? AND recurrence_rule <> ''", models.EventStatusPublished).
+		Where("reminder_offsets IS NOT NULL AND jsonb_array_length(reminder_offsets) > 0").
+		Where("NOT EXISTS (SELECT 1 FROM event_reminders WHERE event_reminders.event_id = events.id AND event_reminders.sent_at IS NULL AND event_reminders.queued_at IS NULL)").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := s.eventService.RescheduleReminders(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendReminder is the job that emails a reminder to the guests who said yes
+// or maybe, unless the event changed in a way that makes it moot. Each guest
+// gets an email record the first time the job runs; failed ones are tried
+// again with the job, and the reminder is marked sent once none is left.
+func sendReminder(ctx context.Context, job *models.Job) error {
+	db := database.GetDB()
+	events := NewEventService()
+
+	reminderID, err := uuid.Parse(fmt.Sprint(job.Payload["reminder_id"]))
+	if err != nil {
+		return permanent(fmt.Errorf("job has no reminder: %w", err))
+	}
+	var reminder models.EventReminder
+	if err := db.First(&reminder, "id = ?", reminderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if reminder.SentAt != nil {
+		return nil
+	}
+
+	event, err := events.GetEventByID(reminder.EventID)
+	if err != nil {
+		// Deleted since the reminder was scheduled
+		return nil
+	}
+
+	occurrence, moot := reminderOccurrence(events, event, &reminder)
+	if !moot {
+		if err := recordReminderEmails(db, &reminder, occurrence); err != nil {
+			return err
+		}
+
+		var emails []models.EmailNotification
+		err := db.Preload("User").Where("reminder_id = ? AND status IN ?", reminder.ID,
+			[]models.EmailNotificationStatus{models.EmailNotificationPending, models.EmailNotificationFailed}).
+			Find(&emails).Error
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for i := range emails {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := deliverEmail(db, &emails[i]); err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d reminder emails failed", failed, len(emails))
+		}
+	}
+
+	if err := db.Model(&reminder).Update("sent_at", time.Now()).Error; err != nil {
+		return err
+	}
+	if event.IsRecurring() {
+		// Line up the next occurrence's reminder now that this one is used
+		return events.RescheduleReminders(event.ID)
+	}
+	return nil
+}
+
+// reminderOccurrence returns the event or occurrence a reminder is for, and
+// whether the reminder is moot because the event is no longer published, has
+// started, or was moved or excluded since
+func reminderOccurrence(events *EventService, event *models.Event, reminder *models.EventReminder) (*models.Event, bool) {
+	if event.Status != models.EventStatusPublished || !time.Now().Before(reminder.StartsAt) {
+		return nil, true
+	}
+
+	occurrence := event
+	if reminder.OccurrenceDate != nil {
+		var err error
+		if occurrence, err = events.GetOccurrence(event, *reminder.OccurrenceDate); err != nil {
+			// The occurrence was excluded from the series
+			return nil, true
+		}
+	}
+	if !occurrence.EventDate.Equal(reminder.StartsAt) {
+		// Moved since; the reschedule made a reminder for the new time
+		return nil, true
+	}
+	return occurrence, false
+}
+
+// recordReminderEmails records an email to each guest who said yes or maybe
+// to the reminded occurrence, unless an earlier attempt already did
+func recordReminderEmails(db *gorm.DB, reminder *models.EventReminder, occurrence *models.Event) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var locked models.EventReminder
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", reminder.ID).Error
+		if err != nil {
+			return err
+		}
+		var recorded int64
+		if err := tx.Model(&models.EmailNotification{}).Where("reminder_id = ?", reminder.ID).Count(&recorded).Error; err != nil {
+			return err
+		}
+		if recorded > 0 {
+			return nil
+		}
+
+		var rsvps []models.RSVP
+		err = forOccurrence(tx, reminder.OccurrenceDate).
+			Where("event_id = ? AND response IN ? AND voided_at IS NULL", reminder.EventID,
+				[]models.RSVPResponse{models.RSVPResponseYes, models.RSVPResponseMaybe}).
+			Find(&rsvps).Error
+		if err != nil || len(rsvps) == 0 {
+			return err
+		}
+
+		emails := make([]models.EmailNotification, 0, len(rsvps))
+		for i := range rsvps {
+			emails = append(emails, models.EmailNotification{
+				UserID:     rsvps[i].UserID,
+				EventID:    reminder.EventID,
+				ReminderID: &reminder.ID,
+				Category:   models.NotificationEventReminders,
+				Template:   string(notifications.TemplateEventReminder),
+				Content: models.EmailContent{
+					When:     formatEventTime(occurrence, occurrence.EventDate),
+					RSVPID:   &rsvps[i].ID,
+					StartsIn: formatOffset(reminder.OffsetMinutes),
+				},
+				Status: models.EmailNotificationPending,
+			})
+		}
+		return tx.Create(&emails).Error
+	})
+}
+
+// RescheduleReminders replaces an event's pending reminders with ones for its current schedule
+func (s *EventService) RescheduleReminders(eventID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		event, err := lockEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+		return s.rescheduleReminders(tx, event)
+	})
+}
+
+// rescheduleReminders replaces an event's pending reminders with the next one
+// per offset for its current schedule. Queued and sent reminders are kept, so
+// a start time that was already reminded of isn't reminded of again, while a
+// moved start gets reminders of its own; the job of a queued reminder checks
+// the schedule again before sending. Offsets whose time has already passed
+// are skipped rather than sent late.
+func (s *EventService) rescheduleReminders(tx *gorm.DB, event *models.Event) error {
+	if err := tx.Where("event_id = ? AND sent_at IS NULL AND queued_at IS NULL", event.ID).Delete(&models.EventReminder{}).Error; err != nil {
+		return err
+	}
+	if len(event.ReminderOffsets) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	occurrences := []models.Event{*event}
+	if event.IsRecurring() {
+		// Offsets are stored largest first
+		largest := time.Duration(event.ReminderOffsets[0]) * time.Minute
+
+		var err error
+		occurrences, err = s.ExpandOccurrences([]models.Event{*event}, now, now.Add(largest+reminderHorizon))
+		if err != nil {
+			return err
+		}
+	}
+
+	var reminders []models.EventReminder
+	for _, offset := range event.ReminderOffsets {
+		before := time.Duration(offset) * time.Minute
+		for _, occurrence := range occurrences {
+			sendAt := occurrence.EventDate.Add(-before)
+			if !sendAt.After(now) {
+				continue
+			}
+
+			reminder := models.EventReminder{
+				EventID:       event.ID,
+				StartsAt:      occurrence.EventDate.UTC(),
+				OffsetMinutes: offset,
+				SendAt:        sendAt.UTC(),
+			}
+			if occurrence.OccurrenceDate != nil {
+				occurrenceDate := occurrence.OccurrenceDate.UTC()
+				reminder.OccurrenceDate = &occurrenceDate
+			}
+			reminders = append(reminders, reminder)
+			break
+		}
+	}
+	if len(reminders) == 0 {
+		return nil
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reminders).Error
+}
+
+// GetReminders lists an event's reminders, sent and pending, in the order they go out
+func (s *EventService) GetReminders(eventID uuid.UUID) ([]models.EventReminder, error) {
+	var reminders []models.EventReminder
+	err := s.db.Where("event_id = ?", eventID).Order("send_at ASC").Find(&reminders).Error
+	return reminders, err
+}
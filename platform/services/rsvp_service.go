@@ -2,62 +2,327 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"01-Login/platform/database"
 	"01-Login/platform/models"
+	"01-Login/platform/realtime"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type RSVPService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	notifications *NotificationService
 }
 
 func NewRSVPService() *RSVPService {
 	return &RSVPService{
-		db: database.GetDB(),
+		db:            database.GetDB(),
+		notifications: NewNotificationService(),
 	}
 }
 
-// CreateOrUpdateRSVP creates a new RSVP or updates existing one
-func (s *RSVPService) CreateOrUpdateRSVP(userID, eventID uuid.UUID, response models.RSVPResponse) (*models.RSVP, error) {
+// ErrEventNotOpen is returned when RSVPing to an event that isn't
+// published: a draft, one a moderator took down, or a cancelled one
+var ErrEventNotOpen = errors.New("this event is not taking RSVPs")
+
+// ErrNotEnoughSeats is returned when a confirmed guest grows their party beyond the seats left
+var ErrNotEnoughSeats = errors.New("not enough seats left for this party size")
+
+// RSVPSubmission is what a guest sends when responding to an event
+type RSVPSubmission struct {
+	Response   models.RSVPResponse
+	GuestCount int      // People in the party including the guest; 0 means 1
+	Companions []string // Optional names of the other people in the party
+	Answers    models.RSVPAnswers
+	// OccurrenceDate picks the occurrence of a recurring event; nil for one-off events
+	OccurrenceDate *time.Time
+}
+
+// validate checks the party against the event's plus-one limit
+func (sub *RSVPSubmission) validate(event *models.Event) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if sub.GuestCount < 1 {
+		errs["guest_count"] = "must be at least 1"
+	} else if sub.GuestCount-1 > event.MaxPlusOnes {
+		errs["guest_count"] = fmt.Sprintf("this event allows at most %d plus-ones", event.MaxPlusOnes)
+	}
+	if len(sub.Companions) > sub.GuestCount-1 {
+		errs["companions"] = "cannot name more companions than plus-ones"
+	}
+	for _, name := range sub.Companions {
+		if strings.TrimSpace(name) == "" {
+			errs["companions"] = "names must not be empty"
+			break
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// CreateOrUpdateRSVP creates a new RSVP or updates existing one. Capacity is
+// counted in people: a "yes" whose party doesn't fit in the seats left goes
+// onto the waitlist, and seats freed by confirmed guests are handed to the
+// waitlist in order.
+func (s *RSVPService) CreateOrUpdateRSVP(userID, eventID uuid.UUID, submission RSVPSubmission) (*models.RSVP, error) {
 	var rsvp models.RSVP
 
-	// Check if RSVP already exists
-	err := s.db.Where("user_id = ? AND event_id = ?", userID, eventID).First(&rsvp).Error
+	response := submission.Response
+	if submission.GuestCount == 0 {
+		submission.GuestCount = 1
+	}
+	if response == models.RSVPResponseNo {
+		// Nobody is coming, so the party doesn't matter
+		submission.GuestCount = 1
+		submission.Companions = nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the event so concurrent RSVPs are checked against its capacity one at a time
+		event, err := lockEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+		if event.Status != models.EventStatusPublished {
+			return ErrEventNotOpen
+		}
+
+		occurrence := submission.OccurrenceDate
+		questions, err := getEventQuestions(tx, eventID)
+		if err != nil {
+			return err
+		}
+		errs := submission.validate(event).
+			merge(checkOccurrence(event, occurrence)).
+			merge(validateAnswers(questions, submission.Answers, response != models.RSVPResponseNo))
+		if errs != nil {
+			return errs
+		}
+
+		// Check if RSVP already exists
+		err = forOccurrence(tx.Where("user_id = ? AND event_id = ?", userID, eventID), occurrence).First(&rsvp).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		isNew := errors.Is(err, gorm.ErrRecordNotFound)
+		previous := rsvp.Response
+		previousParty := rsvp.GuestCount
+
+		if response == models.RSVPResponseYes {
+			switch previous {
+			case models.RSVPResponseYes:
+				// Already confirmed; the seats they hold count towards the new party size
+				ok, err := hasSeats(tx, event, occurrence, submission.GuestCount-rsvp.GuestCount)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return ErrNotEnoughSeats
+				}
+			case models.RSVPResponseWaitlisted:
+				// Keep their place in the queue
+				response = models.RSVPResponseWaitlisted
+			default:
+				ok, err := hasSeats(tx, event, occurrence, submission.GuestCount)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					now := time.Now()
+					response = models.RSVPResponseWaitlisted
+					rsvp.WaitlistedAt = &now
+				}
+			}
+		}
+		if response != models.RSVPResponseWaitlisted {
+			rsvp.WaitlistedAt = nil
+		}
+		rsvp.Response = response
+		rsvp.GuestCount = submission.GuestCount
+		rsvp.Companions = submission.Companions
+		rsvp.Answers = submission.Answers
+
+		if isNew {
+			// Create new RSVP
+			rsvp.UserID = userID
+			rsvp.EventID = eventID
+			if occurrence != nil {
+				occurrenceDate := occurrence.UTC()
+				rsvp.OccurrenceDate = &occurrenceDate
+			}
+			err = tx.Create(&rsvp).Error
+		} else {
+			// Update existing RSVP
+			err = tx.Save(&rsvp).Error
+		}
+		if err != nil {
+			return err
+		}
+		if err := markInvitationResponded(tx, eventID, userID); err != nil {
+			return err
+		}
+		if err := s.notifications.NotifyRSVP(tx, &rsvp); err != nil {
+			return err
+		}
+
+		switch {
+		case isNew:
+			err = queueWebhooks(tx, eventID, models.WebhookRSVPSubmitted, webhookRSVP(tx, rsvp.ID, ""))
+		case rsvp.Response != previous || rsvp.GuestCount != previousParty:
+			err = queueWebhooks(tx, eventID, models.WebhookRSVPChanged, webhookRSVP(tx, rsvp.ID, previous))
+		}
+		if err != nil {
+			return err
+		}
+		if err := realtime.Notify(tx, eventID, realtime.KindRSVPs); err != nil {
+			return err
+		}
 
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		// Seats may have been freed, or a smaller waitlisted party may fit now
+		return fillFromWaitlist(tx, event, occurrence)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		// Create new RSVP
-		rsvp = models.RSVP{
-			UserID:   userID,
-			EventID:  eventID,
-			Response: response,
-		}
-		err = s.db.Create(&rsvp).Error
-	} else {
-		// Update existing RSVP
-		rsvp.Response = response
-		err = s.db.Save(&rsvp).Error
+	// Load relationships
+	if err := s.db.Preload("User").Preload("Event").First(&rsvp, rsvp.ID).Error; err != nil {
+		return nil, err
 	}
 
+	return &rsvp, nil
+}
+
+// GetWaitlistPosition returns the 1-based waitlist position of an RSVP, or 0 if it is not waitlisted
+func (s *RSVPService) GetWaitlistPosition(rsvp *models.RSVP) (int64, error) {
+	if rsvp.Response != models.RSVPResponseWaitlisted || rsvp.WaitlistedAt == nil {
+		return 0, nil
+	}
+
+	var ahead int64
+	err := forOccurrence(s.db.Model(&models.RSVP{}), rsvp.OccurrenceDate).
+		Where("event_id = ? AND response = ? AND voided_at IS NULL", rsvp.EventID, models.RSVPResponseWaitlisted).
+		Where("(waitlisted_at < ? OR (waitlisted_at = ? AND id < ?))", rsvp.WaitlistedAt, rsvp.WaitlistedAt, rsvp.ID).
+		Count(&ahead).Error
 	if err != nil {
+		return 0, err
+	}
+	return ahead + 1, nil
+}
+
+// lockEvent loads an event with a row lock held until the transaction ends
+func lockEvent(tx *gorm.DB, eventID uuid.UUID) (*models.Event, error) {
+	var event models.Event
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, "id = ?", eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("event not found")
+		}
 		return nil, err
 	}
+	return &event, nil
+}
 
-	// Load relationships
-	err = s.db.Preload("User").Preload("Event").First(&rsvp, rsvp.ID).Error
-	return &rsvp, err
+// countConfirmed counts the people in active "yes" RSVPs for an occurrence, i.e. the seats taken
+func countConfirmed(tx *gorm.DB, eventID uuid.UUID, occurrence *time.Time) (int64, error) {
+	var confirmed int64
+	err := forOccurrence(tx.Model(&models.RSVP{}), occurrence).
+		Select("COALESCE(SUM(guest_count), 0)").
+		Where("event_id = ? AND response = ? AND voided_at IS NULL", eventID, models.RSVPResponseYes).
+		Scan(&confirmed).Error
+	return confirmed, err
+}
+
+// hasSeats reports whether an occurrence of the event can take the given
+// number of extra people. The caller must hold the event lock.
+func hasSeats(tx *gorm.DB, event *models.Event, occurrence *time.Time, extra int) (bool, error) {
+	if event.MaxAttendees <= 0 || extra <= 0 {
+		return true, nil
+	}
+	confirmed, err := countConfirmed(tx, event.ID, occurrence)
+	if err != nil {
+		return false, err
+	}
+	return confirmed+int64(extra) <= int64(event.MaxAttendees), nil
 }
 
-// GetRSVP gets a user's RSVP for a specific event
-func (s *RSVPService) GetRSVP(userID, eventID uuid.UUID) (*models.RSVP, error) {
+// fillFromWaitlist promotes waitlisted parties of an occurrence, oldest
+// first, into any free seats, skipping parties too large for what is left.
+// The caller must hold the event lock.
+func fillFromWaitlist(tx *gorm.DB, event *models.Event, occurrence *time.Time) error {
+	var waitlist []models.RSVP
+	err := forOccurrence(tx, occurrence).Where("event_id = ? AND response = ? AND voided_at IS NULL", event.ID, models.RSVPResponseWaitlisted).
+		Order("waitlisted_at ASC, id ASC").Find(&waitlist).Error
+	if err != nil || len(waitlist) == 0 {
+		return err
+	}
+
+	free := int64(-1) // unlimited
+	if event.MaxAttendees > 0 {
+		confirmed, err := countConfirmed(tx, event.ID, occurrence)
+		if err != nil {
+			return err
+		}
+		free = int64(event.MaxAttendees) - confirmed
+	}
+
+	for i := range waitlist {
+		if free == 0 {
+			break
+		}
+		if free > 0 {
+			if int64(waitlist[i].GuestCount) > free {
+				continue
+			}
+			free -= int64(waitlist[i].GuestCount)
+		}
+		if err := tx.Model(&waitlist[i]).Updates(map[string]interface{}{
+			"response":      models.RSVPResponseYes,
+			"waitlisted_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+		// Moving up from the waitlist changes the guest's answer
+		err := queueWebhooks(tx, event.ID, models.WebhookRSVPChanged, webhookRSVP(tx, waitlist[i].ID, models.RSVPResponseWaitlisted))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillAllWaitlists runs fillFromWaitlist for every occurrence of the event
+// that has a waitlist. The caller must hold the event lock.
+func fillAllWaitlists(tx *gorm.DB, event *models.Event) error {
+	var occurrences []*time.Time
+	err := tx.Model(&models.RSVP{}).
+		Distinct("occurrence_date").
+		Where("event_id = ? AND response = ? AND voided_at IS NULL", event.ID, models.RSVPResponseWaitlisted).
+		Pluck("occurrence_date", &occurrences).Error
+	if err != nil {
+		return err
+	}
+
+	for _, occurrence := range occurrences {
+		if err := fillFromWaitlist(tx, event, occurrence); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetRSVP gets a user's RSVP for a specific event, or for one occurrence of a recurring event
+func (s *RSVPService) GetRSVP(userID, eventID uuid.UUID, occurrence *time.Time) (*models.RSVP, error) {
 	var rsvp models.RSVP
-	err := s.db.Where("user_id = ? AND event_id = ?", userID, eventID).
+	err := forOccurrence(s.db, occurrence).Where("user_id = ? AND event_id = ?", userID, eventID).
 		Preload("User").Preload("Event").First(&rsvp).Error
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -67,11 +332,15 @@ func (s *RSVPService) GetRSVP(userID, eventID uuid.UUID) (*models.RSVP, error) {
 	return &rsvp, err
 }
 
-// GetEventRSVPs gets all RSVPs for a specific event
-func (s *RSVPService) GetEventRSVPs(eventID uuid.UUID) ([]models.RSVP, error) {
+// GetEventRSVPs gets all RSVPs for a specific event. For recurring events a
+// nil occurrence returns the RSVPs of every occurrence.
+func (s *RSVPService) GetEventRSVPs(eventID uuid.UUID, occurrence *time.Time) ([]models.RSVP, error) {
 	var rsvps []models.RSVP
-	err := s.db.Where("event_id = ?", eventID).
-		Preload("User").Preload("Event").Find(&rsvps).Error
+	query := s.db.Where("event_id = ?", eventID)
+	if occurrence != nil {
+		query = forOccurrence(query, occurrence)
+	}
+	err := query.Preload("User").Preload("Event").Find(&rsvps).Error
 	return rsvps, err
 }
 
@@ -91,30 +360,82 @@ func (s *RSVPService) GetUserRSVPsByResponse(userID uuid.UUID, response models.R
 	return rsvps, err
 }
 
-// DeleteRSVP removes an RSVP
-func (s *RSVPService) DeleteRSVP(userID, eventID uuid.UUID) error {
-	return s.db.Where("user_id = ? AND event_id = ?", userID, eventID).Delete(&models.RSVP{}).Error
+// DeleteRSVP removes an RSVP, promoting from the waitlist if it held a seat
+func (s *RSVPService) DeleteRSVP(userID, eventID uuid.UUID, occurrence *time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		event, err := lockEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+
+		var rsvp models.RSVP
+		err = forOccurrence(tx, occurrence).Where("user_id = ? AND event_id = ?", userID, eventID).First(&rsvp).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&rsvp).Error; err != nil {
+			return err
+		}
+		if err := realtime.Notify(tx, eventID, realtime.KindRSVPs); err != nil {
+			return err
+		}
+		if rsvp.Response == models.RSVPResponseYes {
+			return fillFromWaitlist(tx, event, occurrence)
+		}
+		return nil
+	})
+}
+
+// RSVPCounts summarises an event's active RSVPs by response
+type RSVPCounts struct {
+	Responses map[string]int64 // Number of RSVPs per response
+	Headcount map[string]int64 // Number of people per response, including plus-ones
 }
 
-// GetRSVPCounts gets count of RSVPs by response type for an event
-func (s *RSVPService) GetRSVPCounts(eventID uuid.UUID) (map[string]int64, error) {
-	counts := make(map[string]int64)
+// GetRSVPCounts gets the number of RSVPs and people by response type for an
+// event. For recurring events a nil occurrence counts every occurrence.
+func (s *RSVPService) GetRSVPCounts(eventID uuid.UUID, occurrence *time.Time) (*RSVPCounts, error) {
+	counts := &RSVPCounts{
+		Responses: make(map[string]int64),
+		Headcount: make(map[string]int64),
+	}
 
 	responses := []models.RSVPResponse{
 		models.RSVPResponseYes,
 		models.RSVPResponseNo,
 		models.RSVPResponseMaybe,
+		models.RSVPResponseWaitlisted,
 	}
-
 	for _, response := range responses {
-		var count int64
-		err := s.db.Model(&models.RSVP{}).
-			Where("event_id = ? AND response = ?", eventID, response).
-			Count(&count).Error
-		if err != nil {
-			return nil, err
-		}
-		counts[string(response)] = count
+		counts.Responses[string(response)] = 0
+		counts.Headcount[string(response)] = 0
+	}
+
+	var rows []struct {
+		Response  string
+		RSVPs     int64
+		Headcount int64
+	}
+	query := s.db.Model(&models.RSVP{})
+	if occurrence != nil {
+		query = forOccurrence(query, occurrence)
+	}
+	err := query.
+		Select("response, COUNT(*) AS rsvps, COALESCE(SUM(guest_count), 0) AS headcount").
+		Where("event_id = ? AND voided_at IS NULL", eventID).
+		Group("response").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts.Responses[row.Response] = row.RSVPs
+		counts.Headcount[row.Response] = row.Headcount
 	}
 
 	return counts, nil
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// developmentSessionSecret stands in for SESSION_SECRET outside of production
+	developmentSessionSecret = "your-secret-key-change-in-production"
+	// minSessionSecretLength is the shortest SESSION_SECRET production accepts
+	minSessionSecretLength = 32
+)
+
+// IsProduction reports whether the app runs in production (APP_ENV=production)
+func IsProduction() bool {
+	return os.Getenv("APP_ENV") == "production"
+}
+
+// SessionSecret returns SESSION_SECRET, which signs session cookies and the
+// tokens in links. Outside of production a development secret stands in for
+// it; in production it must be set to something other than a placeholder.
+func SessionSecret() ([]byte, error) {
+	secret := os.Getenv("SESSION_SECRET")
+	if !IsProduction() {
+		if secret == "" {
+			secret = developmentSessionSecret
+		}
+		return []byte(secret), nil
+	}
+
+	switch {
+	case secret == "":
+		return nil, errors.New("SESSION_SECRET is required in production")
+	case secret == developmentSessionSecret || strings.HasPrefix(secret, "your-"):
+		return nil, errors.New("SESSION_SECRET is still a placeholder")
+	case len(secret) < minSessionSecretLength:
+		return nil, fmt.Errorf("SESSION_SECRET must be at least %d characters", minSessionSecretLength)
+	}
+	return []byte(secret), nil
+}
+
+// appSigningKey signs tokens handed out in links, such as invites and
+// unsubscribe links. main refuses to start when SessionSecret fails, so
+// the fallback is never used in production.
+func appSigningKey() []byte {
+	secret, err := SessionSecret()
+	if err != nil {
+		return []byte(developmentSessionSecret)
+	}
+	return secret
+}
+
+// signToken makes a URL-safe token carrying the payload and its HMAC
+func signToken(key []byte, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyToken returns the payload of a token made by signToken with the same key
+func verifyToken(key []byte, token string) (string, bool) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	given, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", false
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(given, mac.Sum(nil)) {
+		return "", false
+	}
+	return string(payload), true
+}
@@ -10,6 +10,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrUserNotFound is returned when no user matches
+var ErrUserNotFound = errors.New("user not found")
+
 type UserService struct {
 	db *gorm.DB
 }
@@ -34,7 +37,7 @@ func (s *UserService) GetUserByID(id uuid.UUID) (*models.User, error) {
 	var user models.User
 	if err := s.db.First(&user, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -46,7 +49,7 @@ func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
 	if err := s.db.First(&user, "email = ?", email).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -58,7 +61,7 @@ func (s *UserService) GetUserByAuthID(authID string) (*models.User, error) {
 	var user models.User
 	if err := s.db.First(&user, "auth_id = ?", authID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -91,7 +94,7 @@ func (s *UserService) UpdateUser(id uuid.UUID, updates map[string]interface{}) (
 	// Check if user exists
 	if err := s.db.First(&user, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -111,7 +114,7 @@ func (s *UserService) DeleteUser(id uuid.UUID) error {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 	return nil
 }
@@ -146,7 +149,7 @@ func (s *UserService) CreateOrUpdateUserFromAuth(authID, email, name, picture st
 		Email:    email,
 		Name:     name,
 		Picture:  picture,
-		Role:     "user",
+		Role:     models.UserRoleUser,
 		IsActive: true,
 	}
 
@@ -0,0 +1,498 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxWebhooksPerUser caps the endpoints one user can register
+	maxWebhooksPerUser = 10
+	// webhookTimeout bounds a single delivery attempt
+	webhookTimeout = 10 * time.Second
+	// webhookMaxAttempts is how often a delivery is tried before it counts as failed
+	webhookMaxAttempts = 8
+	// webhookResponseLimit is how much of a response body is kept with an attempt
+	webhookResponseLimit = 1024
+)
+
+var (
+	// ErrWebhookNotFound is returned for webhooks that don't exist or belong to someone else
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrDeliveryNotFound is returned for deliveries that aren't the webhook's
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
+
+type WebhookService struct {
+	db *gorm.DB
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService() *WebhookService {
+	return &WebhookService{
+		db: database.GetDB(),
+	}
+}
+
+// WebhookInput registers or changes a webhook. On updates only the fields
+// that were sent are changed.
+type WebhookInput struct {
+	URL         string                    `json:"url"`
+	Description *string                   `json:"description"`
+	EventTypes  []models.WebhookEventType `json:"event_types"`
+	Disabled    *bool                     `json:"disabled"`
+}
+
+// validate checks the fields that were sent; creating requires url and event_types
+func (in *WebhookInput) validate(creating bool) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if in.URL != "" || creating {
+		parsed, err := url.Parse(in.URL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			errs["url"] = "must be an absolute http or https URL"
+		}
+	}
+	if in.EventTypes != nil || creating {
+		if len(in.EventTypes) == 0 {
+			errs["event_types"] = "must name at least one event type"
+		}
+		for _, eventType := range in.EventTypes {
+			if !eventType.IsValid() {
+				errs["event_types"] = "unknown event type " + string(eventType)
+				break
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// CreateWebhook registers a webhook for a user and returns it with its
+// signing secret, which is only ever shown here
+func (s *WebhookService) CreateWebhook(userID uuid.UUID, input *WebhookInput) (*models.Webhook, string, error) {
+	if errs := input.validate(true); errs != nil {
+		return nil, "", errs
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Webhook{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, "", err
+	}
+	if count >= maxWebhooksPerUser {
+		return nil, "", ValidationErrors{"url": fmt.Sprintf("at most %d webhooks can be registered", maxWebhooksPerUser)}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	webhook := models.Webhook{
+		UserID:     userID,
+		URL:        input.URL,
+		EventTypes: uniqueEventTypes(input.EventTypes),
+		Secret:     "whsec_" + hex.EncodeToString(raw),
+	}
+	if input.Description != nil {
+		webhook.Description = *input.Description
+	}
+	if input.Disabled != nil {
+		webhook.Disabled = *input.Disabled
+	}
+
+	if err := s.db.Create(&webhook).Error; err != nil {
+		return nil, "", err
+	}
+	return &webhook, webhook.Secret, nil
+}
+
+// GetWebhooks lists a user's webhooks, oldest first
+func (s *WebhookService) GetWebhooks(userID uuid.UUID) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// GetWebhook returns one of a user's webhooks
+func (s *WebhookService) GetWebhook(userID, id uuid.UUID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := s.db.First(&webhook, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// UpdateWebhook changes the fields of a user's webhook that were sent
+func (s *WebhookService) UpdateWebhook(userID, id uuid.UUID, input *WebhookInput) (*models.Webhook, error) {
+	if errs := input.validate(false); errs != nil {
+		return nil, errs
+	}
+
+	webhook, err := s.GetWebhook(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]interface{}{}
+	if input.URL != "" {
+		changes["url"] = input.URL
+	}
+	if input.Description != nil {
+		changes["description"] = *input.Description
+	}
+	if input.EventTypes != nil {
+		encoded, err := json.Marshal(uniqueEventTypes(input.EventTypes))
+		if err != nil {
+			return nil, err
+		}
+		changes["event_types"] = string(encoded)
+	}
+	if input.Disabled != nil {
+		changes["disabled"] = *input.Disabled
+	}
+	if len(changes) > 0 {
+		if err := s.db.Model(webhook).Updates(changes).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetWebhook(userID, id)
+}
+
+// DeleteWebhook removes a user's webhook along with its deliveries
+func (s *WebhookService) DeleteWebhook(userID, id uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Webhook{}, "id = ? AND user_id = ?", id, userID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		return tx.Delete(&models.WebhookDelivery{}, "webhook_id = ?", id).Error
+	})
+}
+
+// GetDeliveries pages through a webhook's deliveries, newest first, each with its attempts
+func (s *WebhookService) GetDeliveries(userID, webhookID uuid.UUID, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	if _, err := s.GetWebhook(userID, webhookID); err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	var total int64
+	query := s.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("Attempts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// Redeliver sends a delivery of a user's webhook again, with fresh retries.
+// The body is the same as the first time; attempts are added to its history.
+func (s *WebhookService) Redeliver(userID, webhookID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	var delivery models.WebhookDelivery
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.First(&delivery, "id = ? AND webhook_id = ?", deliveryID, webhookID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDeliveryNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&delivery).Update("status", models.WebhookDeliveryPending).Error; err != nil {
+			return err
+		}
+		// Dropped if the delivery is still being retried
+		return enqueueDelivery(tx, delivery.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// queueWebhooks records a delivery for every webhook of the event's owner and
+// co-hosts that subscribes to the event type, skipping deactivated users, and queues sending them with
+// the surrounding transaction. data builds the payload, only when needed.
+func queueWebhooks(tx *gorm.DB, eventID uuid.UUID, eventType models.WebhookEventType, data func() (map[string]interface{}, error)) error {
+	var webhooks []models.Webhook
+	err := tx.Where("disabled = ? AND user_id IN (SELECT user_id FROM event_members WHERE event_id = ? AND role IN ?)",
+		false, eventID, []models.EventRole{models.EventRoleOwner, models.EventRoleCoHost}).
+		Where("user_id IN (SELECT id FROM users WHERE is_active)").
+		Find(&webhooks).Error
+	if err != nil {
+		return err
+	}
+
+	var payload models.WebhookPayload
+	for i := range webhooks {
+		if !webhooks[i].Subscribes(eventType) {
+			continue
+		}
+
+		if payload == nil {
+			values, err := data()
+			if err != nil {
+				return err
+			}
+			// Stored as plain JSON, so every attempt sends the same body
+			encoded, err := json.Marshal(values)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(encoded, &payload); err != nil {
+				return err
+			}
+		}
+
+		delivery := models.WebhookDelivery{
+			WebhookID: webhooks[i].ID,
+			EventType: eventType,
+			Payload:   payload,
+			Status:    models.WebhookDeliveryPending,
+		}
+		if err := tx.Create(&delivery).Error; err != nil {
+			return err
+		}
+		if err := enqueueDelivery(tx, delivery.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhookEvent loads an event as webhooks describe it, with its organizer
+func webhookEvent(tx *gorm.DB, eventID uuid.UUID) func() (map[string]interface{}, error) {
+	return func() (map[string]interface{}, error) {
+		var event models.Event
+		if err := tx.Preload("User").First(&event, "id = ?", eventID).Error; err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"event": event}, nil
+	}
+}
+
+// webhookRSVP loads an RSVP as webhooks describe it, with its guest and event
+func webhookRSVP(tx *gorm.DB, rsvpID uuid.UUID, previous models.RSVPResponse) func() (map[string]interface{}, error) {
+	return func() (map[string]interface{}, error) {
+		var rsvp models.RSVP
+		if err := tx.Preload("User").Preload("Event").First(&rsvp, "id = ?", rsvpID).Error; err != nil {
+			return nil, err
+		}
+		data := map[string]interface{}{"rsvp": rsvp}
+		if previous != "" {
+			data["previous_response"] = previous
+		}
+		return data, nil
+	}
+}
+
+func enqueueDelivery(tx *gorm.DB, deliveryID uuid.UUID) error {
+	return enqueueJob(tx, JobKindDeliverWebhook, models.JobPayload{"delivery_id": deliveryID.String()}, JobOptions{
+		UniqueKey:   JobKindDeliverWebhook + ":" + deliveryID.String(),
+		MaxAttempts: webhookMaxAttempts,
+	})
+}
+
+// deliverWebhook is the job that POSTs a delivery to its webhook and records
+// the attempt. Anything but a 2xx response is retried; once the job runs out
+// of attempts the delivery is marked failed.
+func deliverWebhook(ctx context.Context, job *models.Job) error {
+	db := database.GetDB()
+
+	deliveryID, err := uuid.Parse(fmt.Sprint(job.Payload["delivery_id"]))
+	if err != nil {
+		return permanent(fmt.Errorf("job has no delivery: %w", err))
+	}
+	var delivery models.WebhookDelivery
+	if err := db.First(&delivery, "id = ?", deliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Deleted with its webhook
+			return nil
+		}
+		return err
+	}
+	var webhook models.Webhook
+	if err := db.First(&webhook, "id = ?", delivery.WebhookID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// finish records the delivery's status, which stays pending while the job will retry
+	finish := func(status models.WebhookDeliveryStatus, code int, sendErr error) error {
+		var permanentErr permanentJobError
+		if sendErr != nil && job.Attempts < job.MaxAttempts && !errors.As(sendErr, &permanentErr) {
+			status = models.WebhookDeliveryPending
+		}
+		err := db.Model(&delivery).Updates(map[string]interface{}{
+			"status":           status,
+			"last_status_code": code,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return sendErr
+	}
+
+	if webhook.Disabled {
+		return finish(models.WebhookDeliveryFailed, 0, permanent(errors.New("webhook is disabled")))
+	}
+
+	attempt := models.WebhookAttempt{DeliveryID: delivery.ID}
+	started := time.Now()
+	attempt.StatusCode, attempt.ResponseBody, err = sendWebhook(ctx, &webhook, &delivery)
+	attempt.DurationMs = time.Since(started).Milliseconds()
+	if err == nil && (attempt.StatusCode < 200 || attempt.StatusCode > 299) {
+		err = fmt.Errorf("endpoint answered %d", attempt.StatusCode)
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	if createErr := db.Create(&attempt).Error; createErr != nil {
+		return createErr
+	}
+
+	if err != nil {
+		return finish(models.WebhookDeliveryFailed, attempt.StatusCode, err)
+	}
+	return finish(models.WebhookDeliverySucceeded, attempt.StatusCode, nil)
+}
+
+// sendWebhook POSTs a delivery, signed with the webhook's secret, and returns
+// the response code and the start of the body
+func sendWebhook(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"id":         delivery.ID,
+		"type":       delivery.EventType,
+		"created_at": delivery.CreatedAt,
+		"data":       delivery.Payload,
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Events-Webhooks/1.0")
+	req.Header.Set("X-Webhook-ID", delivery.ID.String())
+	req.Header.Set("X-Webhook-Event", string(delivery.EventType))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(webhook.Secret, timestamp, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	return resp.StatusCode, string(head), nil
+}
+
+// signWebhook is the HMAC-SHA256 of "<timestamp>.<body>", hex encoded.
+// Signing the timestamp lets receivers reject replayed deliveries.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookClient doesn't follow redirects and, unless
+// WEBHOOK_ALLOW_PRIVATE_NETWORKS is set for local development, refuses to
+// connect to loopback, private and link-local addresses, so webhook URLs
+// can't be used to reach the server's own network. It never uses a proxy,
+// which would connect to the receiver on its behalf without the check.
+var webhookClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: webhookTimeout,
+			Control: webhookDialControl,
+		}).DialContext,
+		TLSHandshakeTimeout: webhookTimeout,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func webhookDialControl(network, address string, c syscall.RawConn) error {
+	if os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+	return nil
+}
+
+// uniqueEventTypes drops repeated event types, keeping the first of each
+func uniqueEventTypes(eventTypes []models.WebhookEventType) []models.WebhookEventType {
+	seen := make(map[models.WebhookEventType]bool, len(eventTypes))
+	unique := make([]models.WebhookEventType, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if !seen[eventType] {
+			seen[eventType] = true
+			unique = append(unique, eventType)
+		}
+	}
+	return unique
+}
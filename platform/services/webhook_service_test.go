@@ -0,0 +1,131 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestSignWebhook(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		want      string
+	}{
+		{
+			name:      "body",
+			secret:    "whsec_test",
+			timestamp: "1700000000",
+			body:      `{"id":"1"}`,
+			want:      "11bf4466ea17c3df3fd743af0b435368e16b7a05eb8eced85e8c4670767bdec5",
+		},
+		{
+			name:      "empty body",
+			secret:    "whsec_test",
+			timestamp: "1700000000",
+			body:      "",
+			want:      "5967f3c560522fa40cf2876ebc3c3a08551dd6959aaade3b413460591895bdcc",
+		},
+	}
+
+	hexDigest := regexp.MustCompile(`^[0-9a-f]{64}$`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := signWebhook(tt.secret, tt.timestamp, []byte(tt.body))
+			if !hexDigest.MatchString(got) {
+				t.Errorf("signature %q isn't a lowercase hex SHA-256", got)
+			}
+			if got != tt.want {
+				t.Errorf("signWebhook() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// The timestamp is signed, so a replayed body with a new one doesn't verify
+	body := []byte(`{"id":"1"}`)
+	if signWebhook("whsec_test", "1700000000", body) == signWebhook("whsec_test", "1700000001", body) {
+		t.Error("signature doesn't depend on the timestamp")
+	}
+	if signWebhook("whsec_test", "1700000000", body) == signWebhook("whsec_other", "1700000000", body) {
+		t.Error("signature doesn't depend on the secret")
+	}
+}
+
+func TestWebhookDialControl(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "")
+
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		// Public
+		{"93.184.215.14:443", true},
+		{"[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443", true},
+
+		// Loopback
+		{"127.0.0.1:80", false},
+		{"127.8.9.10:80", false},
+		{"[::1]:80", false},
+
+		// RFC 1918 and unique local
+		{"10.0.0.1:443", false},
+		{"172.16.0.1:443", false},
+		{"172.31.255.254:443", false},
+		{"192.168.1.1:443", false},
+		{"[fd00::1]:443", false},
+
+		// Link-local, including cloud metadata endpoints
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+
+		// IPv4-mapped IPv6
+		{"[::ffff:127.0.0.1]:80", false},
+		{"[::ffff:10.0.0.1]:80", false},
+		{"[::ffff:169.254.169.254]:80", false},
+
+		// Unspecified
+		{"0.0.0.0:80", false},
+		{"[::]:80", false},
+
+		// Multicast
+		{"224.0.0.1:80", false},
+		{"[ff02::1]:80", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := webhookDialControl("tcp", tt.address, nil)
+			if tt.allowed && err != nil {
+				t.Errorf("dialing %s refused: %v", tt.address, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("dialing %s allowed", tt.address)
+			}
+		})
+	}
+}
+
+func TestWebhookDialControlAllowPrivateNetworks(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "true")
+
+	for _, address := range []string{"127.0.0.1:8080", "192.168.1.1:80"} {
+		if err := webhookDialControl("tcp", address, nil); err != nil {
+			t.Errorf("dialing %s refused with private networks allowed: %v", address, err)
+		}
+	}
+}
+
+func TestWebhookDialControlRejectsHostnames(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "")
+
+	// The dialer passes resolved addresses; anything else is refused
+	for _, address := range []string{"localhost:80", "example.com:443"} {
+		if err := webhookDialControl("tcp", address, nil); err == nil {
+			t.Errorf("dialing %s allowed", address)
+		}
+	}
+	if err := webhookDialControl("tcp", "no-port", nil); err == nil {
+		t.Error("dialing an address without a port allowed")
+	}
+}
@@ -0,0 +1,173 @@
+package sessionstore
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"01-Login/platform/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Backend keeps sessions for a Store
+type Backend interface {
+	// FindByToken returns the session with the token hash, or nil if there is none
+	FindByToken(tokenHash string) (*models.Session, error)
+	// Find returns the session with the ID, or nil if there is none
+	Find(id uuid.UUID) (*models.Session, error)
+	// Save creates the session or replaces it
+	Save(session *models.Session) error
+	// Touch records that the session was used at the given time
+	Touch(id uuid.UUID, at time.Time) error
+	// Delete removes the session, if it exists
+	Delete(id uuid.UUID) error
+	// ListForUser returns a user's sessions, most recently used first
+	ListForUser(userID uuid.UUID) ([]models.Session, error)
+	// DeleteExpired removes sessions unused since idleSince or past their expiry at now
+	DeleteExpired(idleSince, now time.Time) (int64, error)
+}
+
+// PostgresBackend keeps sessions in the sessions table, so they are shared
+// by every server instance and survive restarts
+type PostgresBackend struct {
+	db *gorm.DB
+}
+
+// NewPostgresBackend creates a backend on the given database
+func NewPostgresBackend(db *gorm.DB) *PostgresBackend {
+	return &PostgresBackend{db: db}
+}
+
+func (b *PostgresBackend) FindByToken(tokenHash string) (*models.Session, error) {
+	return b.first("token_hash = ?", tokenHash)
+}
+
+func (b *PostgresBackend) Find(id uuid.UUID) (*models.Session, error) {
+	return b.first("id = ?", id)
+}
+
+func (b *PostgresBackend) first(query string, args ...interface{}) (*models.Session, error) {
+	var session models.Session
+	err := b.db.Where(query, args...).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (b *PostgresBackend) Save(session *models.Session) error {
+	return b.db.Save(session).Error
+}
+
+func (b *PostgresBackend) Touch(id uuid.UUID, at time.Time) error {
+	return b.db.Model(&models.Session{}).Where("id = ?", id).Update("last_seen_at", at).Error
+}
+
+func (b *PostgresBackend) Delete(id uuid.UUID) error {
+	return b.db.Delete(&models.Session{}, "id = ?", id).Error
+}
+
+func (b *PostgresBackend) ListForUser(userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := b.db.Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (b *PostgresBackend) DeleteExpired(idleSince, now time.Time) (int64, error) {
+	result := b.db.Where("last_seen_at < ? OR expires_at <= ?", idleSince, now).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
+
+// MemoryBackend keeps sessions in the process, for tests and local
+// development. Sessions are lost on restart and not shared between instances.
+type MemoryBackend struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]models.Session
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{sessions: make(map[uuid.UUID]models.Session)}
+}
+
+func (b *MemoryBackend) FindByToken(tokenHash string) (*models.Session, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, session := range b.sessions {
+		if session.TokenHash == tokenHash {
+			return &session, nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *MemoryBackend) Find(id uuid.UUID) (*models.Session, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	session, ok := b.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (b *MemoryBackend) Save(session *models.Session) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+	b.sessions[session.ID] = *session
+	return nil
+}
+
+func (b *MemoryBackend) Touch(id uuid.UUID, at time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if session, ok := b.sessions[id]; ok {
+		session.LastSeenAt = at
+		b.sessions[id] = session
+	}
+	return nil
+}
+
+func (b *MemoryBackend) Delete(id uuid.UUID) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.sessions, id)
+	return nil
+}
+
+func (b *MemoryBackend) ListForUser(userID uuid.UUID) ([]models.Session, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var sessions []models.Session
+	for _, session := range b.sessions {
+		if session.UserID != nil && *session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+func (b *MemoryBackend) DeleteExpired(idleSince, now time.Time) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var deleted int64
+	for id, session := range b.sessions {
+		if session.LastSeenAt.Before(idleSince) || !session.ExpiresAt.After(now) {
+			delete(b.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
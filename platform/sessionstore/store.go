@@ -0,0 +1,378 @@
+// Package sessionstore keeps browser sessions on the server. The session
+// cookie only carries a signed random token; the session's values, its
+// device and its expiry live in a Backend, so sessions can be listed and
+// revoked and the cookie stays small.
+package sessionstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"01-Login/platform/database"
+	"01-Login/platform/models"
+
+	ginsessions "github.com/gin-contrib/sessions"
+	"github.com/google/uuid"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// UserIDKey is the session value holding the signed-in user's ID. It ties
+// the session to the user for listing and revoking, and changing it gives
+// the session a new token, so a token issued before signing in can't be
+// used to ride along afterwards.
+const UserIDKey = "user_id"
+
+// ImpersonatedUserIDKey holds the user an admin is acting as for support,
+// and ImpersonationStartedKey when they began, as Unix seconds. They sit
+// next to the admin's own sign-in, which stays the session's user.
+const (
+	ImpersonatedUserIDKey   = "impersonated_user_id"
+	ImpersonationStartedKey = "impersonation_started"
+)
+
+const (
+	defaultIdleTimeout = 24 * time.Hour
+	defaultMaxAge      = 30 * 24 * time.Hour
+	// touchInterval limits how often using a session is written back
+	touchInterval = time.Minute
+)
+
+// ErrSessionNotFound is returned when revoking a session the user doesn't have
+var ErrSessionNotFound = errors.New("session not found")
+
+// Store is a gin session store keeping sessions in a Backend. Sessions end
+// when unused for the idle timeout or, regardless of use, at the max age.
+type Store struct {
+	backend     Backend
+	codecs      []securecookie.Codec
+	options     sessions.Options
+	idleTimeout time.Duration
+	maxAge      time.Duration
+}
+
+var _ ginsessions.Store = (*Store)(nil)
+
+// New creates a store signing session cookies with secret
+func New(backend Backend, secret []byte, idleTimeout, maxAge time.Duration) *Store {
+	codecs := securecookie.CodecsFromPairs(secret)
+	for _, codec := range codecs {
+		if cookie, ok := codec.(*securecookie.SecureCookie); ok {
+			cookie.MaxAge(int(maxAge.Seconds()))
+		}
+	}
+	return &Store{
+		backend:     backend,
+		codecs:      codecs,
+		options:     sessions.Options{Path: "/", MaxAge: int(maxAge.Seconds()), HttpOnly: true},
+		idleTimeout: idleTimeout,
+		maxAge:      maxAge,
+	}
+}
+
+// Setup creates a store configured from the environment:
+//
+//	SESSION_STORE         postgres (default) or memory
+//	SESSION_IDLE_TIMEOUT  how long an unused session lasts, e.g. 24h (default)
+//	SESSION_MAX_AGE       how long any session lasts, e.g. 720h (default)
+func Setup(secret []byte) (*Store, error) {
+	idleTimeout, err := durationEnv("SESSION_IDLE_TIMEOUT", defaultIdleTimeout)
+	if err != nil {
+		return nil, err
+	}
+	maxAge, err := durationEnv("SESSION_MAX_AGE", defaultMaxAge)
+	if err != nil {
+		return nil, err
+	}
+	if idleTimeout > maxAge {
+		return nil, fmt.Errorf("SESSION_IDLE_TIMEOUT must not be longer than SESSION_MAX_AGE")
+	}
+
+	var backend Backend
+	switch kind := os.Getenv("SESSION_STORE"); kind {
+	case "", "postgres":
+		backend = NewPostgresBackend(database.GetDB())
+	case "memory":
+		backend = NewMemoryBackend()
+	default:
+		return nil, fmt.Errorf("unknown SESSION_STORE %q", kind)
+	}
+	return New(backend, secret, idleTimeout, maxAge), nil
+}
+
+// Options sets the cookie options of new sessions; MaxAge is ignored in
+// favor of the store's own expiry
+func (s *Store) Options(options ginsessions.Options) {
+	s.options = *options.ToGorillaOptions()
+	s.options.MaxAge = int(s.maxAge.Seconds())
+}
+
+// Get returns the request's session, loading it once per request
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session named by the request's cookie, or starts an empty
+// one if the cookie is missing, forged or its session has ended. The
+// session's ID is the ID of its record, not its token.
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := s.options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.codecs...); err != nil {
+		return session, nil
+	}
+
+	record, err := s.backend.FindByToken(hashToken(token))
+	if err != nil {
+		return session, err
+	}
+	now := time.Now()
+	if record == nil || s.expired(record, now) {
+		return session, nil
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(record.Data)).Decode(&session.Values); err != nil {
+		return session, fmt.Errorf("decoding session %v: %w", record.ID, err)
+	}
+	session.ID = record.ID.String()
+	session.IsNew = false
+
+	if now.Sub(record.LastSeenAt) > touchInterval {
+		if err := s.backend.Touch(record.ID, now); err != nil {
+			log.Printf("Error touching session %v: %v", record.ID, err)
+		}
+	}
+	return session, nil
+}
+
+// Save writes the session's values back. An emptied session, or one whose
+// options ask to delete the cookie, is deleted; a new session, or one that
+// changed users, gets a fresh token and cookie. A session revoked or expired
+// while the request was running stays ended rather than being recreated.
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	var record *models.Session
+	ended := false
+	if id, err := uuid.Parse(session.ID); err == nil {
+		if record, err = s.backend.Find(id); err != nil {
+			return err
+		}
+		ended = record == nil || s.expired(record, time.Now())
+	}
+
+	if ended || session.Options.MaxAge < 0 || len(session.Values) == 0 {
+		if record != nil {
+			if err := s.backend.Delete(record.ID); err != nil {
+				return err
+			}
+		}
+		session.ID = ""
+		s.setCookie(w, session, "", -1)
+		return nil
+	}
+
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(session.Values); err != nil {
+		return err
+	}
+	userID := sessionUserID(session.Values)
+	now := time.Now()
+
+	if record != nil && sameUser(record.UserID, userID) {
+		record.Data = data.Bytes()
+		record.LastSeenAt = now
+		return s.backend.Save(record)
+	}
+
+	if record != nil {
+		if err := s.backend.Delete(record.ID); err != nil {
+			return err
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	record = &models.Session{
+		ID:         uuid.New(),
+		TokenHash:  hashToken(token),
+		UserID:     userID,
+		Data:       data.Bytes(),
+		UserAgent:  r.UserAgent(),
+		IPAddress:  clientIP(r),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.maxAge),
+	}
+	if err := s.backend.Save(record); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), token, s.codecs...)
+	if err != nil {
+		return err
+	}
+	session.ID = record.ID.String()
+	s.setCookie(w, session, encoded, int(s.maxAge.Seconds()))
+	return nil
+}
+
+// ListUserSessions returns a user's active sessions, most recently used first
+func (s *Store) ListUserSessions(userID uuid.UUID) ([]models.Session, error) {
+	all, err := s.backend.ListForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	active := make([]models.Session, 0, len(all))
+	for _, session := range all {
+		if !s.expired(&session, now) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+// RevokeUserSession signs a user out of one of their sessions
+func (s *Store) RevokeUserSession(userID, sessionID uuid.UUID) error {
+	session, err := s.backend.Find(sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID == nil || *session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	return s.backend.Delete(session.ID)
+}
+
+// RevokeAllUserSessions signs a user out everywhere, returning how many
+// sessions ended
+func (s *Store) RevokeAllUserSessions(userID uuid.UUID) (int, error) {
+	return s.RevokeOtherUserSessions(userID, uuid.Nil)
+}
+
+// RevokeOtherUserSessions signs a user out everywhere but the kept session,
+// returning how many sessions ended
+func (s *Store) RevokeOtherUserSessions(userID, keepID uuid.UUID) (int, error) {
+	sessions, err := s.backend.ListForUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == keepID {
+			continue
+		}
+		if err := s.backend.Delete(session.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// Run deletes ended sessions every interval until the context is done
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		if _, err := s.backend.DeleteExpired(now.Add(-s.idleTimeout), now); err != nil {
+			log.Printf("Error deleting expired sessions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expired reports whether a session has been idle too long or reached its max age
+func (s *Store) expired(session *models.Session, now time.Time) bool {
+	return !now.Before(session.ExpiresAt) || now.Sub(session.LastSeenAt) > s.idleTimeout
+}
+
+func (s *Store) setCookie(w http.ResponseWriter, session *sessions.Session, value string, maxAge int) {
+	options := *session.Options
+	options.MaxAge = maxAge
+	http.SetCookie(w, sessions.NewCookie(session.Name(), value, &options))
+}
+
+// sessionUserID reads UserIDKey from a session's values
+func sessionUserID(values map[interface{}]interface{}) *uuid.UUID {
+	raw, _ := values[UserIDKey].(string)
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
+func sameUser(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// clientIP is the address a session was started from, for showing on the
+// device list only. Behind a proxy the first forwarded address is used.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(first)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func durationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as 24h", key)
+	}
+	return d, nil
+}
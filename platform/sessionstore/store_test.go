@@ -0,0 +1,203 @@
+package sessionstore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	testCookie      = "auth-session"
+	testIdleTimeout = time.Hour
+	testMaxAge      = 24 * time.Hour
+)
+
+func newTestStore() (*Store, *MemoryBackend) {
+	backend := NewMemoryBackend()
+	return New(backend, []byte("0123456789abcdef0123456789abcdef"), testIdleTimeout, testMaxAge), backend
+}
+
+// load opens the session a request with the cookie would get; a nil cookie
+// is a browser without one
+func load(t *testing.T, store *Store, cookie *http.Cookie) *sessions.Session {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	session, err := store.New(r, testCookie)
+	if err != nil {
+		t.Fatalf("loading session: %v", err)
+	}
+	return session
+}
+
+// save writes the session back and returns the cookie the response set
+func save(t *testing.T, store *Store, session *sessions.Session) *http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	if err := store.Save(httptest.NewRequest(http.MethodGet, "/", nil), w, session); err != nil {
+		t.Fatalf("saving session: %v", err)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == testCookie {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// signIn starts a session for the user and returns its cookie and record ID
+func signIn(t *testing.T, store *Store, userID uuid.UUID) (*http.Cookie, uuid.UUID) {
+	t.Helper()
+	session := load(t, store, nil)
+	session.Values[UserIDKey] = userID.String()
+	cookie := save(t, store, session)
+	if cookie == nil || cookie.Value == "" {
+		t.Fatal("signing in set no cookie")
+	}
+	return cookie, uuid.MustParse(session.ID)
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	store, _ := newTestStore()
+	userID := uuid.New()
+	cookie, id := signIn(t, store, userID)
+
+	session := load(t, store, cookie)
+	if session.IsNew {
+		t.Fatal("signed-in session didn't load")
+	}
+	if session.ID != id.String() {
+		t.Errorf("session ID = %s, want %s", session.ID, id)
+	}
+	if got := session.Values[UserIDKey]; got != userID.String() {
+		t.Errorf("user ID = %v, want %s", got, userID)
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		lastSeenAt time.Time
+		expiresAt  time.Time
+		expired    bool
+	}{
+		{"active", now.Add(-time.Minute), now.Add(time.Hour), false},
+		{"idle", now.Add(-testIdleTimeout - time.Minute), now.Add(time.Hour), true},
+		{"past max age while active", now.Add(-time.Minute), now.Add(-time.Second), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, backend := newTestStore()
+			cookie, id := signIn(t, store, uuid.New())
+
+			record, _ := backend.Find(id)
+			record.LastSeenAt = tt.lastSeenAt
+			record.ExpiresAt = tt.expiresAt
+			backend.Save(record)
+
+			if session := load(t, store, cookie); session.IsNew != tt.expired {
+				t.Errorf("session loaded as new = %v, want %v", session.IsNew, tt.expired)
+			}
+
+			deleted, _ := backend.DeleteExpired(now.Add(-testIdleTimeout), now)
+			if wantDeleted := map[bool]int64{true: 1, false: 0}[tt.expired]; deleted != wantDeleted {
+				t.Errorf("DeleteExpired removed %d sessions, want %d", deleted, wantDeleted)
+			}
+		})
+	}
+}
+
+func TestStoreRotatesTokenOnSignIn(t *testing.T) {
+	store, backend := newTestStore()
+
+	// A session started before signing in, e.g. holding the OAuth state
+	session := load(t, store, nil)
+	session.Values["state"] = "xyz"
+	anonymous := save(t, store, session)
+	anonymousID := session.ID
+
+	session = load(t, store, anonymous)
+	if session.IsNew {
+		t.Fatal("anonymous session didn't load")
+	}
+	userID := uuid.New()
+	session.Values[UserIDKey] = userID.String()
+	signedIn := save(t, store, session)
+
+	if signedIn == nil || signedIn.Value == anonymous.Value {
+		t.Fatal("signing in kept the session's cookie")
+	}
+	if session.ID == anonymousID {
+		t.Error("signing in kept the session's ID")
+	}
+	if record, _ := backend.Find(uuid.MustParse(anonymousID)); record != nil {
+		t.Error("the anonymous session still exists after signing in")
+	}
+	if load(t, store, anonymous).Values[UserIDKey] != nil {
+		t.Error("the cookie from before signing in is signed in")
+	}
+
+	loaded := load(t, store, signedIn)
+	if loaded.Values[UserIDKey] != userID.String() || loaded.Values["state"] != "xyz" {
+		t.Errorf("signed-in session values = %v", loaded.Values)
+	}
+}
+
+func TestRevokeOtherUserSessions(t *testing.T) {
+	store, _ := newTestStore()
+	userID := uuid.New()
+	current, currentID := signIn(t, store, userID)
+	laptop, _ := signIn(t, store, userID)
+	phone, _ := signIn(t, store, userID)
+	someoneElse, _ := signIn(t, store, uuid.New())
+
+	revoked, err := store.RevokeOtherUserSessions(userID, currentID)
+	if err != nil {
+		t.Fatalf("RevokeOtherUserSessions() = %v", err)
+	}
+	if revoked != 2 {
+		t.Errorf("revoked %d sessions, want 2", revoked)
+	}
+
+	for name, want := range map[*http.Cookie]bool{current: true, laptop: false, phone: false, someoneElse: true} {
+		if active := !load(t, store, name).IsNew; active != want {
+			t.Errorf("session %s active = %v, want %v", name.Value[:8], active, want)
+		}
+	}
+	sessions, _ := store.ListUserSessions(userID)
+	if len(sessions) != 1 || sessions[0].ID != currentID {
+		t.Errorf("user's sessions after revoking = %v, want only the current one", sessions)
+	}
+}
+
+func TestRevokedSessionStaysRevoked(t *testing.T) {
+	store, _ := newTestStore()
+	userID := uuid.New()
+	cookie, id := signIn(t, store, userID)
+
+	// A request that loaded the session before it was revoked finishes after
+	inFlight := load(t, store, cookie)
+	if err := store.RevokeUserSession(userID, id); err != nil {
+		t.Fatalf("RevokeUserSession() = %v", err)
+	}
+	inFlight.Values["flash"] = "saved"
+	reissued := save(t, store, inFlight)
+
+	if reissued == nil || reissued.MaxAge >= 0 {
+		t.Errorf("saving a revoked session set cookie %v, want it deleted", reissued)
+	}
+	if sessions, _ := store.ListUserSessions(userID); len(sessions) != 0 {
+		t.Errorf("saving a revoked session recreated %d sessions", len(sessions))
+	}
+	if !load(t, store, cookie).IsNew {
+		t.Error("the revoked cookie loads a session")
+	}
+}
@@ -3,6 +3,7 @@ package callback
 import (
 	"log"
 	"net/http"
+	"strings"
 
 	"01-Login/platform/services"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"01-Login/platform/authenticator"
+	"01-Login/platform/sessionstore"
 )
 
 // Handler for our callback.
@@ -115,10 +117,18 @@ func Handler(auth *authenticator.Authenticator) gin.HandlerFunc {
 
 		log.Printf("User created/updated in database: %s (ID: %s)", user.Email, user.ID.String())
 
+		if !user.IsActive {
+			log.Printf("Refused login of deactivated user %s", user.ID.String())
+			ctx.String(http.StatusForbidden, "Your account has been deactivated.")
+			return
+		}
+
 		// Add the database user_id to the profile
 		profile["user_id"] = user.ID.String()
 
-		session.Set("access_token", token.AccessToken)
+		// Tying the session to the user gives it a new token, so it shows
+		// up in the user's device list and can be revoked from there
+		session.Set(sessionstore.UserIDKey, user.ID.String())
 		session.Set("profile", profile)
 		if err := session.Save(); err != nil {
 			log.Printf("Session save error: %v", err)
@@ -127,6 +137,19 @@ func Handler(auth *authenticator.Authenticator) gin.HandlerFunc {
 		}
 
 		log.Printf("Login successful for user: %v", profile["email"])
+
+		// Go back to the page that asked for a login, such as an invite link
+		if returnTo, ok := session.Get("return_to").(string); ok {
+			session.Delete("return_to")
+			if err := session.Save(); err != nil {
+				log.Printf("Session save error: %v", err)
+			}
+			if strings.HasPrefix(returnTo, "/") && !strings.HasPrefix(returnTo, "//") && !strings.HasPrefix(returnTo, "/\\") {
+				ctx.Redirect(http.StatusTemporaryRedirect, returnTo)
+				return
+			}
+		}
+
 		// Redirect to logged in page.
 		ctx.Redirect(http.StatusTemporaryRedirect, "/user")
 	}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 
+	"01-Login/platform/authorization"
 	"01-Login/platform/services"
 
 	"github.com/gin-contrib/sessions"
@@ -52,9 +53,14 @@ func Handler(ctx *gin.Context) {
 		return
 	}
 
-	// Check if user is the owner of the event
-	if event.UserID != user.ID {
-		ctx.String(http.StatusForbidden, "You can only edit your own events")
+	// Check if user's role on the event lets them edit it
+	role, err := services.NewMemberService().GetRole(event.ID, user.ID)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to load your role on this event")
+		return
+	}
+	if !authorization.CanManageEvent(role) {
+		ctx.String(http.StatusForbidden, "You can only edit events you organize")
 		return
 	}
 
@@ -7,8 +7,12 @@ import (
 	"net/http"
 	"os"
 
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // DetailHandler for the event detail page - serves template like other pages
@@ -38,5 +42,98 @@ func DetailHandler(ctx *gin.Context) {
 		"googleMapsAPIKey": googleMapsAPIKey,
 	}
 
+	if id, err := uuid.Parse(eventID); err == nil {
+		eventService := services.NewEventService()
+		event, err := eventService.GetEventByID(id)
+
+		// Private events are only shown to the organizer and invitees
+		if err == nil && !canViewEvent(ctx, event) {
+			return
+		}
+
+		// Show a cancellation banner for cancelled events
+		if err == nil && event.Status == models.EventStatusCancelled {
+			cancellation := gin.H{}
+			if transition, err := eventService.GetLatestTransition(event.ID, models.EventStatusCancelled); err != nil {
+				log.Printf("Error loading cancellation for event %v: %v", event.ID, err)
+			} else if transition != nil {
+				cancellation["reason"] = transition.Reason
+				cancellation["cancelledAt"] = transition.CreatedAt.In(event.Location()).Format("January 2, 2006")
+			}
+			templateData["cancellation"] = cancellation
+		}
+
+		// Show the organizer's announcements above the event, newest first
+		if err == nil {
+			announcements, err := services.NewAnnouncementService().GetAnnouncements(event.ID)
+			if err != nil {
+				log.Printf("Error loading announcements for event %v: %v", event.ID, err)
+			}
+			var items []gin.H
+			for _, announcement := range announcements {
+				items = append(items, gin.H{
+					"body":     announcement.Body,
+					"author":   announcement.Author.Name,
+					"postedAt": announcement.CreatedAt.In(event.Location()).Format("January 2, 2006 3:04 PM"),
+				})
+			}
+			templateData["announcements"] = items
+		}
+	}
+
 	ctx.HTML(http.StatusOK, "event-detail.html", templateData)
 }
+
+// canViewEvent redeems an invite link in the URL and checks that the visitor
+// may see the event. If not, it has already responded: signed-out visitors
+// are sent to log in and come back, everyone else gets the not-found page.
+func canViewEvent(ctx *gin.Context, event *models.Event) bool {
+	session := sessions.Default(ctx)
+	invitationService := services.NewInvitationService()
+
+	var user *models.User
+	if profile, ok := session.Get("profile").(map[string]interface{}); ok {
+		authID, _ := profile["sub"].(string)
+		if found, err := services.NewUserService().GetUserByAuthID(authID); err == nil && found.IsActive {
+			user = found
+		}
+	}
+
+	token := ctx.Query("invite")
+	if token != "" && user == nil {
+		redirectToLogin(ctx)
+		return false
+	}
+	if token != "" {
+		if _, err := invitationService.AcceptInvitation(user, event, token); err != nil {
+			log.Printf("Error accepting invitation to event %v: %v", event.ID, err)
+		}
+	}
+
+	canView, err := invitationService.CanView(user, event)
+	if err != nil {
+		log.Printf("Error checking access to event %v: %v", event.ID, err)
+	}
+	if canView {
+		return true
+	}
+
+	if user == nil {
+		redirectToLogin(ctx)
+		return false
+	}
+	ctx.HTML(http.StatusNotFound, "event-not-found.html", gin.H{
+		"error": "This event is private. Ask the organizer for an invitation.",
+	})
+	return false
+}
+
+// redirectToLogin sends the visitor to log in, returning to this page afterwards
+func redirectToLogin(ctx *gin.Context) {
+	session := sessions.Default(ctx)
+	session.Set("return_to", ctx.Request.URL.RequestURI())
+	if err := session.Save(); err != nil {
+		log.Printf("Session save error: %v", err)
+	}
+	ctx.Redirect(http.StatusSeeOther, "/login")
+}
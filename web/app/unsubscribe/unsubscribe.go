@@ -0,0 +1,59 @@
+package unsubscribe
+
+import (
+	"net/http"
+
+	"01-Login/platform/models"
+	"01-Login/platform/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// categoryQuestions asks to confirm unsubscribing from a category
+var categoryQuestions = map[models.NotificationCategory]string{
+	models.NotificationRSVPConfirmations:  "Stop getting RSVP confirmations by email?",
+	models.NotificationEventUpdates:       "Stop getting emails about changes to events?",
+	models.NotificationEventCancellations: "Stop getting emails about cancelled events?",
+	models.NotificationEventReminders:     "Stop getting event reminders by email?",
+	models.NotificationCommentMentions:    "Stop getting emails when someone mentions you in a discussion?",
+	models.NotificationAnnouncements:      "Stop getting organizers' announcements by email?",
+}
+
+// categoryMessages says what stops arriving after unsubscribing from a category
+var categoryMessages = map[models.NotificationCategory]string{
+	models.NotificationRSVPConfirmations:  "You won't get RSVP confirmations by email anymore.",
+	models.NotificationEventUpdates:       "You won't get emails about changes to events anymore.",
+	models.NotificationEventCancellations: "You won't get emails about cancelled events anymore.",
+	models.NotificationEventReminders:     "You won't get event reminders by email anymore.",
+	models.NotificationCommentMentions:    "You won't get emails when someone mentions you in a discussion anymore.",
+	models.NotificationAnnouncements:      "You won't get organizers' announcements by email anymore.",
+}
+
+// Handler for the unsubscribe link in emails. It only asks to confirm, so
+// link scanners and prefetching mail clients don't unsubscribe anyone; the
+// confirm button POSTs the token to /api/notifications/unsubscribe, without
+// having to log in.
+func Handler(ctx *gin.Context) {
+	token := ctx.Query("token")
+	category, err := services.NewNotificationService().UnsubscribeCategory(token)
+	if err != nil {
+		ctx.HTML(http.StatusBadRequest, "unsubscribe.html", gin.H{
+			"error": "This unsubscribe link is invalid. Make sure you copied the whole link from the email.",
+		})
+		return
+	}
+
+	question, ok := categoryQuestions[category]
+	if !ok {
+		question = "Stop getting any emails from us?"
+	}
+	message, ok := categoryMessages[category]
+	if !ok {
+		message = "You won't get any more emails from us."
+	}
+	ctx.HTML(http.StatusOK, "unsubscribe.html", gin.H{
+		"token":    token,
+		"question": question,
+		"message":  message,
+	})
+}
@@ -243,6 +243,7 @@ const EventDetailPage = ({ eventId, userInfo: propUserInfo }) => {
   const isMobile = useMediaQuery(theme.breakpoints.down('sm'));
   
   const [event, setEvent] = useState(null);
+  const [eventRole, setEventRole] = useState('');
   const [loading, setLoading] = useState(true);
   const [userInfo, setUserInfo] = useState(null);
   const [isAuthenticated, setIsAuthenticated] = useState(false);
@@ -269,15 +270,43 @@ const EventDetailPage = ({ eventId, userInfo: propUserInfo }) => {
       fetchUserRSVP(id);
     }
 
-    // Fetch RSVP counts only if the event data is loaded and the current user is the owner
-    if (event && userInfo && event.user_id === userInfo.user_id && id) {
+    // Fetch RSVP counts only if the event data is loaded and the current user has a role on the event
+    if (event && eventRole && id) {
       fetchRSVPCounts(id);
     } else {
-      // For non-owners, or if data isn't ready, ensure counts are reset or not shown
+      // For guests, or if data isn't ready, ensure counts are reset or not shown
       // (The rendering logic will handle not showing the section)
       setRSVPCounts({ yes: 0, no: 0, maybe: 0 }); 
     }
-  }, [isAuthenticated, event, userInfo, eventId]);
+  }, [isAuthenticated, event, eventRole, eventId]);
+
+  useEffect(() => {
+    // Keep the event and RSVP counts live while the page is open
+    const id = eventId || window.eventId;
+    if (!id || typeof EventSource === 'undefined') {
+      return undefined;
+    }
+
+    const source = new EventSource(`/api/events/${id}/stream`, { withCredentials: true });
+    source.addEventListener('event', (e) => {
+      const data = JSON.parse(e.data);
+      if (data.data) {
+        setEvent(data.data);
+      }
+    });
+    source.addEventListener('counts', (e) => {
+      const data = JSON.parse(e.data);
+      if (data.counts) {
+        setRSVPCounts(data.counts);
+      }
+    });
+    source.addEventListener('deleted', () => {
+      source.close();
+      window.location.reload();
+    });
+
+    return () => source.close();
+  }, [eventId]);
 
   const fetchUserInfo = async () => {
     try {
@@ -303,6 +332,7 @@ const EventDetailPage = ({ eventId, userInfo: propUserInfo }) => {
       const data = await response.json();
       if (data.data) {
         setEvent(data.data);
+        setEventRole(data.role || '');
       }
     } catch (error) {
       console.error('Error fetching event:', error);
@@ -500,7 +530,8 @@ const EventDetailPage = ({ eventId, userInfo: propUserInfo }) => {
     );
   }
 
-  const isOwner = userInfo && event && event.user_id === userInfo.user_id;
+  const isOwner = eventRole === 'owner';
+  const isHost = isOwner || eventRole === 'co_host';
 
   let imageUrlToDisplay = event.image; // Custom image URL
   if (!imageUrlToDisplay && event.event_type) {
@@ -579,7 +610,7 @@ const EventDetailPage = ({ eventId, userInfo: propUserInfo }) => {
                   >
                     <ShareIcon sx={{ color: 'text.secondary' }} />
                   </IconButton>
-                  {isOwner && (
+                  {isHost && (
                     <IconButton 
                       aria-label="More options" 
                       onClick={handleMenuOpen} 
@@ -601,9 +632,11 @@ const EventDetailPage = ({ eventId, userInfo: propUserInfo }) => {
                     <MenuItem onClick={handleAddToGoogleCalendar}>
                       <EventIcon sx={{ mr: 1, color: 'primary.main' }} /> Add to Google Calendar
                     </MenuItem>
-                    <MenuItem onClick={handleDelete}>
-                      <DeleteIcon sx={{ mr: 1, color: 'error.main' }} /> Delete
-                    </MenuItem>
+                    {isOwner && (
+                      <MenuItem onClick={handleDelete}>
+                        <DeleteIcon sx={{ mr: 1, color: 'error.main' }} /> Delete
+                      </MenuItem>
+                    )}
                     <MenuItem onClick={handlePlaceholder}>
                       <HelpIcon sx={{ mr: 1, color: 'text.secondary' }} /> Placeholder Option
                     </MenuItem>
@@ -718,7 +751,7 @@ const EventDetailPage = ({ eventId, userInfo: propUserInfo }) => {
                   )}
 
                   {/* RSVP Section - Only show if authenticated and not owner */}
-                  {isAuthenticated && !isOwner && (
+                  {isAuthenticated && !isHost && (
                     <Box>
                       <DetailItem>
                         <DetailIcon>
@@ -806,8 +839,8 @@ const EventDetailPage = ({ eventId, userInfo: propUserInfo }) => {
                     </Box>
                   )}
 
-                  {/* RSVP Counts - Only show if owner and counts are available */}
-                  {isOwner && (rsvpCounts.yes > 0 || rsvpCounts.no > 0 || rsvpCounts.maybe > 0) && (
+                  {/* RSVP Counts - Only show to organizers and check-in staff when counts are available */}
+                  {eventRole && (rsvpCounts.yes > 0 || rsvpCounts.no > 0 || rsvpCounts.maybe > 0) && (
                     <Box>
                       <DetailItem>
                         <DetailIcon>